@@ -3,3 +3,7 @@ package zda
 type nodeJoin struct {
 	n *node
 }
+
+type nodeReenumerate struct {
+	n *node
+}
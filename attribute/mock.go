@@ -7,6 +7,7 @@ import (
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zigbee"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type MockMonitor struct {
@@ -30,4 +31,17 @@ func (m *MockMonitor) Detach(ctx context.Context, unconfigure bool) error {
 
 }
 
+func (m *MockMonitor) ExpectedInterval() time.Duration {
+	return m.Called().Get(0).(time.Duration)
+}
+
+func (m *MockMonitor) VerifyReporting(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMonitor) RefreshReporting(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
 var _ Monitor = (*MockMonitor)(nil)
@@ -2,6 +2,7 @@ package attribute
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/shimmeringbee/da"
 	"github.com/shimmeringbee/logwrap"
@@ -49,11 +50,22 @@ type Monitor interface {
 	Load(ctx context.Context) error
 	Attach(ctx context.Context, e zigbee.Endpoint, c zigbee.ClusterID, a zcl.AttributeID, dt zcl.AttributeDataType, rc ReportingConfig, pc PollingConfig) error
 	Detach(ctx context.Context, unconfigure bool) error
+	// ExpectedInterval is the longest the attribute should go without a report or poll, zero if unknown.
+	ExpectedInterval() time.Duration
+	// VerifyReporting queries the device for its reporting configuration, returning false if reporting was configured
+	// by the monitor but the device has since lost it.
+	VerifyReporting(ctx context.Context) (bool, error)
+	// RefreshReporting binds and configures reporting again with the configuration used when attached, if reporting
+	// was configured.
+	RefreshReporting(ctx context.Context) error
 }
 
 const ReportingConfiguredKey = "ReportingConfigured"
 const PollingConfiguredKey = "PollingConfigured"
 const PollingIntervalKey = "PollingInterval"
+const ReportingMinimumIntervalKey = "ReportingMinimumInterval"
+const ReportingMaximumIntervalKey = "ReportingMaximumInterval"
+const ReportableChangeKey = "ReportableChange"
 
 const RemoteEndpointKey = "RemoteEndpoint"
 const ClusterIdKey = "ClusterID"
@@ -183,17 +195,14 @@ func (z *zclMonitor) Attach(ctx context.Context, e zigbee.Endpoint, c zigbee.Clu
 	if rc.Mode == AttemptConfigureReporting {
 		z.logger.Info(ctx, "Attempting to configure attribute reporting.")
 
-		if reportingErr = z.nodeBinder.BindNodeToController(ctx, z.ieeeAddress, z.localEndpoint, e, c); reportingErr != nil {
-			z.logger.Warn(ctx, "Binding node to controller failed.", logwrap.Err(reportingErr))
+		if reportingErr = z.configureReporting(ctx, ack, seq, rc); reportingErr != nil {
 			failedReporting = true
 		} else {
-			if reportingErr = z.zclCommunicator.ConfigureReporting(ctx, z.ieeeAddress, ack, z.clusterID, zigbee.NoManufacturer, z.localEndpoint, z.remoteEndpoint, seq, z.attributeID, z.attributeDataType, uint16(math.Round(rc.MinimumInterval.Seconds())), uint16(math.Round(rc.MaximumInterval.Seconds())), rc.ReportableChange); reportingErr != nil {
-				z.logger.Warn(ctx, "Configure reporting failed.", logwrap.Err(reportingErr))
-				failedReporting = true
-			} else {
-				z.config.Set(ReportingConfiguredKey, true)
-				z.logger.Info(ctx, "Reporting configured successfully.")
-			}
+			z.config.Set(ReportingConfiguredKey, true)
+			converter.Store(z.config, ReportingMinimumIntervalKey, rc.MinimumInterval, converter.DurationEncoder)
+			converter.Store(z.config, ReportingMaximumIntervalKey, rc.MaximumInterval, converter.DurationEncoder)
+			storeReportableChange(z.config, rc.ReportableChange)
+			z.logger.Info(ctx, "Reporting configured successfully.")
 		}
 	}
 
@@ -207,6 +216,178 @@ func (z *zclMonitor) Attach(ctx context.Context, e zigbee.Endpoint, c zigbee.Clu
 	return z.reattach(ctx)
 }
 
+func (z *zclMonitor) configureReporting(ctx context.Context, ack bool, seq uint8, rc ReportingConfig) error {
+	if err := z.nodeBinder.BindNodeToController(ctx, z.ieeeAddress, z.localEndpoint, z.remoteEndpoint, z.clusterID); err != nil {
+		z.logger.Warn(ctx, "Binding node to controller failed.", logwrap.Err(err))
+		return err
+	}
+
+	if err := z.zclCommunicator.ConfigureReporting(ctx, z.ieeeAddress, ack, z.clusterID, zigbee.NoManufacturer, z.localEndpoint, z.remoteEndpoint, seq, z.attributeID, z.attributeDataType, uint16(math.Round(rc.MinimumInterval.Seconds())), uint16(math.Round(rc.MaximumInterval.Seconds())), reportableChangeForDataType(z.attributeDataType, rc.ReportableChange)); err != nil {
+		z.logger.Warn(ctx, "Configure reporting failed.", logwrap.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// storedReportingConfig returns the reporting configuration used when the monitor was attached, false is returned if
+// the configuration was not completely persisted, as is the case for monitors attached by earlier versions.
+func (z *zclMonitor) storedReportingConfig() (ReportingConfig, bool) {
+	rc := ReportingConfig{Mode: AttemptConfigureReporting}
+
+	/* Discrete types have no reportable change, so none is persisted. */
+	complete := z.config.Exists(ReportingMinimumIntervalKey) && z.config.Exists(ReportingMaximumIntervalKey) &&
+		(zcl.DiscreteTypes[z.attributeDataType] || z.config.Exists(ReportableChangeKey))
+
+	rc.MinimumInterval, _ = converter.Retrieve(z.config, ReportingMinimumIntervalKey, converter.DurationDecoder)
+	rc.MaximumInterval, _ = converter.Retrieve(z.config, ReportingMaximumIntervalKey, converter.DurationDecoder)
+
+	switch z.config.Type(ReportableChangeKey) {
+	case persistence.Int:
+		rc.ReportableChange, _ = z.config.Int(ReportableChangeKey)
+	case persistence.UnsignedInt:
+		rc.ReportableChange, _ = z.config.UInt(ReportableChangeKey)
+	case persistence.Float:
+		rc.ReportableChange, _ = z.config.Float(ReportableChangeKey)
+	}
+
+	return rc, complete
+}
+
+// storeReportableChange persists a reportable change as the widest persistable type of the same kind, the zcl library
+// accepts any width when marshalling integer types.
+func storeReportableChange(s persistence.Section, v any) {
+	switch n := v.(type) {
+	case int:
+		s.Set(ReportableChangeKey, int64(n))
+	case int8:
+		s.Set(ReportableChangeKey, int64(n))
+	case int16:
+		s.Set(ReportableChangeKey, int64(n))
+	case int32:
+		s.Set(ReportableChangeKey, int64(n))
+	case int64:
+		s.Set(ReportableChangeKey, n)
+	case uint:
+		s.Set(ReportableChangeKey, uint64(n))
+	case uint8:
+		s.Set(ReportableChangeKey, uint64(n))
+	case uint16:
+		s.Set(ReportableChangeKey, uint64(n))
+	case uint32:
+		s.Set(ReportableChangeKey, uint64(n))
+	case uint64:
+		s.Set(ReportableChangeKey, n)
+	case float32:
+		s.Set(ReportableChangeKey, float64(n))
+	case float64:
+		s.Set(ReportableChangeKey, n)
+	default:
+		s.Delete(ReportableChangeKey)
+	}
+}
+
+func (z *zclMonitor) VerifyReporting(ctx context.Context) (bool, error) {
+	if v, ok := z.config.Bool(ReportingConfiguredKey); !ok || !v {
+		return true, nil
+	}
+
+	ieee, _, ack, seq := z.transmissionLookup(z.device, zigbee.ProfileHomeAutomation)
+
+	response, err := z.zclCommunicator.RequestResponse(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameGlobal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           z.clusterID,
+		SourceEndpoint:      z.localEndpoint,
+		DestinationEndpoint: z.remoteEndpoint,
+		Command: &global.ReadReportingConfiguration{
+			Records: []global.ReadReportingConfigurationRecord{{Direction: 0x00, Identifier: z.attributeID}},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	cmd, ok := response.Command.(*global.ReadReportingConfigurationResponse)
+	if !ok {
+		return false, errors.New("read reporting configuration received command back which was not ReadReportingConfigurationResponse")
+	}
+
+	expectedMaximum, _ := converter.Retrieve(z.config, ReportingMaximumIntervalKey, converter.DurationDecoder)
+
+	for _, record := range cmd.Records {
+		if record.Identifier == z.attributeID {
+			/* Devices which have lost their configuration either fail the read, or return their default intervals. */
+			return record.Status == 0 && record.MaximumInterval == uint16(math.Round(expectedMaximum.Seconds())), nil
+		}
+	}
+
+	return false, nil
+}
+
+func (z *zclMonitor) RefreshReporting(ctx context.Context) error {
+	if v, ok := z.config.Bool(ReportingConfiguredKey); !ok || !v {
+		return nil
+	}
+
+	rc, complete := z.storedReportingConfig()
+	if !complete {
+		z.logger.Warn(ctx, "Stored reporting configuration is incomplete, skipping refresh until the device is enumerated again.")
+		return nil
+	}
+
+	z.logger.Info(ctx, "Refreshing attribute reporting configuration.")
+
+	_, _, ack, seq := z.transmissionLookup(z.device, zigbee.ProfileHomeAutomation)
+	return z.configureReporting(ctx, ack, seq, rc)
+}
+
+func (z *zclMonitor) ExpectedInterval() time.Duration {
+	var interval time.Duration
+
+	if v, ok := z.config.Bool(ReportingConfiguredKey); ok && v {
+		interval, _ = converter.Retrieve(z.config, ReportingMaximumIntervalKey, converter.DurationDecoder)
+	}
+
+	if v, ok := z.config.Bool(PollingConfiguredKey); ok && v {
+		if polling, _ := converter.Retrieve(z.config, PollingIntervalKey, converter.DurationDecoder); interval == 0 || (polling > 0 && polling < interval) {
+			interval = polling
+		}
+	}
+
+	return interval
+}
+
+// reportableChangeForDataType converts a reportable change into the Go type the zcl library requires to marshal the
+// attribute data type, floating point types must be provided as exactly float32 or float64.
+func reportableChangeForDataType(dt zcl.AttributeDataType, v any) any {
+	var f float64
+
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	case uint:
+		f = float64(n)
+	default:
+		return v
+	}
+
+	switch dt {
+	case zcl.TypeFloatSingle:
+		return float32(f)
+	case zcl.TypeFloatDouble:
+		return f
+	default:
+		return v
+	}
+}
+
 func (z *zclMonitor) Detach(ctx context.Context, unconfigure bool) error {
 	z.logger.Info(ctx, "Detaching event monitor...", logwrap.Datum("Unconfigure", unconfigure))
 	z.zclCommunicator.UnregisterMatch(z.match)
@@ -221,6 +402,9 @@ func (z *zclMonitor) Detach(ctx context.Context, unconfigure bool) error {
 		}
 
 		z.config.Delete(ReportingConfiguredKey)
+		z.config.Delete(ReportingMinimumIntervalKey)
+		z.config.Delete(ReportingMaximumIntervalKey)
+		z.config.Delete(ReportableChangeKey)
 		z.config.Delete(PollingConfiguredKey)
 	}
 
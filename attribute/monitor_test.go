@@ -6,6 +6,7 @@ import (
 	mocks2 "github.com/shimmeringbee/da/mocks"
 	"github.com/shimmeringbee/logwrap"
 	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
 	"github.com/shimmeringbee/persistence/impl/memory"
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zcl/commands/global"
@@ -174,6 +175,42 @@ func Test_zclMonitor_Attach(t *testing.T) {
 
 		reportingConfiguredSetting, _ := z.config.Bool(ReportingConfiguredKey)
 		assert.True(t, reportingConfiguredSetting)
+
+		assert.Equal(t, 5*time.Minute, z.ExpectedInterval())
+	})
+
+	t.Run("attach converts reportable change for single precision float attributes", func(t *testing.T) {
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+
+		mzp := &zigbee.MockProvider{}
+		defer mzp.AssertExpectations(t)
+		expectedIeee := zigbee.GenerateLocalAdministeredIEEEAddress()
+
+		mzp.On("BindNodeToController", mock.Anything, expectedIeee, zigbee.Endpoint(2), zigbee.Endpoint(1), zigbee.ClusterID(2)).Return(nil)
+		mzc.On("ConfigureReporting", mock.Anything, expectedIeee, false, zigbee.ClusterID(2), zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(1), uint8(0), zcl.AttributeID(3), zcl.TypeFloatSingle, uint16(60), uint16(300), float32(0.5)).Return(nil)
+
+		s := memory.New()
+
+		d := &mocks2.MockDevice{}
+		defer d.AssertExpectations(t)
+		d.On("Identifier").Return(zigbee.GenerateLocalAdministeredIEEEAddress())
+
+		cb := func(zcl.AttributeID, zcl.AttributeDataTypeValue) {}
+
+		tl := func(dd da.Device, _ zigbee.ProfileID) (zigbee.IEEEAddress, zigbee.Endpoint, bool, uint8) {
+			assert.Equal(t, d, dd)
+			return expectedIeee, 2, false, 0
+		}
+
+		z := NewMonitor(mzc, mzp, tl, logwrap.New(discard.Discard())).(*zclMonitor)
+		z.Init(s, d, cb)
+		defer z.Detach(context.Background(), false)
+
+		err := z.Attach(context.Background(), 1, 2, 3, zcl.TypeFloatSingle, ReportingConfig{Mode: AttemptConfigureReporting, MinimumInterval: 1 * time.Minute, MaximumInterval: 5 * time.Minute, ReportableChange: 0.5}, PollingConfig{Mode: NeverPoll})
+		assert.NoError(t, err)
 	})
 
 	t.Run("attach succeeds for reporting fails, polling if failed, polling configured", func(t *testing.T) {
@@ -217,6 +254,8 @@ func Test_zclMonitor_Attach(t *testing.T) {
 		pollingIntervalSetting, _ := z.config.Int(PollingIntervalKey)
 		assert.Equal(t, int64(60000), pollingIntervalSetting)
 		assert.NotNil(t, z.ticker)
+
+		assert.Equal(t, time.Minute, z.ExpectedInterval())
 	})
 
 	t.Run("attach succeeds for reporting succeeds, polling always", func(t *testing.T) {
@@ -443,6 +482,123 @@ func Test_zclMonitor_Detach(t *testing.T) {
 	})
 }
 
+func newReportingMonitor(t *testing.T) (*zclMonitor, *mocks.MockZCLCommunicator, *zigbee.MockProvider, zigbee.IEEEAddress) {
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzp := &zigbee.MockProvider{}
+	t.Cleanup(func() { mzp.AssertExpectations(t) })
+
+	expectedIeee := zigbee.GenerateLocalAdministeredIEEEAddress()
+
+	d := &mocks2.MockDevice{}
+	d.On("Identifier").Return(expectedIeee)
+
+	tl := func(da.Device, zigbee.ProfileID) (zigbee.IEEEAddress, zigbee.Endpoint, bool, uint8) {
+		return expectedIeee, 2, false, 0
+	}
+
+	s := memory.New()
+	s.Set(ReportingConfiguredKey, true)
+	converter.Store(s, ReportingMinimumIntervalKey, time.Minute, converter.DurationEncoder)
+	converter.Store(s, ReportingMaximumIntervalKey, 5*time.Minute, converter.DurationEncoder)
+	storeReportableChange(s, uint(1))
+
+	z := NewMonitor(mzc, mzp, tl, logwrap.New(discard.Discard())).(*zclMonitor)
+	z.Init(s, d, func(zcl.AttributeID, zcl.AttributeDataTypeValue) {})
+
+	z.ieeeAddress = expectedIeee
+	z.localEndpoint = 2
+	z.remoteEndpoint = 1
+	z.clusterID = 2
+	z.attributeID = 3
+	z.attributeDataType = zcl.TypeUnsignedInt8
+
+	return z, mzc, mzp, expectedIeee
+}
+
+func Test_zclMonitor_VerifyReporting(t *testing.T) {
+	t.Run("returns true if the device reports the configured maximum interval", func(t *testing.T) {
+		z, mzc, _, ieee := newReportingMonitor(t)
+
+		mzc.On("RequestResponse", mock.Anything, ieee, false, mock.MatchedBy(func(m zcl.Message) bool {
+			cmd, ok := m.Command.(*global.ReadReportingConfiguration)
+			return ok && m.ClusterID == 2 && m.DestinationEndpoint == 1 && cmd.Records[0].Identifier == 3
+		})).Return(zcl.Message{Command: &global.ReadReportingConfigurationResponse{Records: []global.ReadReportingConfigurationResponseRecord{{Identifier: 3, MaximumInterval: 300}}}}, nil)
+
+		present, err := z.VerifyReporting(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, present)
+	})
+
+	t.Run("returns false if the device has lost or reset its configuration", func(t *testing.T) {
+		z, mzc, _, ieee := newReportingMonitor(t)
+
+		mzc.On("RequestResponse", mock.Anything, ieee, false, mock.Anything).Return(zcl.Message{Command: &global.ReadReportingConfigurationResponse{Records: []global.ReadReportingConfigurationResponseRecord{{Identifier: 3, Status: 0x8b}}}}, nil).Once()
+
+		present, err := z.VerifyReporting(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, present)
+
+		mzc.On("RequestResponse", mock.Anything, ieee, false, mock.Anything).Return(zcl.Message{Command: &global.ReadReportingConfigurationResponse{Records: []global.ReadReportingConfigurationResponseRecord{{Identifier: 3, MaximumInterval: 3600}}}}, nil).Once()
+
+		present, err = z.VerifyReporting(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, present)
+	})
+
+	t.Run("returns true without querying the device if reporting was not configured", func(t *testing.T) {
+		z, _, _, _ := newReportingMonitor(t)
+		z.config.Delete(ReportingConfiguredKey)
+
+		present, err := z.VerifyReporting(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, present)
+	})
+}
+
+func Test_zclMonitor_RefreshReporting(t *testing.T) {
+	t.Run("binds and configures reporting with the stored configuration", func(t *testing.T) {
+		z, mzc, mzp, ieee := newReportingMonitor(t)
+
+		mzp.On("BindNodeToController", mock.Anything, ieee, zigbee.Endpoint(2), zigbee.Endpoint(1), zigbee.ClusterID(2)).Return(nil)
+		mzc.On("ConfigureReporting", mock.Anything, ieee, false, zigbee.ClusterID(2), zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(1), uint8(0), zcl.AttributeID(3), zcl.TypeUnsignedInt8, uint16(60), uint16(300), uint64(1)).Return(nil)
+
+		err := z.RefreshReporting(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns the error if binding fails", func(t *testing.T) {
+		z, _, mzp, ieee := newReportingMonitor(t)
+
+		mzp.On("BindNodeToController", mock.Anything, ieee, zigbee.Endpoint(2), zigbee.Endpoint(1), zigbee.ClusterID(2)).Return(io.EOF)
+
+		err := z.RefreshReporting(context.Background())
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("skips refreshing if the stored configuration predates persisting the minimum interval and reportable change", func(t *testing.T) {
+		z, _, _, _ := newReportingMonitor(t)
+		z.config.Delete(ReportingMinimumIntervalKey)
+		z.config.Delete(ReportableChangeKey)
+
+		err := z.RefreshReporting(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("refreshes a discrete attribute which has no stored reportable change", func(t *testing.T) {
+		z, mzc, mzp, ieee := newReportingMonitor(t)
+		z.attributeDataType = zcl.TypeBoolean
+		z.config.Delete(ReportableChangeKey)
+
+		mzp.On("BindNodeToController", mock.Anything, ieee, zigbee.Endpoint(2), zigbee.Endpoint(1), zigbee.ClusterID(2)).Return(nil)
+		mzc.On("ConfigureReporting", mock.Anything, ieee, false, zigbee.ClusterID(2), zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(1), uint8(0), zcl.AttributeID(3), zcl.TypeBoolean, uint16(60), uint16(300), nil).Return(nil)
+
+		err := z.RefreshReporting(context.Background())
+		assert.NoError(t, err)
+	})
+}
+
 func Test_zclMonitor_poller(t *testing.T) {
 	t.Run("polls device for data when requested", func(t *testing.T) {
 		expectedIeee := zigbee.GenerateLocalAdministeredIEEEAddress()
@@ -0,0 +1,89 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+)
+
+// MaximumQueuedCommands is the maximum number of messages held for a node until it checks in.
+const MaximumQueuedCommands = 32
+
+var _ implcaps.CommandQueue = (*commandQueue)(nil)
+
+func newCommandQueue() *commandQueue {
+	return &commandQueue{m: &sync.Mutex{}}
+}
+
+type commandQueue struct {
+	m        *sync.Mutex
+	enabled  bool
+	messages []implcaps.QueuedMessage
+}
+
+func (q *commandQueue) Enable(enabled bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.enabled = enabled
+
+	if !enabled {
+		q.messages = nil
+	}
+}
+
+func (q *commandQueue) Len() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return len(q.messages)
+}
+
+func (q *commandQueue) Drain() []implcaps.QueuedMessage {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	messages := q.messages
+	q.messages = nil
+
+	return messages
+}
+
+// enqueue holds the message until the node checks in, returning false if the queue is not enabled.
+func (q *commandQueue) enqueue(msg implcaps.QueuedMessage) (bool, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if !q.enabled {
+		return false, nil
+	}
+
+	if len(q.messages) >= MaximumQueuedCommands {
+		return true, implcaps.ErrCommandQueueFull
+	}
+
+	q.messages = append(q.messages, msg)
+	return true, nil
+}
+
+// queuingCommunicator is the communicator provided to capabilities, it holds requests made with a context marked by
+// implcaps.QueueUntilCheckIn in the destination node's command queue.
+type queuingCommunicator struct {
+	communicator.Communicator
+	gw *ZDA
+}
+
+func (q queuingCommunicator) Request(ctx context.Context, address zigbee.IEEEAddress, requireAck bool, message zcl.Message) error {
+	if implcaps.IsQueuedUntilCheckIn(ctx) {
+		if n := q.gw.getNode(address); n != nil {
+			if queued, err := n.commandQueue.enqueue(implcaps.QueuedMessage{RequireAck: requireAck, Message: message}); queued {
+				return err
+			}
+		}
+	}
+
+	return q.Communicator.Request(ctx, address, requireAck, message)
+}
@@ -0,0 +1,105 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+)
+
+func Test_commandQueue(t *testing.T) {
+	t.Run("does not queue messages unless enabled", func(t *testing.T) {
+		q := newCommandQueue()
+
+		queued, err := q.enqueue(implcaps.QueuedMessage{})
+		assert.False(t, queued)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, q.Len())
+	})
+
+	t.Run("drains queued messages in order", func(t *testing.T) {
+		q := newCommandQueue()
+		q.Enable(true)
+
+		_, _ = q.enqueue(implcaps.QueuedMessage{Message: zcl.Message{TransactionSequence: 1}})
+		_, _ = q.enqueue(implcaps.QueuedMessage{Message: zcl.Message{TransactionSequence: 2}})
+		assert.Equal(t, 2, q.Len())
+
+		messages := q.Drain()
+		assert.Equal(t, []implcaps.QueuedMessage{{Message: zcl.Message{TransactionSequence: 1}}, {Message: zcl.Message{TransactionSequence: 2}}}, messages)
+		assert.Equal(t, 0, q.Len())
+	})
+
+	t.Run("disabling discards queued messages", func(t *testing.T) {
+		q := newCommandQueue()
+		q.Enable(true)
+
+		_, _ = q.enqueue(implcaps.QueuedMessage{})
+		q.Enable(false)
+
+		assert.Equal(t, 0, q.Len())
+	})
+
+	t.Run("errors if the queue is full", func(t *testing.T) {
+		q := newCommandQueue()
+		q.Enable(true)
+
+		for range MaximumQueuedCommands {
+			_, _ = q.enqueue(implcaps.QueuedMessage{})
+		}
+
+		queued, err := q.enqueue(implcaps.QueuedMessage{})
+		assert.True(t, queued)
+		assert.ErrorIs(t, err, implcaps.ErrCommandQueueFull)
+		assert.Equal(t, MaximumQueuedCommands, q.Len())
+	})
+}
+
+func Test_queuingCommunicator_Request(t *testing.T) {
+	addr := zigbee.GenerateLocalAdministeredIEEEAddress()
+
+	newQueuingCommunicator := func(t *testing.T) (queuingCommunicator, *mocks.MockZCLCommunicator, *node) {
+		mzc := &mocks.MockZCLCommunicator{}
+		t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+		n := &node{address: addr, m: &sync.RWMutex{}, commandQueue: newCommandQueue()}
+		gw := &ZDA{nodeLock: &sync.RWMutex{}, node: map[zigbee.IEEEAddress]*node{addr: n}}
+
+		return queuingCommunicator{Communicator: mzc, gw: gw}, mzc, n
+	}
+
+	t.Run("sends immediately if the context is not marked for queueing", func(t *testing.T) {
+		qc, mzc, n := newQueuingCommunicator(t)
+		n.commandQueue.Enable(true)
+
+		mzc.On("Request", mock.Anything, addr, true, zcl.Message{}).Return(nil)
+
+		err := qc.Request(context.Background(), addr, true, zcl.Message{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n.commandQueue.Len())
+	})
+
+	t.Run("sends immediately if the node does not support poll control", func(t *testing.T) {
+		qc, mzc, n := newQueuingCommunicator(t)
+
+		mzc.On("Request", mock.Anything, addr, true, zcl.Message{}).Return(nil)
+
+		err := qc.Request(implcaps.QueueUntilCheckIn(context.Background()), addr, true, zcl.Message{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n.commandQueue.Len())
+	})
+
+	t.Run("queues the message if marked and the node supports poll control", func(t *testing.T) {
+		qc, _, n := newQueuingCommunicator(t)
+		n.commandQueue.Enable(true)
+
+		err := qc.Request(implcaps.QueueUntilCheckIn(context.Background()), addr, true, zcl.Message{TransactionSequence: 4})
+		assert.NoError(t, err)
+		assert.Equal(t, []implcaps.QueuedMessage{{RequireAck: true, Message: zcl.Message{TransactionSequence: 4}}}, n.commandQueue.Drain())
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zcl/commands/global"
 	"github.com/shimmeringbee/zcl/commands/local/basic"
+	"github.com/shimmeringbee/zda/extcaps"
 	"github.com/shimmeringbee/zda/implcaps"
 	"github.com/shimmeringbee/zda/implcaps/factory"
 	"github.com/shimmeringbee/zda/rules"
@@ -55,6 +56,14 @@ func (e enumerateDevice) onNodeJoin(ctx context.Context, join nodeJoin) error {
 	return nil
 }
 
+func (e enumerateDevice) onNodeReenumerate(ctx context.Context, re nodeReenumerate) error {
+	if err := e.startEnumeration(ctx, re.n); err != nil {
+		e.logger.LogInfo(ctx, "Failed to start re-enumeration of node.", logwrap.Datum("IEEEAddress", re.n.address.String()), logwrap.Err(err))
+	}
+
+	return nil
+}
+
 func (e enumerateDevice) startEnumeration(ctx context.Context, n *node) error {
 	e.logger.LogInfo(ctx, "Request to enumerate node received.", logwrap.Datum("IEEEAddress", n.address.String()))
 
@@ -333,9 +342,26 @@ func (e enumerateDevice) updateCapabilitiesOnDevice(ctx context.Context, d *devi
 	}
 
 	var activeCapabilities []da.Capability
+	var enumeratedCapabilities []da.Capability
+
+	for _, ep := range id.endpoints {
+		for capImplName := range ep.rulesOutput.Capabilities {
+			if cF, found := factory.Mapping[capImplName]; found {
+				enumeratedCapabilities = append(enumeratedCapabilities, cF)
+			}
+		}
+	}
+
+	/* Hold queued requests as soon as the node is known to check in, regardless of which capability queues them first. */
+	if slices.Contains(enumeratedCapabilities, extcaps.PollControlFlag) {
+		d.n.commandQueue.Enable(true)
+	}
 
 	d.m.Lock()
 
+	/* Detach capabilities no longer enumerated first, so unconfiguring them can not undo their replacement's reporting. */
+	e.detachRedundantCapabilities(ctx, d, enumeratedCapabilities, errs)
+
 	for _, ep := range id.endpoints {
 		for capImplName, settings := range ep.rulesOutput.Capabilities {
 			cF, found := factory.Mapping[capImplName]
@@ -364,8 +390,18 @@ func (e enumerateDevice) updateCapabilitiesOnDevice(ctx context.Context, d *devi
 		}
 	}
 
+	e.detachRedundantCapabilities(ctx, d, activeCapabilities, errs)
+
+	d.m.Unlock()
+
+	return errs
+}
+
+// detachRedundantCapabilities detaches every capability on the device which is not in the list of capabilities to
+// retain, device lock must be held.
+func (e enumerateDevice) detachRedundantCapabilities(ctx context.Context, d *device, retain []da.Capability, errs map[da.Capability]*capabilities.EnumerationCapability) {
 	for cf, impl := range d.capabilities {
-		if !slices.Contains(activeCapabilities, cf) {
+		if !slices.Contains(retain, cf) {
 			errs[cf] = &capabilities.EnumerationCapability{Attached: false}
 
 			e.logger.LogInfo(ctx, "Removing redundant capability implementation.", logwrap.Datum("Device", capabilities.StandardNames[cf]))
@@ -377,10 +413,6 @@ func (e enumerateDevice) updateCapabilitiesOnDevice(ctx context.Context, d *devi
 			e.dm.detachCapabilityFromDevice(d, impl)
 		}
 	}
-
-	d.m.Unlock()
-
-	return errs
 }
 
 func (e enumerateDevice) enumerateCapabilityOnDevice(ctx context.Context, d *device, capImplName string, cF da.Capability, activeCapabilities []da.Capability, settings map[string]any) (bool, []error) {
@@ -2,6 +2,7 @@ package zda
 
 import (
 	"context"
+	"fmt"
 	"github.com/shimmeringbee/da"
 	"github.com/shimmeringbee/da/capabilities"
 	"github.com/shimmeringbee/logwrap"
@@ -10,9 +11,14 @@ import (
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zcl/commands/global"
 	"github.com/shimmeringbee/zcl/commands/local/basic"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
 	"github.com/shimmeringbee/zda/implcaps"
 	"github.com/shimmeringbee/zda/implcaps/factory"
 	"github.com/shimmeringbee/zda/implcaps/generic/product_information"
+	"github.com/shimmeringbee/zda/implcaps/zcl/flow_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/pressure_sensor"
+	"github.com/shimmeringbee/zda/mocks"
 	"github.com/shimmeringbee/zda/rules"
 	"github.com/shimmeringbee/zigbee"
 	"github.com/stretchr/testify/assert"
@@ -558,6 +564,37 @@ func Test_enumerateDevice_updateCapabilitiesOnDevice(t *testing.T) {
 		assert.True(t, errs[capabilities.ProductInformationFlag].Attached)
 	})
 
+	t.Run("enables the node's command queue before enumerating capabilities if the device has poll control", func(t *testing.T) {
+		mdm := &mockDeviceManager{}
+		defer mdm.AssertExpectations(t)
+
+		n := &node{commandQueue: newCommandQueue()}
+		d := &device{m: &sync.RWMutex{}, n: n, deviceId: 1, capabilities: map[da.Capability]implcaps.ZDACapability{}}
+
+		ed := enumerateDevice{logger: logwrap.New(discard.Discard()), capabilityFactory: func(_ string, _ implcaps.ZDAInterface) implcaps.ZDACapability {
+			assert.True(t, n.commandQueue.enabled)
+			return nil
+		}, dm: mdm, gw: &ZDA{section: memory.New()}}
+
+		id := inventoryDevice{
+			uniqueId: 1,
+			endpoints: []endpointDetails{
+				{
+					rulesOutput: rules.Output{
+						Capabilities: map[string]map[string]any{
+							"ZCLAlarmSensor": {},
+							"ZCLPollControl": {},
+						},
+					},
+				},
+			},
+		}
+
+		_ = ed.updateCapabilitiesOnDevice(context.Background(), d, id)
+
+		assert.True(t, n.commandQueue.enabled)
+	})
+
 	t.Run("removes an existing capability that's not longer required", func(t *testing.T) {
 		mdm := &mockDeviceManager{}
 		defer mdm.AssertExpectations(t)
@@ -584,4 +621,129 @@ func Test_enumerateDevice_updateCapabilitiesOnDevice(t *testing.T) {
 		assert.True(t, errs[capabilities.EnumerateDeviceFlag].Attached)
 		assert.False(t, errs[capabilities.ProductInformationFlag].Attached)
 	})
+	t.Run("migrates a pressure sensor wrongly enumerated from the flow measurement cluster", func(t *testing.T) {
+		mdm := &mockDeviceManager{}
+		defer mdm.AssertExpectations(t)
+
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		pmm := &attribute.MockMonitor{}
+		defer pmm.AssertExpectations(t)
+
+		fmm := &attribute.MockMonitor{}
+		defer fmm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(pmm).Once()
+		mzi.On("NewAttributeMonitor").Return(fmm).Once()
+		pmm.On("Init", mock.Anything, mock.Anything, mock.Anything)
+		fmm.On("Init", mock.Anything, mock.Anything, mock.Anything)
+
+		d := &device{m: &sync.RWMutex{}, deviceId: 1, capabilities: map[da.Capability]implcaps.ZDACapability{}}
+
+		ps := pressure_sensor.NewPressureSensor(mzi)
+		ps.Init(d, memory.New())
+		d.capabilities[capabilities.PressureSensorFlag] = ps
+
+		ed := enumerateDevice{logger: logwrap.New(discard.Discard()), capabilityFactory: func(name string, _ implcaps.ZDAInterface) implcaps.ZDACapability {
+			assert.Equal(t, factory.ZCLFlowSensor, name)
+			return flow_sensor.NewFlowSensor(mzi)
+		}, dm: mdm, gw: &ZDA{section: memory.New()}}
+
+		id := inventoryDevice{
+			uniqueId: 1,
+			endpoints: []endpointDetails{
+				{
+					rulesOutput: rules.Output{
+						Capabilities: map[string]map[string]any{
+							factory.ZCLFlowSensor: {
+								"ZigbeeEndpoint": zigbee.Endpoint(1),
+							},
+						},
+					},
+				},
+			},
+		}
+
+		fmm.On("Attach", mock.Anything, zigbee.Endpoint(1), zcl.FlowMeasurementId, mock.Anything, zcl.TypeUnsignedInt16, mock.Anything, mock.Anything).Return(nil)
+		pmm.On("Detach", mock.Anything, true).Return(nil)
+
+		mdm.On("attachCapabilityToDevice", d, mock.AnythingOfType("*flow_sensor.Implementation"))
+		mdm.On("detachCapabilityFromDevice", d, ps)
+
+		errs := ed.updateCapabilitiesOnDevice(context.Background(), d, id)
+
+		assert.True(t, errs[extcaps.FlowSensorFlag].Attached)
+		assert.False(t, errs[capabilities.PressureSensorFlag].Attached)
+	})
+
+	t.Run("unconfigures the reporting of a migrated pressure sensor before configuring the flow sensor", func(t *testing.T) {
+		mdm := &mockDeviceManager{}
+		defer mdm.AssertExpectations(t)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mp := &zigbee.MockProvider{}
+		defer mp.AssertExpectations(t)
+
+		var calls []string
+
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+		mzc.On("ConfigureReporting", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			op := "configure"
+			if args.Get(10).(uint16) == 0xffff {
+				op = "unconfigure"
+			}
+
+			calls = append(calls, fmt.Sprintf("%s %04x", op, args.Get(3).(zigbee.ClusterID)))
+		}).Return(nil)
+		mp.On("BindNodeToController", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		tl := func(da.Device, zigbee.ProfileID) (zigbee.IEEEAddress, zigbee.Endpoint, bool, uint8) {
+			return zigbee.IEEEAddress(1), zigbee.Endpoint(1), false, 0
+		}
+
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(attribute.NewMonitor(mzc, mp, tl, logwrap.New(discard.Discard()))).Once()
+		mzi.On("NewAttributeMonitor").Return(attribute.NewMonitor(mzc, mp, tl, logwrap.New(discard.Discard()))).Once()
+
+		d := &device{m: &sync.RWMutex{}, deviceId: 1, capabilities: map[da.Capability]implcaps.ZDACapability{}}
+
+		ps := pressure_sensor.NewPressureSensor(mzi)
+		ps.Init(d, memory.New())
+		_, err := ps.Enumerate(context.Background(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(1)})
+		assert.NoError(t, err)
+		d.capabilities[capabilities.PressureSensorFlag] = ps
+
+		ed := enumerateDevice{logger: logwrap.New(discard.Discard()), capabilityFactory: func(name string, _ implcaps.ZDAInterface) implcaps.ZDACapability {
+			return flow_sensor.NewFlowSensor(mzi)
+		}, dm: mdm, gw: &ZDA{section: memory.New()}}
+
+		id := inventoryDevice{
+			uniqueId: 1,
+			endpoints: []endpointDetails{
+				{
+					rulesOutput: rules.Output{
+						Capabilities: map[string]map[string]any{
+							factory.ZCLFlowSensor: {
+								"ZigbeeEndpoint": zigbee.Endpoint(1),
+							},
+						},
+					},
+				},
+			},
+		}
+
+		mdm.On("attachCapabilityToDevice", d, mock.AnythingOfType("*flow_sensor.Implementation"))
+		mdm.On("detachCapabilityFromDevice", d, ps)
+
+		errs := ed.updateCapabilitiesOnDevice(context.Background(), d, id)
+
+		assert.True(t, errs[extcaps.FlowSensorFlag].Attached)
+		assert.Equal(t, []string{"configure 0403", "unconfigure 0403", "configure 0404"}, calls)
+	})
 }
@@ -0,0 +1,31 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"time"
+)
+
+// Availability is a capability which reports whether a device is still present on the network, based upon when the
+// gateway last heard from it.
+type Availability interface {
+	// Available returns true if the device has been heard from within its timeout.
+	Available(context.Context) (bool, error)
+	// LastSeen returns when the device was last heard from.
+	LastSeen(context.Context) (time.Time, error)
+	// Timeout returns how long the device may be silent before it is considered unavailable.
+	Timeout(context.Context) (time.Duration, error)
+	// SetTimeout configures how long the device may be silent before it is considered unavailable, zero reverts to
+	// the timeout derived from the device's reporting intervals.
+	SetTimeout(context.Context, time.Duration) error
+}
+
+// AvailabilityUpdate is sent when a device goes offline or comes back online.
+type AvailabilityUpdate struct {
+	// Device whose availability changed.
+	Device da.Device
+	// Available is true if the device is online.
+	Available bool
+	// LastSeen is when the device was last heard from.
+	LastSeen time.Time
+}
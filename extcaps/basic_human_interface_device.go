@@ -0,0 +1,61 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// Button is a button present on a human interface device, such as a wall switch or remote control.
+type Button string
+
+const (
+	ButtonOn     Button = "On"
+	ButtonOff    Button = "Off"
+	ButtonToggle Button = "Toggle"
+	ButtonUp     Button = "Up"
+	ButtonDown   Button = "Down"
+	ButtonScene  Button = "Scene"
+)
+
+// ButtonAction is the action which a human performed upon a button.
+type ButtonAction uint8
+
+const (
+	ButtonPressed  ButtonAction = 0x00
+	ButtonHeld     ButtonAction = 0x01
+	ButtonReleased ButtonAction = 0x02
+)
+
+var ButtonActionNameMapping = map[ButtonAction]string{
+	ButtonPressed:  "Pressed",
+	ButtonHeld:     "Held",
+	ButtonReleased: "Released",
+}
+
+func (a ButtonAction) String() string {
+	if name, found := ButtonActionNameMapping[a]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// BasicHumanInterfaceDevice is a capability which represents a device that humans interact with to provide input,
+// such as a wall switch or remote control. The da library defines capabilities.BasicHumanInterfaceDeviceFlag, this
+// package provides the interface for it.
+type BasicHumanInterfaceDevice interface {
+	// Buttons returns the buttons the device is capable of reporting actions for.
+	Buttons(context.Context) ([]Button, error)
+}
+
+// BasicHumanInterfaceDeviceAction is sent when a human performs an action upon a button of the device.
+type BasicHumanInterfaceDeviceAction struct {
+	// Device that the action was performed upon.
+	Device da.Device
+	// Button the action was performed upon.
+	Button Button
+	// Action which was performed.
+	Action ButtonAction
+	// Scene is the scene requested to be recalled, only populated for ButtonScene.
+	Scene uint8
+}
@@ -0,0 +1,27 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// CarbonDioxideReading is a carbon dioxide concentration reading.
+type CarbonDioxideReading struct {
+	// Value contains a floating point number representing the concentration, in parts per million.
+	Value float64
+}
+
+// CarbonDioxideSensor is a capability that provides carbon dioxide concentration readings from a device. Multiple
+// readings can be returned if the device has multiple sensors.
+type CarbonDioxideSensor interface {
+	// Reading reads (or provides the most recent) carbon dioxide readings the device has.
+	Reading(context.Context) ([]CarbonDioxideReading, error)
+}
+
+// CarbonDioxideSensorUpdate is sent to inform consumers of the devices carbon dioxide values, there may be no change.
+type CarbonDioxideSensorUpdate struct {
+	// Device that is informing of its carbon dioxide readings state.
+	Device da.Device
+	// New state of device.
+	State []CarbonDioxideReading
+}
@@ -0,0 +1,44 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// CoverState is the state of a cover, positions are expressed as how closed the cover is, 0.0 being fully open and
+// 1.0 being fully closed.
+type CoverState struct {
+	// LiftSupported is true if the cover can be raised and lowered.
+	LiftSupported bool
+	// Lift is the current lift position of the cover.
+	Lift float64
+	// TiltSupported is true if the cover can be tilted.
+	TiltSupported bool
+	// Tilt is the current tilt position of the cover.
+	Tilt float64
+}
+
+// Cover is a capability which represents a device which covers an opening, such as a blind, curtain or shutter. The
+// da library defines capabilities.CoverFlag, this package provides the interface for it.
+type Cover interface {
+	// Open fully opens the cover.
+	Open(context.Context) error
+	// Close fully closes the cover.
+	Close(context.Context) error
+	// Stop halts any movement of the cover.
+	Stop(context.Context) error
+	// SetLift moves the cover to the lift position provided.
+	SetLift(context.Context, float64) error
+	// SetTilt moves the cover to the tilt position provided, an error is returned if tilt is not supported.
+	SetTilt(context.Context, float64) error
+	// Status returns the current state of the cover.
+	Status(context.Context) (CoverState, error)
+}
+
+// CoverUpdate is sent to inform consumers that a covers state has changed.
+type CoverUpdate struct {
+	// Device that has updated state.
+	Device da.Device
+	// State of the cover.
+	State CoverState
+}
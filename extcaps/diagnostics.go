@@ -0,0 +1,64 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"time"
+)
+
+// LinkQuality is the quality of the radio link from a node to the gateway.
+type LinkQuality struct {
+	// LQI is the link quality indicator of the last frame received from the node.
+	LQI uint8
+	// AverageLQI is a rolling average of the link quality indicator of frames received from the node.
+	AverageLQI float64
+	// LastReceived is the time the last frame was received from the node.
+	LastReceived time.Time
+	// RSSIPresent is true if the node has reported received signal strength via its Diagnostics cluster.
+	RSSIPresent bool
+	// RSSI is the received signal strength in dBm of the last message the node received, as reported by the node.
+	RSSI int8
+	// AverageRSSI is a rolling average of the received signal strength reported by the node.
+	AverageRSSI float64
+}
+
+// DiagnosticCounters are counters maintained by a node's network stack, read from its Diagnostics cluster.
+type DiagnosticCounters struct {
+	// NumberOfResets is the number of times the node has reset.
+	NumberOfResets uint64
+	// MACTxUnicastRetry is the number of MAC layer unicast retries.
+	MACTxUnicastRetry uint64
+	// MACTxUnicastFail is the number of MAC layer unicasts which failed after all retries.
+	MACTxUnicastFail uint64
+	// APSTxUnicastSuccess is the number of APS layer unicasts which were acknowledged.
+	APSTxUnicastSuccess uint64
+	// APSTxUnicastRetry is the number of APS layer unicast retries.
+	APSTxUnicastRetry uint64
+	// APSTxUnicastFail is the number of APS layer unicasts which failed after all retries.
+	APSTxUnicastFail uint64
+	// AverageMACRetryPerAPSMessageSent is the average number of MAC retries needed to send an APS message.
+	AverageMACRetryPerAPSMessageSent uint64
+	// LastUpdated is when the counters were read from the node.
+	LastUpdated time.Time
+}
+
+// Diagnostics is a capability which reports the health of a node's link to the gateway, to find weak links before
+// devices drop off the network.
+type Diagnostics interface {
+	// LinkQuality returns the quality of the node's link to the gateway.
+	LinkQuality(context.Context) (LinkQuality, error)
+	// Counters returns the last read diagnostic counters, false if they have never been read.
+	Counters(context.Context) (DiagnosticCounters, bool, error)
+	// ReadCounters reads the diagnostic counters from the node.
+	ReadCounters(context.Context) (DiagnosticCounters, error)
+}
+
+// DiagnosticsUpdate is sent when a node's diagnostic counters have been read.
+type DiagnosticsUpdate struct {
+	// Device whose diagnostics were read.
+	Device da.Device
+	// LinkQuality of the node at the time of the read.
+	LinkQuality LinkQuality
+	// Counters read from the node.
+	Counters DiagnosticCounters
+}
@@ -0,0 +1,176 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// LockState is the state of the bolt of a lock.
+type LockState uint8
+
+const (
+	NotFullyLocked LockState = 0x00
+	Locked         LockState = 0x01
+	Unlocked       LockState = 0x02
+	LockUnknown    LockState = 0xff
+)
+
+var LockStateNameMapping = map[LockState]string{
+	NotFullyLocked: "NotFullyLocked",
+	Locked:         "Locked",
+	Unlocked:       "Unlocked",
+	LockUnknown:    "Unknown",
+}
+
+func (s LockState) String() string {
+	if name, found := LockStateNameMapping[s]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// DoorState is the state of the door a lock is fitted to, if the lock is able to sense it.
+type DoorState uint8
+
+const (
+	DoorOpen        DoorState = 0x00
+	DoorClosed      DoorState = 0x01
+	DoorJammed      DoorState = 0x02
+	DoorForcedOpen  DoorState = 0x03
+	DoorError       DoorState = 0x04
+	DoorUnsupported DoorState = 0xfe
+	DoorUnknown     DoorState = 0xff
+)
+
+var DoorStateNameMapping = map[DoorState]string{
+	DoorOpen:        "Open",
+	DoorClosed:      "Closed",
+	DoorJammed:      "Jammed",
+	DoorForcedOpen:  "ForcedOpen",
+	DoorError:       "Error",
+	DoorUnsupported: "Unsupported",
+	DoorUnknown:     "Unknown",
+}
+
+func (s DoorState) String() string {
+	if name, found := DoorStateNameMapping[s]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// LockOperationSource is what operated a lock.
+type LockOperationSource uint8
+
+const (
+	SourceKeypad        LockOperationSource = 0x00
+	SourceRF            LockOperationSource = 0x01
+	SourceManual        LockOperationSource = 0x02
+	SourceRFID          LockOperationSource = 0x03
+	SourceIndeterminate LockOperationSource = 0xff
+)
+
+var LockOperationSourceNameMapping = map[LockOperationSource]string{
+	SourceKeypad:        "Keypad",
+	SourceRF:            "RF",
+	SourceManual:        "Manual",
+	SourceRFID:          "RFID",
+	SourceIndeterminate: "Indeterminate",
+}
+
+func (s LockOperationSource) String() string {
+	if name, found := LockOperationSourceNameMapping[s]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// LockOperation is the operation which was performed upon a lock.
+type LockOperation uint8
+
+const (
+	OperationUnknown                      LockOperation = 0x00
+	OperationLock                         LockOperation = 0x01
+	OperationUnlock                       LockOperation = 0x02
+	OperationLockFailureInvalidCode       LockOperation = 0x03
+	OperationLockFailureInvalidSchedule   LockOperation = 0x04
+	OperationUnlockFailureInvalidCode     LockOperation = 0x05
+	OperationUnlockFailureInvalidSchedule LockOperation = 0x06
+	OperationOneTouchLock                 LockOperation = 0x07
+	OperationKeyLock                      LockOperation = 0x08
+	OperationKeyUnlock                    LockOperation = 0x09
+	OperationAutoLock                     LockOperation = 0x0a
+	OperationScheduleLock                 LockOperation = 0x0b
+	OperationScheduleUnlock               LockOperation = 0x0c
+	OperationManualLock                   LockOperation = 0x0d
+	OperationManualUnlock                 LockOperation = 0x0e
+	OperationNonAccessUser                LockOperation = 0x0f
+)
+
+var LockOperationNameMapping = map[LockOperation]string{
+	OperationUnknown:                      "Unknown",
+	OperationLock:                         "Lock",
+	OperationUnlock:                       "Unlock",
+	OperationLockFailureInvalidCode:       "LockFailureInvalidCode",
+	OperationLockFailureInvalidSchedule:   "LockFailureInvalidSchedule",
+	OperationUnlockFailureInvalidCode:     "UnlockFailureInvalidCode",
+	OperationUnlockFailureInvalidSchedule: "UnlockFailureInvalidSchedule",
+	OperationOneTouchLock:                 "OneTouchLock",
+	OperationKeyLock:                      "KeyLock",
+	OperationKeyUnlock:                    "KeyUnlock",
+	OperationAutoLock:                     "AutoLock",
+	OperationScheduleLock:                 "ScheduleLock",
+	OperationScheduleUnlock:               "ScheduleUnlock",
+	OperationManualLock:                   "ManualLock",
+	OperationManualUnlock:                 "ManualUnlock",
+	OperationNonAccessUser:                "NonAccessUser",
+}
+
+func (o LockOperation) String() string {
+	if name, found := LockOperationNameMapping[o]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// DoorLockState is the state of a door lock.
+type DoorLockState struct {
+	// Lock is the state of the bolt.
+	Lock LockState
+	// Door is the state of the door, DoorUnsupported if the lock can not sense the door.
+	Door DoorState
+}
+
+// DoorLock is a capability which represents a device which locks and unlocks a door.
+type DoorLock interface {
+	// Lock locks the door, a PIN may be provided if the lock requires one, otherwise an empty string is used.
+	Lock(context.Context, string) error
+	// Unlock unlocks the door, a PIN may be provided if the lock requires one, otherwise an empty string is used.
+	Unlock(context.Context, string) error
+	// Status returns the current state of the door lock.
+	Status(context.Context) (DoorLockState, error)
+}
+
+// DoorLockUpdate is sent to inform consumers that a door locks state has changed.
+type DoorLockUpdate struct {
+	// Device that has updated state.
+	Device da.Device
+	// State of the door lock.
+	State DoorLockState
+}
+
+// DoorLockOperated is sent when a door lock reports that it has been operated, or that an operation failed.
+type DoorLockOperated struct {
+	// Device that was operated.
+	Device da.Device
+	// Source is what operated the lock.
+	Source LockOperationSource
+	// Operation is what was performed.
+	Operation LockOperation
+	// UserID is the ID of the user who operated the lock, if known.
+	UserID uint16
+}
@@ -0,0 +1,46 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// EnergyMeasurementPresent indicates which measurements a device is able to provide.
+type EnergyMeasurementPresent uint
+
+const (
+	ActivePowerPresent EnergyMeasurementPresent = 1 << iota
+	VoltagePresent
+	CurrentPresent
+	EnergyPresent
+)
+
+// EnergyMeasurementState is the state of a devices energy measurements, all values are in SI units.
+type EnergyMeasurementState struct {
+	// Present is a bitmask of which measurements are provided by the device.
+	Present EnergyMeasurementPresent
+	// ActivePower is the instantaneous active power, in watts.
+	ActivePower float64
+	// Voltage is the RMS voltage, in volts.
+	Voltage float64
+	// Current is the RMS current, in amperes.
+	Current float64
+	// Energy is the cumulative energy delivered to the device, in joules.
+	Energy float64
+}
+
+// EnergyMeasurement is a capability which represents a device which measures the electrical energy used by itself or
+// the load attached to it. The da library defines capabilities.EnergyMeasurementFlag, this package provides the
+// interface for it.
+type EnergyMeasurement interface {
+	// Reading returns the current energy measurements of the device.
+	Reading(context.Context) (EnergyMeasurementState, error)
+}
+
+// EnergyMeasurementUpdate is sent to inform consumers that a devices energy measurements have changed.
+type EnergyMeasurementUpdate struct {
+	// Device that has updated state.
+	Device da.Device
+	// State of the energy measurements.
+	State EnergyMeasurementState
+}
@@ -0,0 +1,47 @@
+package extcaps
+
+import (
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+)
+
+// EventToCapability maps a device abstraction capabilities event message back to the capability flag, including
+// the capabilities defined in this package.
+func EventToCapability(v interface{}) (da.Capability, bool) {
+	switch v.(type) {
+	case OTAUpgradeUpdate:
+		return OTAUpgradeFlag, true
+	case GroupsUpdate:
+		return GroupsFlag, true
+	case ScenesUpdate, SceneRecalled:
+		return ScenesFlag, true
+	case DiagnosticsUpdate:
+		return DiagnosticsFlag, true
+	case AvailabilityUpdate:
+		return AvailabilityFlag, true
+	case ThermostatUpdate:
+		return ThermostatFlag, true
+	case CoverUpdate:
+		return capabilities.CoverFlag, true
+	case DoorLockUpdate, DoorLockOperated:
+		return DoorLockFlag, true
+	case FanUpdate:
+		return FanFlag, true
+	case CarbonDioxideSensorUpdate:
+		return CarbonDioxideSensorFlag, true
+	case ParticulateMatterSensorUpdate:
+		return ParticulateMatterSensorFlag, true
+	case FlowSensorUpdate:
+		return FlowSensorFlag, true
+	case SoilMoistureSensorUpdate:
+		return SoilMoistureSensorFlag, true
+	case LeafWetnessSensorUpdate:
+		return LeafWetnessSensorFlag, true
+	case BasicHumanInterfaceDeviceAction:
+		return capabilities.BasicHumanInterfaceDeviceFlag, true
+	case EnergyMeasurementUpdate:
+		return capabilities.EnergyMeasurementFlag, true
+	default:
+		return capabilities.EventToCapability(v)
+	}
+}
@@ -0,0 +1,61 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// FanMode is the speed or mode of operation of a fan.
+type FanMode uint8
+
+const (
+	FanOff    FanMode = 0x00
+	FanLow    FanMode = 0x01
+	FanMedium FanMode = 0x02
+	FanHigh   FanMode = 0x03
+	FanOn     FanMode = 0x04
+	FanAuto   FanMode = 0x05
+	FanSmart  FanMode = 0x06
+)
+
+var FanModeNameMapping = map[FanMode]string{
+	FanOff:    "Off",
+	FanLow:    "Low",
+	FanMedium: "Medium",
+	FanHigh:   "High",
+	FanOn:     "On",
+	FanAuto:   "Auto",
+	FanSmart:  "Smart",
+}
+
+func (m FanMode) String() string {
+	if name, found := FanModeNameMapping[m]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// FanState is the state of a fan.
+type FanState struct {
+	// Mode is the current mode of the fan.
+	Mode FanMode
+	// SupportedModes are the modes which the fan may be set to.
+	SupportedModes []FanMode
+}
+
+// Fan is a capability which represents a device which moves air, such as a ceiling fan or air purifier.
+type Fan interface {
+	// Status returns the current state of the fan.
+	Status(context.Context) (FanState, error)
+	// SetMode sets the mode of the fan, an error is returned if the fan does not support the mode.
+	SetMode(context.Context, FanMode) error
+}
+
+// FanUpdate is sent to inform consumers that a fans state has changed.
+type FanUpdate struct {
+	// Device that has updated state.
+	Device da.Device
+	// State of the fan.
+	State FanState
+}
@@ -0,0 +1,54 @@
+// Package extcaps contains device abstraction capabilities implemented by ZDA which are not yet present in the da
+// library. Their names are registered in capabilities.StandardNames so that they are persisted and logged in the same
+// way as the standard capabilities.
+package extcaps
+
+import (
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+)
+
+const (
+	/* Basic capabilities to permit management of devices. */
+	OTAUpgradeFlag   = da.Capability(0x0110)
+	PollControlFlag  = da.Capability(0x0111)
+	GroupsFlag       = da.Capability(0x0112)
+	ScenesFlag       = da.Capability(0x0113)
+	DiagnosticsFlag  = da.Capability(0x0114)
+	AvailabilityFlag = da.Capability(0x0115)
+
+	/* Capabilities to change the physical state of devices. */
+	ThermostatFlag = da.Capability(0x1020)
+	DoorLockFlag   = da.Capability(0x1021)
+	FanFlag        = da.Capability(0x1022)
+
+	/* Capabilities to read information from the environment. */
+	CarbonDioxideSensorFlag     = da.Capability(0x2005)
+	ParticulateMatterSensorFlag = da.Capability(0x2006)
+	FlowSensorFlag              = da.Capability(0x2007)
+	SoilMoistureSensorFlag      = da.Capability(0x2008)
+	LeafWetnessSensorFlag       = da.Capability(0x2009)
+)
+
+var StandardNames = map[da.Capability]string{
+	OTAUpgradeFlag:              "OTAUpgrade",
+	PollControlFlag:             "PollControl",
+	GroupsFlag:                  "Groups",
+	ScenesFlag:                  "Scenes",
+	DiagnosticsFlag:             "Diagnostics",
+	AvailabilityFlag:            "Availability",
+	ThermostatFlag:              "Thermostat",
+	DoorLockFlag:                "DoorLock",
+	FanFlag:                     "Fan",
+	CarbonDioxideSensorFlag:     "CarbonDioxideSensor",
+	ParticulateMatterSensorFlag: "ParticulateMatterSensor",
+	FlowSensorFlag:              "FlowSensor",
+	SoilMoistureSensorFlag:      "SoilMoistureSensor",
+	LeafWetnessSensorFlag:       "LeafWetnessSensor",
+}
+
+func init() {
+	for f, n := range StandardNames {
+		capabilities.StandardNames[f] = n
+	}
+}
@@ -0,0 +1,106 @@
+package extcaps
+
+import (
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStandardNames(t *testing.T) {
+	t.Run("extension capabilities are registered with the da standard names", func(t *testing.T) {
+		for f, n := range StandardNames {
+			assert.Equal(t, n, capabilities.StandardNames[f])
+		}
+	})
+
+	t.Run("extension capabilities do not collide with da capabilities", func(t *testing.T) {
+		seen := map[string]bool{}
+
+		for _, n := range capabilities.StandardNames {
+			assert.False(t, seen[n], "duplicate capability name: %s", n)
+			seen[n] = true
+		}
+	})
+}
+
+func TestEventToCapability(t *testing.T) {
+	t.Run("maps extension events to their capability", func(t *testing.T) {
+		f, ok := EventToCapability(ThermostatUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, ThermostatFlag, f)
+
+		f, ok = EventToCapability(CoverUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, capabilities.CoverFlag, f)
+
+		f, ok = EventToCapability(DoorLockUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, DoorLockFlag, f)
+
+		f, ok = EventToCapability(DoorLockOperated{})
+		assert.True(t, ok)
+		assert.Equal(t, DoorLockFlag, f)
+
+		f, ok = EventToCapability(FanUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, FanFlag, f)
+
+		f, ok = EventToCapability(CarbonDioxideSensorUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, CarbonDioxideSensorFlag, f)
+
+		f, ok = EventToCapability(ParticulateMatterSensorUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, ParticulateMatterSensorFlag, f)
+
+		f, ok = EventToCapability(FlowSensorUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, FlowSensorFlag, f)
+
+		f, ok = EventToCapability(SoilMoistureSensorUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, SoilMoistureSensorFlag, f)
+
+		f, ok = EventToCapability(LeafWetnessSensorUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, LeafWetnessSensorFlag, f)
+
+		f, ok = EventToCapability(OTAUpgradeUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, OTAUpgradeFlag, f)
+
+		f, ok = EventToCapability(GroupsUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, GroupsFlag, f)
+
+		f, ok = EventToCapability(ScenesUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, ScenesFlag, f)
+
+		f, ok = EventToCapability(SceneRecalled{})
+		assert.True(t, ok)
+		assert.Equal(t, ScenesFlag, f)
+
+		f, ok = EventToCapability(DiagnosticsUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, DiagnosticsFlag, f)
+
+		f, ok = EventToCapability(AvailabilityUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, AvailabilityFlag, f)
+
+		f, ok = EventToCapability(BasicHumanInterfaceDeviceAction{})
+		assert.True(t, ok)
+		assert.Equal(t, capabilities.BasicHumanInterfaceDeviceFlag, f)
+
+		f, ok = EventToCapability(EnergyMeasurementUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, capabilities.EnergyMeasurementFlag, f)
+	})
+
+	t.Run("falls through to da for standard events", func(t *testing.T) {
+		f, ok := EventToCapability(capabilities.OnOffUpdate{})
+		assert.True(t, ok)
+		assert.Equal(t, capabilities.OnOffFlag, f)
+	})
+}
@@ -0,0 +1,27 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// FlowReading is a volumetric flow rate reading.
+type FlowReading struct {
+	// Value contains a floating point number representing the flow rate, in cubic meters per hour.
+	Value float64
+}
+
+// FlowSensor is a capability that provides flow rate readings from a device. Multiple readings can be returned if the
+// device has multiple sensors.
+type FlowSensor interface {
+	// Reading reads (or provides the most recent) flow readings the device has.
+	Reading(context.Context) ([]FlowReading, error)
+}
+
+// FlowSensorUpdate is sent to inform consumers of the devices flow values, there may be no change.
+type FlowSensorUpdate struct {
+	// Device that is informing of its flow readings state.
+	Device da.Device
+	// New state of device.
+	State []FlowReading
+}
@@ -0,0 +1,25 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// Groups is a capability which manages a devices membership of Zigbee groups, permitting commands to be multicast to
+// many devices at once.
+type Groups interface {
+	// AddGroup adds the device to the group.
+	AddGroup(context.Context, uint16) error
+	// RemoveGroup removes the device from the group.
+	RemoveGroup(context.Context, uint16) error
+	// Memberships returns the groups the device is a member of.
+	Memberships(context.Context) ([]uint16, error)
+}
+
+// GroupsUpdate is sent when the group memberships of a device change.
+type GroupsUpdate struct {
+	// Device whose memberships changed.
+	Device da.Device
+	// Groups the device is now a member of.
+	Groups []uint16
+}
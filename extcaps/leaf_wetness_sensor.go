@@ -0,0 +1,27 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// LeafWetnessReading is a leaf wetness reading.
+type LeafWetnessReading struct {
+	// Value contains a floating point number representing the proportion of the leaf surface that is wet, between 0.0 and 1.0.
+	Value float64
+}
+
+// LeafWetnessSensor is a capability that provides leaf wetness readings from a device. Multiple readings can be returned if the
+// device has multiple sensors.
+type LeafWetnessSensor interface {
+	// Reading reads (or provides the most recent) leaf wetness readings the device has.
+	Reading(context.Context) ([]LeafWetnessReading, error)
+}
+
+// LeafWetnessSensorUpdate is sent to inform consumers of the devices leaf wetness values, there may be no change.
+type LeafWetnessSensorUpdate struct {
+	// Device that is informing of its leaf wetness readings state.
+	Device da.Device
+	// New state of device.
+	State []LeafWetnessReading
+}
@@ -0,0 +1,86 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// OTAUpgradeStatus is the progress of a firmware upgrade of a device.
+type OTAUpgradeStatus uint8
+
+const (
+	// OTAIdle is the status of a device which has not been requested to upgrade.
+	OTAIdle OTAUpgradeStatus = 0x00
+	// OTARequested is the status of a device which has been requested to upgrade, but has not yet queried for an image.
+	OTARequested OTAUpgradeStatus = 0x01
+	// OTADownloading is the status of a device which is downloading an image.
+	OTADownloading OTAUpgradeStatus = 0x02
+	// OTAComplete is the status of a device which has downloaded an image and has been told to apply it.
+	OTAComplete OTAUpgradeStatus = 0x03
+	// OTAUpToDate is the status of a device which was requested to upgrade, but no newer image is available.
+	OTAUpToDate OTAUpgradeStatus = 0x04
+	// OTAFailed is the status of a device which failed to download or validate an image.
+	OTAFailed OTAUpgradeStatus = 0x05
+	// OTACancelled is the status of a device whose upgrade was cancelled.
+	OTACancelled OTAUpgradeStatus = 0x06
+)
+
+var OTAUpgradeStatusNameMapping = map[OTAUpgradeStatus]string{
+	OTAIdle:        "Idle",
+	OTARequested:   "Requested",
+	OTADownloading: "Downloading",
+	OTAComplete:    "Complete",
+	OTAUpToDate:    "UpToDate",
+	OTAFailed:      "Failed",
+	OTACancelled:   "Cancelled",
+}
+
+func (s OTAUpgradeStatus) String() string {
+	if name, found := OTAUpgradeStatusNameMapping[s]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// OTAUpgradeState is the state of a firmware upgrade of a device.
+type OTAUpgradeState struct {
+	// Status of the upgrade.
+	Status OTAUpgradeStatus
+	// CurrentVersion is the file version the device last reported it was running.
+	CurrentVersion uint32
+	// TargetVersion is the file version of the image being upgraded to.
+	TargetVersion uint32
+	// Offset is the number of bytes of the image the device has downloaded.
+	Offset uint32
+	// Size is the total size of the image being upgraded to, in bytes.
+	Size uint32
+}
+
+// Progress returns the fraction of the image downloaded, between 0.0 and 1.0.
+func (s OTAUpgradeState) Progress() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+
+	return float64(s.Offset) / float64(s.Size)
+}
+
+// OTAUpgrade is a capability which permits the firmware of a device to be upgraded over the air.
+type OTAUpgrade interface {
+	// Start requests the device upgrades to the latest image available, the upgrade begins when the device next
+	// queries for an image.
+	Start(context.Context) error
+	// Cancel aborts any upgrade in progress.
+	Cancel(context.Context) error
+	// Status returns the state of the devices upgrade.
+	Status(context.Context) (OTAUpgradeState, error)
+}
+
+// OTAUpgradeUpdate is sent to inform consumers of the progress of a devices upgrade.
+type OTAUpgradeUpdate struct {
+	// Device that is being upgraded.
+	Device da.Device
+	// New state of device.
+	State OTAUpgradeState
+}
@@ -0,0 +1,28 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// ParticulateMatterReading is a PM2.5 particulate matter concentration reading.
+type ParticulateMatterReading struct {
+	// Value contains a floating point number representing the concentration, in micrograms per cubic meter.
+	Value float64
+}
+
+// ParticulateMatterSensor is a capability that provides PM2.5 particulate matter readings from a device. Multiple
+// readings can be returned if the device has multiple sensors.
+type ParticulateMatterSensor interface {
+	// Reading reads (or provides the most recent) particulate matter readings the device has.
+	Reading(context.Context) ([]ParticulateMatterReading, error)
+}
+
+// ParticulateMatterSensorUpdate is sent to inform consumers of the devices particulate matter values, there may be no
+// change.
+type ParticulateMatterSensorUpdate struct {
+	// Device that is informing of its particulate matter readings state.
+	Device da.Device
+	// New state of device.
+	State []ParticulateMatterReading
+}
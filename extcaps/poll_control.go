@@ -0,0 +1,15 @@
+package extcaps
+
+import (
+	"context"
+	"time"
+)
+
+// PollControl is a capability of sleepy devices which periodically check in with the gateway, commands may be held
+// until the device checks in so that they are received reliably.
+type PollControl interface {
+	// CheckInInterval returns how often the device checks in with the gateway, zero if unknown.
+	CheckInInterval(context.Context) (time.Duration, error)
+	// QueuedCommands returns the number of commands held until the device next checks in.
+	QueuedCommands(context.Context) (int, error)
+}
@@ -0,0 +1,53 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"time"
+)
+
+// Scene is a scene stored on a device, scenes are identified by the group they belong to and their id within it.
+type Scene struct {
+	// Group the scene belongs to, 0 is used for scenes which are not associated with a group.
+	Group uint16
+	// ID of the scene within the group.
+	ID uint8
+	// Name of the scene.
+	Name string
+	// TransitionTime is the time taken to move to the scene when it is recalled.
+	TransitionTime time.Duration
+}
+
+// Scenes is a capability which manages scenes stored on a device, permitting them to be recalled instantly.
+type Scenes interface {
+	// AddScene creates the scene on the device, its state is captured later with StoreScene.
+	AddScene(context.Context, Scene) error
+	// StoreScene captures the devices current state into the scene.
+	StoreScene(context.Context, uint16, uint8) error
+	// RecallScene moves the device to the state stored in the scene.
+	RecallScene(context.Context, uint16, uint8) error
+	// RemoveScene deletes the scene from the device.
+	RemoveScene(context.Context, uint16, uint8) error
+	// ViewScene queries the device for the scene.
+	ViewScene(context.Context, uint16, uint8) (Scene, error)
+	// Scenes returns the scenes known to be stored on the device.
+	Scenes(context.Context) ([]Scene, error)
+	// ActiveScene returns the scene the device was last moved to, if its state has not changed since.
+	ActiveScene(context.Context) (Scene, bool, error)
+}
+
+// ScenesUpdate is sent when the scenes stored on a device change.
+type ScenesUpdate struct {
+	// Device whose scenes changed.
+	Device da.Device
+	// Scenes stored on the device.
+	Scenes []Scene
+}
+
+// SceneRecalled is sent when a device moves to a scene, whether recalled by the gateway or another device.
+type SceneRecalled struct {
+	// Device which recalled the scene.
+	Device da.Device
+	// Scene which was recalled.
+	Scene Scene
+}
@@ -0,0 +1,27 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// SoilMoistureReading is a soil moisture reading.
+type SoilMoistureReading struct {
+	// Value contains a floating point number representing the volumetric water content of the soil, between 0.0 and 1.0.
+	Value float64
+}
+
+// SoilMoistureSensor is a capability that provides soil moisture readings from a device. Multiple readings can be returned if the
+// device has multiple sensors.
+type SoilMoistureSensor interface {
+	// Reading reads (or provides the most recent) soil moisture readings the device has.
+	Reading(context.Context) ([]SoilMoistureReading, error)
+}
+
+// SoilMoistureSensorUpdate is sent to inform consumers of the devices soil moisture values, there may be no change.
+type SoilMoistureSensorUpdate struct {
+	// Device that is informing of its soil moisture readings state.
+	Device da.Device
+	// New state of device.
+	State []SoilMoistureReading
+}
@@ -0,0 +1,90 @@
+package extcaps
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+)
+
+// ThermostatMode is the mode of operation of a thermostat.
+type ThermostatMode uint8
+
+const (
+	ThermostatOff           ThermostatMode = 0x00
+	ThermostatAuto          ThermostatMode = 0x01
+	ThermostatCool          ThermostatMode = 0x03
+	ThermostatHeat          ThermostatMode = 0x04
+	ThermostatEmergencyHeat ThermostatMode = 0x05
+	ThermostatPrecooling    ThermostatMode = 0x06
+	ThermostatFanOnly       ThermostatMode = 0x07
+	ThermostatDry           ThermostatMode = 0x08
+	ThermostatSleep         ThermostatMode = 0x09
+)
+
+var ThermostatModeNameMapping = map[ThermostatMode]string{
+	ThermostatOff:           "Off",
+	ThermostatAuto:          "Auto",
+	ThermostatCool:          "Cool",
+	ThermostatHeat:          "Heat",
+	ThermostatEmergencyHeat: "EmergencyHeat",
+	ThermostatPrecooling:    "Precooling",
+	ThermostatFanOnly:       "FanOnly",
+	ThermostatDry:           "Dry",
+	ThermostatSleep:         "Sleep",
+}
+
+func (m ThermostatMode) String() string {
+	if name, found := ThermostatModeNameMapping[m]; found {
+		return name
+	} else {
+		return "Unknown"
+	}
+}
+
+// ThermostatSetpoint identifies which setpoint of a thermostat is being adjusted.
+type ThermostatSetpoint uint8
+
+const (
+	HeatingSetpoint ThermostatSetpoint = 0x00
+	CoolingSetpoint ThermostatSetpoint = 0x01
+	BothSetpoints   ThermostatSetpoint = 0x02
+)
+
+// ThermostatState is the state of a thermostat, all temperatures are in Kelvin.
+type ThermostatState struct {
+	// LocalTemperature is the temperature measured by the thermostat.
+	LocalTemperature float64
+	// HeatingSetpoint is the temperature the thermostat heats to.
+	HeatingSetpoint float64
+	// CoolingSetpoint is the temperature the thermostat cools to.
+	CoolingSetpoint float64
+	// Mode is the current system mode of the thermostat.
+	Mode ThermostatMode
+	// Heating is true if the thermostat is currently calling for heat.
+	Heating bool
+	// Cooling is true if the thermostat is currently calling for cooling.
+	Cooling bool
+	// Fan is true if the thermostat is currently running a fan.
+	Fan bool
+}
+
+// Thermostat is a capability which represents a device which controls heating or cooling to a setpoint.
+type Thermostat interface {
+	// Status returns the current state of the thermostat.
+	Status(context.Context) (ThermostatState, error)
+	// SetHeatingSetpoint sets the temperature the thermostat heats to, in Kelvin.
+	SetHeatingSetpoint(context.Context, float64) error
+	// SetCoolingSetpoint sets the temperature the thermostat cools to, in Kelvin.
+	SetCoolingSetpoint(context.Context, float64) error
+	// SetMode sets the system mode of the thermostat.
+	SetMode(context.Context, ThermostatMode) error
+	// AdjustSetpoint raises or lowers the setpoint relative to its current value, in Kelvin.
+	AdjustSetpoint(context.Context, ThermostatSetpoint, float64) error
+}
+
+// ThermostatUpdate is sent to inform consumers that a thermostats state has changed.
+type ThermostatUpdate struct {
+	// Device that has updated state.
+	Device da.Device
+	// State of the thermostat.
+	State ThermostatState
+}
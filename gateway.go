@@ -9,23 +9,35 @@ import (
 	"github.com/shimmeringbee/persistence"
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
 	"github.com/shimmeringbee/zcl/communicator"
 	"github.com/shimmeringbee/zda/implcaps"
 	"github.com/shimmeringbee/zda/implcaps/factory"
+	"github.com/shimmeringbee/zda/implcaps/zcl/scenes"
+	"github.com/shimmeringbee/zda/ota"
 	"github.com/shimmeringbee/zda/rules"
 	"github.com/shimmeringbee/zigbee"
 	"log"
 	"os"
 	"sync"
+	"time"
 )
 
 const DefaultGatewayHomeAutomationEndpoint = zigbee.Endpoint(0x01)
 
+// gatewayInClusters are the server clusters the gateway advertises on DefaultGatewayHomeAutomationEndpoint, so that
+// devices searching for a server (such as an OTA Upgrade or Time server) can discover the gateway.
+var gatewayInClusters = []zigbee.ClusterID{zcl.TimeId, zcl.OTAUpgradeId}
+
 func New(baseCtx context.Context, s persistence.Section, p zigbee.Provider, r ruleExecutor) *ZDA {
 	ctx, cancel := context.WithCancel(baseCtx)
 
 	zclCommandRegistry := zcl.NewCommandRegistry()
 	global.Register(zclCommandRegistry)
+	onoff.Register(zclCommandRegistry)
+	level.Register(zclCommandRegistry)
+	scenes.Register(zclCommandRegistry)
 
 	gw := &ZDA{
 		provider:           p,
@@ -48,11 +60,22 @@ func New(baseCtx context.Context, s persistence.Section, p zigbee.Provider, r ru
 		ruleExecutor: r,
 
 		events: make(chan any, 1),
+
+		groupSequence: makeTransactionSequence(),
+		rejoinPolicy:  RejoinIgnore,
+
+		tombstoneLock: &sync.Mutex{},
 	}
 
 	gw.zdaInterface = zdaInterface{
 		gw: gw,
-		c:  gw.zclCommunicator,
+		c:  queuingCommunicator{Communicator: gw.zclCommunicator, gw: gw},
+	}
+
+	gw.timeServer = &timeServer{
+		gw:       gw,
+		location: time.Local,
+		now:      time.Now,
 	}
 
 	gw.WithGoLogger(log.New(os.Stderr, "", log.LstdFlags))
@@ -72,6 +95,7 @@ func New(baseCtx context.Context, s persistence.Section, p zigbee.Provider, r ru
 	}
 
 	gw.callbacks.Add(gw.ed.onNodeJoin)
+	gw.callbacks.Add(gw.ed.onNodeReenumerate)
 
 	return gw
 }
@@ -102,6 +126,14 @@ type ZDA struct {
 	ed                 *enumerateDevice
 	events             chan any
 	zclCommandRegistry *zcl.CommandRegistry
+
+	otaImageStore ota.ImageStore
+	timeServer    *timeServer
+	groupSequence chan uint8
+	rejoinPolicy  RejoinPolicy
+
+	tombstoneLock      *sync.Mutex
+	tombstoneRetention time.Duration
 }
 
 func (z *ZDA) Capabilities() []da.Capability {
@@ -152,13 +184,19 @@ func (z *ZDA) Start(ctx context.Context) error {
 
 	z.logger.LogInfo(z.ctx, "Adapter coordinator IEEE address.", logwrap.Datum("IEEEAddress", z.selfDevice.Identifier().String()))
 
-	if err := z.provider.RegisterAdapterEndpoint(ctx, DefaultGatewayHomeAutomationEndpoint, zigbee.ProfileHomeAutomation, 1, 1, []zigbee.ClusterID{}, []zigbee.ClusterID{}); err != nil {
+	if err := z.provider.RegisterAdapterEndpoint(ctx, DefaultGatewayHomeAutomationEndpoint, zigbee.ProfileHomeAutomation, 1, 1, gatewayInClusters, []zigbee.ClusterID{}); err != nil {
 		z.logger.LogError(z.ctx, "Failed to register endpoint against adapter.", logwrap.Datum("Endpoint", DefaultGatewayHomeAutomationEndpoint), logwrap.Err(err))
 		return err
 	}
 
+	z.zclCommunicator.RegisterMatch(z.timeServer.match())
+
 	go z.providerLoop()
 
+	if z.tombstoneRetention > 0 {
+		go z.tombstonePurger(TombstonePurgeInterval)
+	}
+
 	return nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/shimmeringbee/logwrap"
 	"github.com/shimmeringbee/logwrap/impl/discard"
 	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zigbee"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -61,6 +62,16 @@ func Test_gateway_Start(t *testing.T) {
 		assert.Equal(t, gw, self.Gateway())
 		assert.Contains(t, self.Capabilities(), capabilities.DeviceDiscoveryFlag)
 	})
+
+	t.Run("registers the gateway endpoint as a time and ota upgrade server", func(t *testing.T) {
+		gw, mp, _, stop := newTestGateway()
+		defer stop(t)
+
+		mp.On("RegisterAdapterEndpoint", mock.Anything, DefaultGatewayHomeAutomationEndpoint, zigbee.ProfileHomeAutomation, uint16(1), uint8(1), []zigbee.ClusterID{zcl.TimeId, zcl.OTAUpgradeId}, []zigbee.ClusterID{}).Return(nil).Once()
+
+		err := gw.Start(nil)
+		assert.NoError(t, err)
+	})
 }
 
 func Test_gateway_Stop(t *testing.T) {
@@ -4,6 +4,7 @@ go 1.22.0
 
 require (
 	github.com/expr-lang/expr v1.16.9
+	github.com/shimmeringbee/bytecodec v0.0.0-20240614104652-9d31c74dcd13
 	github.com/shimmeringbee/callbacks v0.0.0-20240614104656-b56cd6b4b604
 	github.com/shimmeringbee/da v0.0.0-20240714070346-b84fc2e73097
 	github.com/shimmeringbee/logwrap v0.1.3
@@ -19,7 +20,6 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/shimmeringbee/bytecodec v0.0.0-20240614104652-9d31c74dcd13 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
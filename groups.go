@@ -0,0 +1,287 @@
+package zda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/implcaps/zcl/scenes"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// GroupSender is implemented by zigbee providers which are able to multicast messages to a group. zigbee.Provider has no
+// group transmission, and no provider currently implements GroupSender, so by default group commands are unicast to
+// each member of the group in turn. A provider must implement GroupSender for group commands to be multicast.
+type GroupSender interface {
+	SendApplicationMessageToGroup(ctx context.Context, group zigbee.GroupID, message zigbee.ApplicationMessage) error
+}
+
+// groupEndpoint is implemented by Groups capabilities, providing the endpoint group commands are unicast to.
+type groupEndpoint interface {
+	RemoteEndpoint() zigbee.Endpoint
+}
+
+var ErrDeviceNotFound = errors.New("device not found")
+var ErrDeviceDoesNotSupportGroups = errors.New("device does not support groups")
+
+// broadcastEndpoint is used as the destination endpoint of group messages, every endpoint in the group receives them.
+const broadcastEndpoint = zigbee.Endpoint(0xff)
+
+func (z *ZDA) sectionForGroup(g zigbee.GroupID) persistence.Section {
+	return z.section.Section("Group", strconv.Itoa(int(g)))
+}
+
+// AddDeviceToGroup adds the device to the group, the device must have the Groups capability.
+func (z *ZDA) AddDeviceToGroup(ctx context.Context, d da.Device, g zigbee.GroupID) error {
+	addr, gc, err := z.groupsCapability(d)
+	if err != nil {
+		return err
+	}
+
+	if err := gc.AddGroup(ctx, uint16(g)); err != nil {
+		return err
+	}
+
+	z.sectionForGroup(g).Section("Member", addr.IEEEAddress.String()).Set(strconv.Itoa(int(addr.SubIdentifier)), true)
+	return nil
+}
+
+// RemoveDeviceFromGroup removes the device from the group, the group is forgotten once it has no members.
+func (z *ZDA) RemoveDeviceFromGroup(ctx context.Context, d da.Device, g zigbee.GroupID) error {
+	addr, gc, err := z.groupsCapability(d)
+	if err != nil {
+		return err
+	}
+
+	if err := gc.RemoveGroup(ctx, uint16(g)); err != nil {
+		return err
+	}
+
+	z.sectionRemoveGroupMember(g, addr)
+	return nil
+}
+
+func (z *ZDA) groupsCapability(d da.Device) (IEEEAddressWithSubIdentifier, extcaps.Groups, error) {
+	addr, ok := d.Identifier().(IEEEAddressWithSubIdentifier)
+	if !ok {
+		return addr, nil, ErrDeviceNotFound
+	}
+
+	dd := z.getDevice(addr)
+	if dd == nil {
+		return addr, nil, ErrDeviceNotFound
+	}
+
+	gc, ok := dd.Capability(extcaps.GroupsFlag).(extcaps.Groups)
+	if !ok {
+		return addr, nil, ErrDeviceDoesNotSupportGroups
+	}
+
+	return addr, gc, nil
+}
+
+func (z *ZDA) sectionRemoveGroupMember(g zigbee.GroupID, addr IEEEAddressWithSubIdentifier) {
+	gs := z.sectionForGroup(g)
+	ns := gs.Section("Member", addr.IEEEAddress.String())
+	ns.Delete(strconv.Itoa(int(addr.SubIdentifier)))
+
+	if len(ns.Keys()) == 0 {
+		gs.Section("Member").SectionDelete(addr.IEEEAddress.String())
+	}
+
+	if len(gs.Section("Member").SectionKeys()) == 0 {
+		z.section.Section("Group").SectionDelete(strconv.Itoa(int(g)))
+	}
+}
+
+// sectionRemoveDeviceFromGroups forgets a removed device's memberships of all groups.
+func (z *ZDA) sectionRemoveDeviceFromGroups(addr IEEEAddressWithSubIdentifier) {
+	for _, g := range z.Groups() {
+		z.sectionRemoveGroupMember(g, addr)
+	}
+}
+
+// sectionMoveNodeGroupMemberships moves the group memberships of a node's devices to a new address.
+func (z *ZDA) sectionMoveNodeGroupMemberships(from zigbee.IEEEAddress, to zigbee.IEEEAddress) {
+	for _, g := range z.Groups() {
+		ms := z.sectionForGroup(g).Section("Member")
+
+		if ms.SectionExists(from.String()) {
+			copySection(ms.Section(to.String()), ms.Section(from.String()))
+			ms.SectionDelete(from.String())
+		}
+	}
+}
+
+// Groups returns the groups which have members.
+func (z *ZDA) Groups() []zigbee.GroupID {
+	var groups []zigbee.GroupID
+
+	for _, k := range z.section.Section("Group").SectionKeys() {
+		if g, err := strconv.ParseUint(k, 10, 16); err == nil {
+			groups = append(groups, zigbee.GroupID(g))
+		}
+	}
+
+	slices.Sort(groups)
+	return groups
+}
+
+// GroupMembers returns the devices which are members of the group.
+func (z *ZDA) GroupMembers(g zigbee.GroupID) []da.Device {
+	var devices []da.Device
+
+	for _, d := range z.groupMembers(g) {
+		devices = append(devices, d)
+	}
+
+	return devices
+}
+
+func (z *ZDA) groupMembers(g zigbee.GroupID) []*device {
+	var devices []*device
+
+	ms := z.sectionForGroup(g).Section("Member")
+
+	for _, nk := range ms.SectionKeys() {
+		ieee, err := strconv.ParseUint(nk, 16, 64)
+		if err != nil {
+			continue
+		}
+
+		for _, dk := range ms.Section(nk).Keys() {
+			sub, err := strconv.ParseUint(dk, 10, 8)
+			if err != nil {
+				continue
+			}
+
+			if d := z.getDevice(IEEEAddressWithSubIdentifier{IEEEAddress: zigbee.IEEEAddress(ieee), SubIdentifier: uint8(sub)}); d != nil {
+				devices = append(devices, d)
+			}
+		}
+	}
+
+	return devices
+}
+
+// GroupOn sends On to the group, updating the state of every member.
+func (z *ZDA) GroupOn(ctx context.Context, g zigbee.GroupID) error {
+	return z.sendToGroup(ctx, g, zcl.OnOffId, onoff.OnId, &onoff.On{})
+}
+
+// GroupOff sends Off to the group, updating the state of every member.
+func (z *ZDA) GroupOff(ctx context.Context, g zigbee.GroupID) error {
+	return z.sendToGroup(ctx, g, zcl.OnOffId, onoff.OffId, &onoff.Off{})
+}
+
+// GroupSetLevel sends Move To Level (with On/Off) to the group, level is between 0.0 and 1.0. The state of every
+// member is updated.
+func (z *ZDA) GroupSetLevel(ctx context.Context, g zigbee.GroupID, lvl float64, duration time.Duration) error {
+	return z.sendToGroup(ctx, g, zcl.LevelControlId, level.MoveToLevelWithOnOffId, &level.MoveToLevelWithOnOff{
+		Level:          uint8(math.Round(math.Max(0, math.Min(1.0, lvl)) * 254)),
+		TransitionTime: uint16(math.Min(0xfffe, math.Round(float64(duration)/float64(100*time.Millisecond)))),
+	})
+}
+
+// GroupRecallScene sends Recall Scene to the group, moving every member to the scene stored for the group.
+func (z *ZDA) GroupRecallScene(ctx context.Context, g zigbee.GroupID, scene uint8) error {
+	return z.sendToGroup(ctx, g, zcl.ScenesId, scenes.RecallSceneId, &scenes.RecallScene{GroupID: uint16(g), SceneID: scene})
+}
+
+func (z *ZDA) sendToGroup(ctx context.Context, g zigbee.GroupID, cluster zigbee.ClusterID, id zcl.CommandIdentifier, cmd any) error {
+	msg := zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: z.nextGroupTransactionSequence(),
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           cluster,
+		SourceEndpoint:      DefaultGatewayHomeAutomationEndpoint,
+		DestinationEndpoint: broadcastEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	}
+
+	members := z.groupMembers(g)
+
+	gs, ok := z.provider.(GroupSender)
+	if !ok {
+		return z.unicastToGroup(ctx, g, members, msg)
+	}
+
+	appMsg, err := z.zclCommandRegistry.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal group message: %w", err)
+	}
+
+	if err := gs.SendApplicationMessageToGroup(ctx, g, appMsg); err != nil {
+		return err
+	}
+
+	z.logger.LogDebug(ctx, "Multicast command to group.", logwrap.Datum("GroupID", g), logwrap.Datum("ClusterID", cluster), logwrap.Datum("Members", len(members)))
+
+	for _, d := range members {
+		informGroupMember(d, msg)
+	}
+
+	return nil
+}
+
+// unicastToGroup sends a group command to each member of the group in turn, for providers which can not multicast.
+// Members which fail to receive the command are not informed of it, and their errors are returned.
+func (z *ZDA) unicastToGroup(ctx context.Context, g zigbee.GroupID, members []*device, msg zcl.Message) error {
+	z.logger.LogDebug(ctx, "Unicasting command to group members.", logwrap.Datum("GroupID", g), logwrap.Datum("ClusterID", msg.ClusterID), logwrap.Datum("Members", len(members)))
+
+	var errs []error
+
+	for _, d := range members {
+		dmsg := msg
+
+		d.m.RLock()
+		if ge, ok := d.capabilities[extcaps.GroupsFlag].(groupEndpoint); ok {
+			dmsg.DestinationEndpoint = ge.RemoteEndpoint()
+		}
+		d.m.RUnlock()
+
+		var ieee zigbee.IEEEAddress
+		var ack bool
+		ieee, dmsg.SourceEndpoint, ack, dmsg.TransactionSequence = z.transmissionLookup(d, zigbee.ProfileHomeAutomation)
+
+		if err := z.zclCommunicator.Request(ctx, ieee, ack, dmsg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send group command to %s: %w", d.address, err))
+			continue
+		}
+
+		informGroupMember(d, msg)
+	}
+
+	return errors.Join(errs...)
+}
+
+func informGroupMember(d *device, msg zcl.Message) {
+	d.m.RLock()
+	defer d.m.RUnlock()
+
+	for _, c := range d.capabilities {
+		if o, ok := c.(implcaps.GroupCommandObserver); ok {
+			o.GroupCommand(msg)
+		}
+	}
+}
+
+func (z *ZDA) nextGroupTransactionSequence() uint8 {
+	nextSeq := <-z.groupSequence
+	z.groupSequence <- nextSeq
+
+	return nextSeq
+}
@@ -0,0 +1,290 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/implcaps/zcl/scenes"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+var _ GroupSender = (*mockGroupSendingProvider)(nil)
+
+type mockGroupSendingProvider struct {
+	*zigbee.MockProvider
+}
+
+func (m *mockGroupSendingProvider) SendApplicationMessageToGroup(ctx context.Context, group zigbee.GroupID, message zigbee.ApplicationMessage) error {
+	args := m.Called(ctx, group, message)
+	return args.Error(0)
+}
+
+type mockGroupsCapability struct {
+	mock.Mock
+}
+
+func (m *mockGroupsCapability) Capability() da.Capability {
+	return extcaps.GroupsFlag
+}
+
+func (m *mockGroupsCapability) Name() string {
+	return "Groups"
+}
+
+func (m *mockGroupsCapability) Init(_ da.Device, _ persistence.Section) {}
+
+func (m *mockGroupsCapability) Load(_ context.Context) (bool, error) {
+	return true, nil
+}
+
+func (m *mockGroupsCapability) Enumerate(_ context.Context, _ map[string]any) (bool, error) {
+	return true, nil
+}
+
+func (m *mockGroupsCapability) Detach(_ context.Context, _ implcaps.DetachType) error {
+	return nil
+}
+
+func (m *mockGroupsCapability) ImplName() string {
+	return "MockGroups"
+}
+
+func (m *mockGroupsCapability) AddGroup(ctx context.Context, group uint16) error {
+	return m.Called(ctx, group).Error(0)
+}
+
+func (m *mockGroupsCapability) RemoveGroup(ctx context.Context, group uint16) error {
+	return m.Called(ctx, group).Error(0)
+}
+
+func (m *mockGroupsCapability) Memberships(_ context.Context) ([]uint16, error) {
+	return nil, nil
+}
+
+func (m *mockGroupsCapability) GroupCommand(msg zcl.Message) {
+	m.Called(msg)
+}
+
+func (m *mockGroupsCapability) RemoteEndpoint() zigbee.Endpoint {
+	return 5
+}
+
+func newTestGroupGateway(t *testing.T) (*ZDA, *mockGroupSendingProvider) {
+	mp := &mockGroupSendingProvider{MockProvider: &zigbee.MockProvider{}}
+	t.Cleanup(func() { mp.AssertExpectations(t) })
+
+	gw := New(context.Background(), memory.New(), mp, nil)
+	gw.WithLogWrapLogger(logwrap.New(discard.Discard()))
+	gw.events = make(chan any, 0xffff)
+
+	return gw, mp
+}
+
+func newTestGroupDevice(t *testing.T, gw *ZDA) (*device, *mockGroupsCapability) {
+	mgc := &mockGroupsCapability{}
+	t.Cleanup(func() { mgc.AssertExpectations(t) })
+
+	n, _ := gw.createNode(zigbee.GenerateLocalAdministeredIEEEAddress())
+	d := gw.createNextDevice(n)
+	d.capabilities[extcaps.GroupsFlag] = mgc
+
+	return d, mgc
+}
+
+func TestZDA_AddDeviceToGroup(t *testing.T) {
+	t.Run("adds the device to the group and records the membership", func(t *testing.T) {
+		gw, _ := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+
+		err := gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []zigbee.GroupID{0x1234}, gw.Groups())
+		assert.Equal(t, []da.Device{d}, gw.GroupMembers(0x1234))
+	})
+
+	t.Run("does not record the membership if the device fails to add the group", func(t *testing.T) {
+		gw, _ := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(io.EOF)
+
+		err := gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Empty(t, gw.Groups())
+	})
+
+	t.Run("errors if the device does not support groups", func(t *testing.T) {
+		gw, _ := newTestGroupGateway(t)
+
+		n, _ := gw.createNode(zigbee.GenerateLocalAdministeredIEEEAddress())
+		d := gw.createNextDevice(n)
+
+		err := gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+		assert.ErrorIs(t, err, ErrDeviceDoesNotSupportGroups)
+	})
+}
+
+func TestZDA_RemoveDeviceFromGroup(t *testing.T) {
+	t.Run("removes the device from the group and forgets the empty group", func(t *testing.T) {
+		gw, _ := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+		other, omgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		mgc.On("RemoveGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		omgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		omgc.On("RemoveGroup", mock.Anything, uint16(0x1234)).Return(nil)
+
+		_ = gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+		_ = gw.AddDeviceToGroup(context.Background(), other, 0x1234)
+
+		err := gw.RemoveDeviceFromGroup(context.Background(), d, 0x1234)
+		assert.NoError(t, err)
+		assert.Equal(t, []da.Device{other}, gw.GroupMembers(0x1234))
+
+		err = gw.RemoveDeviceFromGroup(context.Background(), other, 0x1234)
+		assert.NoError(t, err)
+		assert.Empty(t, gw.Groups())
+	})
+
+	t.Run("removing a device from the gateway removes its memberships", func(t *testing.T) {
+		gw, _ := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		_ = gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+
+		_ = gw.removeDevice(context.Background(), d.address)
+		assert.Empty(t, gw.Groups())
+	})
+}
+
+func TestZDA_GroupOn(t *testing.T) {
+	t.Run("multicasts on to the group and informs every member", func(t *testing.T) {
+		gw, mp := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		_ = gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+
+		mp.On("SendApplicationMessageToGroup", mock.Anything, zigbee.GroupID(0x1234), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			msg := args.Get(2).(zigbee.ApplicationMessage)
+			assert.Equal(t, zcl.OnOffId, msg.ClusterID)
+			assert.Equal(t, DefaultGatewayHomeAutomationEndpoint, msg.SourceEndpoint)
+			assert.Equal(t, zigbee.Endpoint(0xff), msg.DestinationEndpoint)
+		})
+
+		mgc.On("GroupCommand", mock.MatchedBy(func(m zcl.Message) bool {
+			return m.ClusterID == zcl.OnOffId && m.CommandIdentifier == onoff.OnId
+		}))
+
+		err := gw.GroupOn(context.Background(), 0x1234)
+		assert.NoError(t, err)
+	})
+
+	t.Run("does not inform members if sending fails", func(t *testing.T) {
+		gw, mp := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		_ = gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+
+		mp.On("SendApplicationMessageToGroup", mock.Anything, zigbee.GroupID(0x1234), mock.Anything).Return(io.EOF)
+
+		err := gw.GroupOn(context.Background(), 0x1234)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("unicasts to each member if the provider cannot multicast, informing those which received it", func(t *testing.T) {
+		mp := &zigbee.MockProvider{}
+		defer mp.AssertExpectations(t)
+
+		gw := New(context.Background(), memory.New(), mp, nil)
+		gw.WithLogWrapLogger(logwrap.New(discard.Discard()))
+		gw.events = make(chan any, 0xffff)
+
+		received, rmgc := newTestGroupDevice(t, gw)
+		failed, fmgc := newTestGroupDevice(t, gw)
+
+		for _, mgc := range []*mockGroupsCapability{rmgc, fmgc} {
+			mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		}
+
+		_ = gw.AddDeviceToGroup(context.Background(), received, 0x1234)
+		_ = gw.AddDeviceToGroup(context.Background(), failed, 0x1234)
+
+		isOn := mock.MatchedBy(func(m zigbee.ApplicationMessage) bool {
+			return m.ClusterID == zcl.OnOffId && m.SourceEndpoint == DefaultGatewayHomeAutomationEndpoint && m.DestinationEndpoint == 5
+		})
+
+		mp.On("SendApplicationMessageToNode", mock.Anything, received.n.address, isOn, false).Return(nil)
+		mp.On("SendApplicationMessageToNode", mock.Anything, failed.n.address, isOn, false).Return(io.EOF)
+
+		rmgc.On("GroupCommand", mock.MatchedBy(func(m zcl.Message) bool {
+			return m.ClusterID == zcl.OnOffId && m.CommandIdentifier == onoff.OnId
+		}))
+
+		err := gw.GroupOn(context.Background(), 0x1234)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestZDA_GroupSetLevel(t *testing.T) {
+	t.Run("multicasts move to level with on off to the group and informs every member", func(t *testing.T) {
+		gw, mp := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		_ = gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+
+		mp.On("SendApplicationMessageToGroup", mock.Anything, zigbee.GroupID(0x1234), mock.MatchedBy(func(m zigbee.ApplicationMessage) bool {
+			return m.ClusterID == zcl.LevelControlId && m.DestinationEndpoint == zigbee.Endpoint(0xff)
+		})).Return(nil)
+
+		mgc.On("GroupCommand", mock.MatchedBy(func(m zcl.Message) bool {
+			cmd, ok := m.Command.(*level.MoveToLevelWithOnOff)
+			return ok && *cmd == level.MoveToLevelWithOnOff{Level: 127, TransitionTime: 10}
+		}))
+
+		err := gw.GroupSetLevel(context.Background(), 0x1234, 0.5, time.Second)
+		assert.NoError(t, err)
+	})
+}
+
+func TestZDA_GroupRecallScene(t *testing.T) {
+	t.Run("multicasts recall scene to the group and informs every member", func(t *testing.T) {
+		gw, mp := newTestGroupGateway(t)
+		d, mgc := newTestGroupDevice(t, gw)
+
+		mgc.On("AddGroup", mock.Anything, uint16(0x1234)).Return(nil)
+		_ = gw.AddDeviceToGroup(context.Background(), d, 0x1234)
+
+		mp.On("SendApplicationMessageToGroup", mock.Anything, zigbee.GroupID(0x1234), mock.MatchedBy(func(m zigbee.ApplicationMessage) bool {
+			return m.ClusterID == zcl.ScenesId
+		})).Return(nil)
+
+		mgc.On("GroupCommand", mock.MatchedBy(func(m zcl.Message) bool {
+			cmd, ok := m.Command.(*scenes.RecallScene)
+			return ok && *cmd == scenes.RecallScene{GroupID: 0x1234, SceneID: 3}
+		}))
+
+		err := gw.GroupRecallScene(context.Background(), 0x1234, 3)
+		assert.NoError(t, err)
+	})
+}
@@ -3,14 +3,38 @@ package factory
 import (
 	"github.com/shimmeringbee/da"
 	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/zda/extcaps"
 	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/implcaps/generic/availability"
 	"github.com/shimmeringbee/zda/implcaps/generic/device_workarounds"
+	"github.com/shimmeringbee/zda/implcaps/generic/diagnostics"
 	"github.com/shimmeringbee/zda/implcaps/generic/product_information"
+	"github.com/shimmeringbee/zda/implcaps/zcl/alarm_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/alarm_warning_device"
+	"github.com/shimmeringbee/zda/implcaps/zcl/carbon_dioxide_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/door_lock"
+	"github.com/shimmeringbee/zda/implcaps/zcl/energy_measurement"
+	"github.com/shimmeringbee/zda/implcaps/zcl/fan"
+	"github.com/shimmeringbee/zda/implcaps/zcl/flow_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/groups"
 	"github.com/shimmeringbee/zda/implcaps/zcl/humidity_sensor"
 	"github.com/shimmeringbee/zda/implcaps/zcl/identify"
+	"github.com/shimmeringbee/zda/implcaps/zcl/illuminance_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/leaf_wetness_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/light"
+	"github.com/shimmeringbee/zda/implcaps/zcl/occupancy_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/on_off"
+	"github.com/shimmeringbee/zda/implcaps/zcl/ota_upgrade"
+	"github.com/shimmeringbee/zda/implcaps/zcl/particulate_matter_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/poll_control"
 	"github.com/shimmeringbee/zda/implcaps/zcl/power_supply"
 	"github.com/shimmeringbee/zda/implcaps/zcl/pressure_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/remote_control"
+	"github.com/shimmeringbee/zda/implcaps/zcl/scenes"
+	"github.com/shimmeringbee/zda/implcaps/zcl/soil_moisture_sensor"
 	"github.com/shimmeringbee/zda/implcaps/zcl/temperature_sensor"
+	"github.com/shimmeringbee/zda/implcaps/zcl/thermostat"
+	"github.com/shimmeringbee/zda/implcaps/zcl/window_covering"
 )
 
 const GenericProductInformation = "GenericProductInformation"
@@ -20,15 +44,61 @@ const ZCLPressureSensor = "ZCLPressureSensor"
 const ZCLIdentify = "ZCLIdentify"
 const ZCLPowerSupply = "ZCLPowerSupply"
 const GenericDeviceWorkarounds = "GenericDeviceWorkarounds"
+const GenericDiagnostics = "GenericDiagnostics"
+const GenericAvailability = "GenericAvailability"
+const ZCLOnOff = "ZCLOnOff"
+const ZCLLight = "ZCLLight"
+const ZCLAlarmSensor = "ZCLAlarmSensor"
+const ZCLAlarmWarningDevice = "ZCLAlarmWarningDevice"
+const ZCLOccupancySensor = "ZCLOccupancySensor"
+const ZCLIlluminanceSensor = "ZCLIlluminanceSensor"
+const ZCLThermostat = "ZCLThermostat"
+const ZCLWindowCovering = "ZCLWindowCovering"
+const ZCLDoorLock = "ZCLDoorLock"
+const ZCLEnergyMeasurement = "ZCLEnergyMeasurement"
+const ZCLFan = "ZCLFan"
+const ZCLCarbonDioxideSensor = "ZCLCarbonDioxideSensor"
+const ZCLParticulateMatterSensor = "ZCLParticulateMatterSensor"
+const ZCLFlowSensor = "ZCLFlowSensor"
+const ZCLSoilMoistureSensor = "ZCLSoilMoistureSensor"
+const ZCLLeafWetnessSensor = "ZCLLeafWetnessSensor"
+const ZCLRemoteControl = "ZCLRemoteControl"
+const ZCLOTAUpgrade = "ZCLOTAUpgrade"
+const ZCLPollControl = "ZCLPollControl"
+const ZCLGroups = "ZCLGroups"
+const ZCLScenes = "ZCLScenes"
 
 var Mapping = map[string]da.Capability{
-	GenericProductInformation: capabilities.ProductInformationFlag,
-	ZCLTemperatureSensor:      capabilities.TemperatureSensorFlag,
-	ZCLHumiditySensor:         capabilities.RelativeHumiditySensorFlag,
-	ZCLPressureSensor:         capabilities.PressureSensorFlag,
-	ZCLIdentify:               capabilities.IdentifyFlag,
-	ZCLPowerSupply:            capabilities.PowerSupplyFlag,
-	GenericDeviceWorkarounds:  capabilities.DeviceWorkaroundsFlag,
+	GenericProductInformation:  capabilities.ProductInformationFlag,
+	ZCLTemperatureSensor:       capabilities.TemperatureSensorFlag,
+	ZCLHumiditySensor:          capabilities.RelativeHumiditySensorFlag,
+	ZCLPressureSensor:          capabilities.PressureSensorFlag,
+	ZCLIdentify:                capabilities.IdentifyFlag,
+	ZCLPowerSupply:             capabilities.PowerSupplyFlag,
+	GenericDeviceWorkarounds:   capabilities.DeviceWorkaroundsFlag,
+	GenericDiagnostics:         extcaps.DiagnosticsFlag,
+	GenericAvailability:        extcaps.AvailabilityFlag,
+	ZCLOnOff:                   capabilities.OnOffFlag,
+	ZCLLight:                   capabilities.LightFlag,
+	ZCLAlarmSensor:             capabilities.AlarmSensorFlag,
+	ZCLAlarmWarningDevice:      capabilities.AlarmWarningDeviceFlag,
+	ZCLOccupancySensor:         capabilities.OccupancySensorFlag,
+	ZCLIlluminanceSensor:       capabilities.IlluminationSensorFlag,
+	ZCLThermostat:              extcaps.ThermostatFlag,
+	ZCLWindowCovering:          capabilities.CoverFlag,
+	ZCLDoorLock:                extcaps.DoorLockFlag,
+	ZCLEnergyMeasurement:       capabilities.EnergyMeasurementFlag,
+	ZCLFan:                     extcaps.FanFlag,
+	ZCLCarbonDioxideSensor:     extcaps.CarbonDioxideSensorFlag,
+	ZCLParticulateMatterSensor: extcaps.ParticulateMatterSensorFlag,
+	ZCLFlowSensor:              extcaps.FlowSensorFlag,
+	ZCLSoilMoistureSensor:      extcaps.SoilMoistureSensorFlag,
+	ZCLLeafWetnessSensor:       extcaps.LeafWetnessSensorFlag,
+	ZCLRemoteControl:           capabilities.BasicHumanInterfaceDeviceFlag,
+	ZCLOTAUpgrade:              extcaps.OTAUpgradeFlag,
+	ZCLPollControl:             extcaps.PollControlFlag,
+	ZCLGroups:                  extcaps.GroupsFlag,
+	ZCLScenes:                  extcaps.ScenesFlag,
 }
 
 func Create(name string, iface implcaps.ZDAInterface) implcaps.ZDACapability {
@@ -47,6 +117,52 @@ func Create(name string, iface implcaps.ZDAInterface) implcaps.ZDACapability {
 		return power_suply.NewPowerSupply(iface)
 	case GenericDeviceWorkarounds:
 		return device_workaround.NewDeviceWorkaround(iface)
+	case GenericDiagnostics:
+		return diagnostics.NewDiagnostics(iface)
+	case GenericAvailability:
+		return availability.NewAvailability(iface)
+	case ZCLOnOff:
+		return on_off.NewOnOff(iface)
+	case ZCLLight:
+		return light.NewLight(iface)
+	case ZCLAlarmSensor:
+		return alarm_sensor.NewAlarmSensor(iface)
+	case ZCLAlarmWarningDevice:
+		return alarm_warning_device.NewAlarmWarningDevice(iface)
+	case ZCLOccupancySensor:
+		return occupancy_sensor.NewOccupancySensor(iface)
+	case ZCLIlluminanceSensor:
+		return illuminance_sensor.NewIlluminanceSensor(iface)
+	case ZCLThermostat:
+		return thermostat.NewThermostat(iface)
+	case ZCLWindowCovering:
+		return window_covering.NewWindowCovering(iface)
+	case ZCLDoorLock:
+		return door_lock.NewDoorLock(iface)
+	case ZCLEnergyMeasurement:
+		return energy_measurement.NewEnergyMeasurement(iface)
+	case ZCLFan:
+		return fan.NewFan(iface)
+	case ZCLCarbonDioxideSensor:
+		return carbon_dioxide_sensor.NewCarbonDioxideSensor(iface)
+	case ZCLParticulateMatterSensor:
+		return particulate_matter_sensor.NewParticulateMatterSensor(iface)
+	case ZCLFlowSensor:
+		return flow_sensor.NewFlowSensor(iface)
+	case ZCLSoilMoistureSensor:
+		return soil_moisture_sensor.NewSoilMoistureSensor(iface)
+	case ZCLLeafWetnessSensor:
+		return leaf_wetness_sensor.NewLeafWetnessSensor(iface)
+	case ZCLRemoteControl:
+		return remote_control.NewRemoteControl(iface)
+	case ZCLOTAUpgrade:
+		return ota_upgrade.NewOTAUpgrade(iface)
+	case ZCLPollControl:
+		return poll_control.NewPollControl(iface)
+	case ZCLGroups:
+		return groups.NewGroups(iface)
+	case ZCLScenes:
+		return scenes.NewScenes(iface)
 	default:
 		return nil
 	}
@@ -0,0 +1,379 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/basic"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ extcaps.Availability = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	RouterKey    = "Router"
+	TimeoutKey   = "Timeout"
+	AvailableKey = "Available"
+	LastSeenKey  = "LastSeen"
+)
+
+// DefaultRouterTimeout is how long a router may be silent for if no reporting intervals are known, routers are pinged
+// once quiet for half their timeout, so this is reached only if they stop responding.
+const DefaultRouterTimeout = 15 * time.Minute
+
+// DefaultEndDeviceTimeout is how long an end device may be silent for if no reporting intervals are known, sleepy
+// devices without reporting configured often only send a message every day.
+const DefaultEndDeviceTimeout = 25 * time.Hour
+
+// ExpectedIntervalMultiple is the number of expected intervals which may pass without hearing from a device before
+// it is considered unavailable, allowing for lost reports.
+const ExpectedIntervalMultiple = 3
+
+// PingTimeout is how long to wait for a router to respond to a ping.
+const PingTimeout = 10 * time.Second
+
+var ErrInvalidTimeout = errors.New("availability timeout must not be negative")
+
+func NewAvailability(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi, logger: zi.Logger(), configMutex: &sync.RWMutex{}, timerMutex: &sync.Mutex{}, stateMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	configMutex    *sync.RWMutex
+	remoteEndpoint zigbee.Endpoint
+	router         bool
+	timeout        time.Duration
+	match          *communicator.Match
+
+	timerMutex *sync.Mutex
+	timer      *time.Timer
+
+	stateMutex *sync.Mutex
+	available  bool
+	lastSeen   time.Time
+	graceFrom  time.Time
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.AvailabilityFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.AvailabilityFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	v, ok := i.s.Int(implcaps.RemoteEndpointKey)
+	if !ok {
+		return false, fmt.Errorf("availability missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	router, _ := i.s.Bool(RouterKey)
+	timeout, _ := i.s.Int(TimeoutKey)
+
+	i.configMutex.Lock()
+	i.remoteEndpoint = zigbee.Endpoint(v)
+	i.router = router
+	i.timeout = time.Duration(timeout) * time.Second
+	i.configMutex.Unlock()
+
+	i.loadState()
+	i.attach()
+	i.scheduleCheck()
+
+	return true, nil
+}
+
+// Enumerate records how the device should be pinged, state from a previous enumeration is retained so that
+// re-enumeration does not change the device's availability. A timeout set with SetTimeout is only replaced if the
+// AvailabilityTimeout setting is provided.
+func (i *Implementation) Enumerate(_ context.Context, m map[string]any) (bool, error) {
+	remoteEndpoint := implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+	router := implcaps.Get(m, "ZigbeeRouter", false)
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(remoteEndpoint))
+	i.s.Set(RouterKey, router)
+
+	if _, found := m["AvailabilityTimeout"]; found {
+		i.s.Set(TimeoutKey, implcaps.Get(m, "AvailabilityTimeout", 0))
+	}
+
+	timeout, _ := i.s.Int(TimeoutKey)
+
+	i.configMutex.Lock()
+	i.remoteEndpoint = remoteEndpoint
+	i.router = router
+	i.timeout = time.Duration(timeout) * time.Second
+	attached := i.match != nil
+	i.configMutex.Unlock()
+
+	if !attached {
+		i.loadState()
+		i.attach()
+	}
+
+	i.scheduleCheck()
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.configMutex.Lock()
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+		i.match = nil
+	}
+	i.configMutex.Unlock()
+
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	if i.timer != nil {
+		i.timer.Stop()
+		i.timer = nil
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "GenericAvailability"
+}
+
+// loadState restores the persisted availability, a device which was available is given a full timeout from now
+// before being considered unavailable, as it could not have been heard from while the gateway was stopped.
+func (i *Implementation) loadState() {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	i.available, _ = i.s.Bool(AvailableKey, true)
+	i.lastSeen, _ = converter.Retrieve(i.s, LastSeenKey, converter.TimeDecoder)
+
+	if i.available {
+		i.graceFrom = time.Now()
+	}
+
+	i.s.Set(AvailableKey, i.available)
+}
+
+func (i *Implementation) attach() {
+	ieee, _, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	match := communicator.NewMatch(func(a zigbee.IEEEAddress, _ zigbee.ApplicationMessage, _ zcl.Message) bool {
+		return a == ieee
+	}, func(_ communicator.MessageWithSource) {
+		i.seen(time.Now())
+	})
+
+	i.configMutex.Lock()
+	defer i.configMutex.Unlock()
+
+	i.match = &match
+	i.zi.ZCLCommunicator().RegisterMatch(match)
+}
+
+func (i *Implementation) scheduleCheck() {
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	if i.timer != nil {
+		i.timer.Stop()
+	}
+
+	i.timer = time.AfterFunc(i.effectiveTimeout()/4, i.periodicCheck)
+}
+
+func (i *Implementation) periodicCheck() {
+	ctx, cancel := context.WithTimeout(context.Background(), PingTimeout*2)
+	defer cancel()
+
+	i.check(ctx)
+
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	/* Only reschedule if not detached while checking. */
+	if i.timer != nil {
+		i.timer = time.AfterFunc(i.effectiveTimeout()/4, i.periodicCheck)
+	}
+}
+
+// check updates the availability of the device, routers which have been quiet for half their timeout are pinged to
+// find out if they are still present.
+func (i *Implementation) check(ctx context.Context) {
+	ieee, _, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	/* Any frame received from the node counts, not only the ZCL messages matched by this capability. */
+	if q, found := i.zi.LinkQuality(ieee); found {
+		i.seen(q.Received)
+	}
+
+	timeout := i.effectiveTimeout()
+	quiet := time.Since(i.reference())
+
+	i.configMutex.RLock()
+	router := i.router
+	i.configMutex.RUnlock()
+
+	if router && quiet >= timeout/2 {
+		if err := i.ping(ctx); err != nil {
+			i.logger.Info(ctx, "Router failed to respond to availability ping.", logwrap.Err(err))
+		} else {
+			i.seen(time.Now())
+			return
+		}
+	}
+
+	if quiet >= timeout {
+		i.setAvailable(false)
+	}
+}
+
+func (i *Implementation) ping(ctx context.Context) error {
+	pctx, cancel := context.WithTimeout(ctx, PingTimeout)
+	defer cancel()
+
+	i.configMutex.RLock()
+	remoteEndpoint := i.remoteEndpoint
+	i.configMutex.RUnlock()
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	_, err := i.zi.ZCLCommunicator().ReadAttributes(pctx, ieee, ack, zcl.BasicId, zigbee.NoManufacturer, localEndpoint, remoteEndpoint, seq, []zcl.AttributeID{basic.ZCLVersion})
+	return err
+}
+
+func (i *Implementation) seen(t time.Time) {
+	i.stateMutex.Lock()
+	if !t.After(i.lastSeen) {
+		i.stateMutex.Unlock()
+		return
+	}
+
+	i.lastSeen = t
+	converter.Store(i.s, LastSeenKey, t, converter.TimeEncoder)
+	i.stateMutex.Unlock()
+
+	i.setAvailable(true)
+}
+
+func (i *Implementation) setAvailable(available bool) {
+	i.stateMutex.Lock()
+	if i.available == available {
+		i.stateMutex.Unlock()
+		return
+	}
+
+	i.available = available
+	lastSeen := i.lastSeen
+
+	i.s.Set(AvailableKey, available)
+	converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+	i.stateMutex.Unlock()
+
+	i.zi.SendEvent(extcaps.AvailabilityUpdate{Device: i.d, Available: available, LastSeen: lastSeen})
+}
+
+// reference returns the time the device's silence is measured from, when it was last seen or, if it was available
+// when the gateway started, the time the capability was loaded.
+func (i *Implementation) reference() time.Time {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	if i.graceFrom.After(i.lastSeen) {
+		return i.graceFrom
+	}
+
+	return i.lastSeen
+}
+
+// effectiveTimeout returns the configured timeout, or if none is configured a multiple of the shortest reporting or
+// polling interval of the device, falling back to a default by device type.
+func (i *Implementation) effectiveTimeout() time.Duration {
+	i.configMutex.RLock()
+	timeout, router := i.timeout, i.router
+	i.configMutex.RUnlock()
+
+	if timeout > 0 {
+		return timeout
+	}
+
+	ieee, _, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if interval, found := i.zi.ExpectedInterval(ieee); found {
+		return interval * ExpectedIntervalMultiple
+	}
+
+	if router {
+		return DefaultRouterTimeout
+	}
+
+	return DefaultEndDeviceTimeout
+}
+
+func (i *Implementation) Available(_ context.Context) (bool, error) {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	return i.available, nil
+}
+
+func (i *Implementation) LastSeen(_ context.Context) (time.Time, error) {
+	ieee, _, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	if q, found := i.zi.LinkQuality(ieee); found && q.Received.After(i.lastSeen) {
+		return q.Received, nil
+	}
+
+	return i.lastSeen, nil
+}
+
+func (i *Implementation) Timeout(_ context.Context) (time.Duration, error) {
+	return i.effectiveTimeout(), nil
+}
+
+func (i *Implementation) SetTimeout(_ context.Context, timeout time.Duration) error {
+	if timeout < 0 {
+		return ErrInvalidTimeout
+	}
+
+	i.configMutex.Lock()
+	i.timeout = timeout
+	i.configMutex.Unlock()
+
+	i.s.Set(TimeoutKey, int(timeout/time.Second))
+
+	i.scheduleCheck()
+
+	return nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
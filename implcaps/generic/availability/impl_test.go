@@ -0,0 +1,237 @@
+package availability
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+	mzc.On("RegisterMatch", mock.Anything).Maybe()
+	mzc.On("UnregisterMatch", mock.Anything).Maybe()
+
+	i := NewAvailability(mzi)
+	i.Init(nil, memory.New())
+	i.remoteEndpoint = 4
+
+	t.Cleanup(func() { _ = i.Detach(context.TODO(), implcaps.DeviceRemoved) })
+
+	return i, mzi, mzc
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		assert.Equal(t, extcaps.AvailabilityFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.AvailabilityFlag], i.Name())
+		assert.Equal(t, "GenericAvailability", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads configuration and state, giving an available device a grace period", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("ExpectedInterval", zigbee.IEEEAddress(1)).Return(time.Duration(0), false).Maybe()
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false)
+
+		lastSeen := time.Now().Add(-48 * time.Hour)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+		i.s.Set(RouterKey, true)
+		i.s.Set(TimeoutKey, 60)
+		i.s.Set(AvailableKey, true)
+		converter.Store(i.s, LastSeenKey, lastSeen, converter.TimeEncoder)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.remoteEndpoint)
+		assert.True(t, i.router)
+		assert.Equal(t, time.Minute, i.timeout)
+		assert.NotNil(t, i.timer)
+
+		seen, _ := i.LastSeen(context.TODO())
+		assert.WithinDuration(t, lastSeen, seen, time.Millisecond)
+		assert.WithinDuration(t, time.Now(), i.reference(), time.Second)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches as available, retaining a timeout set by the user on re-enumeration", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("ExpectedInterval", zigbee.IEEEAddress(1)).Return(time.Duration(0), false).Maybe()
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5), "ZigbeeRouter": true})
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.NotNil(t, i.match)
+
+		available, _ := i.Available(context.TODO())
+		assert.True(t, available)
+
+		assert.NoError(t, i.SetTimeout(context.TODO(), 2*time.Minute))
+
+		_, _ = i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5), "ZigbeeRouter": true})
+
+		timeout, _ := i.Timeout(context.TODO())
+		assert.Equal(t, 2*time.Minute, timeout)
+	})
+}
+
+func TestImplementation_Timeout(t *testing.T) {
+	t.Run("is derived from the expected interval of the node", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("ExpectedInterval", zigbee.IEEEAddress(1)).Return(5*time.Minute, true)
+
+		timeout, _ := i.Timeout(context.TODO())
+		assert.Equal(t, 15*time.Minute, timeout)
+	})
+
+	t.Run("falls back to a default by device type", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("ExpectedInterval", zigbee.IEEEAddress(1)).Return(time.Duration(0), false)
+
+		timeout, _ := i.Timeout(context.TODO())
+		assert.Equal(t, DefaultEndDeviceTimeout, timeout)
+
+		i.router = true
+		timeout, _ = i.Timeout(context.TODO())
+		assert.Equal(t, DefaultRouterTimeout, timeout)
+	})
+
+	t.Run("a configured timeout takes priority, and negative timeouts are rejected", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		assert.NoError(t, i.SetTimeout(context.TODO(), time.Hour))
+		timeout, _ := i.Timeout(context.TODO())
+		assert.Equal(t, time.Hour, timeout)
+
+		assert.ErrorIs(t, i.SetTimeout(context.TODO(), -time.Second), ErrInvalidTimeout)
+	})
+
+	t.Run("can be changed while the periodic check is running", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false).Maybe()
+		mzi.On("SendEvent", mock.Anything).Maybe()
+
+		assert.NoError(t, i.SetTimeout(context.TODO(), 4*time.Millisecond))
+
+		for n := 0; n < 20; n++ {
+			time.Sleep(time.Millisecond)
+			assert.NoError(t, i.SetTimeout(context.TODO(), time.Duration(4+n%2)*time.Millisecond))
+		}
+	})
+}
+
+func TestImplementation_check(t *testing.T) {
+	t.Run("marks a quiet end device unavailable, and available again when it is heard from", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		i.timeout = time.Minute
+		i.available = true
+		i.lastSeen = time.Now().Add(-2 * time.Minute)
+
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false).Once()
+		mzi.On("SendEvent", extcaps.AvailabilityUpdate{Device: nil, Available: false, LastSeen: i.lastSeen}).Once()
+
+		i.check(context.TODO())
+
+		available, _ := i.Available(context.TODO())
+		assert.False(t, available)
+
+		changed, _ := i.LastChangeTime(context.TODO())
+		assert.WithinDuration(t, time.Now(), changed, time.Second)
+
+		received := time.Now()
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{Received: received}, true)
+		mzi.On("SendEvent", extcaps.AvailabilityUpdate{Device: nil, Available: true, LastSeen: received}).Once()
+
+		i.check(context.TODO())
+
+		available, _ = i.Available(context.TODO())
+		assert.True(t, available)
+	})
+
+	t.Run("does not send an event if availability is unchanged", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		i.timeout = time.Minute
+		i.available = true
+		i.lastSeen = time.Now()
+
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false)
+
+		i.check(context.TODO())
+
+		available, _ := i.Available(context.TODO())
+		assert.True(t, available)
+	})
+
+	t.Run("pings a quiet router, which remains available if it responds", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		i.timeout = time.Minute
+		i.router = true
+		i.available = true
+		i.lastSeen = time.Now().Add(-45 * time.Second)
+
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false)
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.BasicId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{0x0000}).Return([]global.ReadAttributeResponseRecord(nil), nil)
+
+		i.check(context.TODO())
+
+		seen, _ := i.LastSeen(context.TODO())
+		assert.WithinDuration(t, time.Now(), seen, time.Second)
+
+		available, _ := i.Available(context.TODO())
+		assert.True(t, available)
+	})
+
+	t.Run("marks a router unavailable if it fails to respond after its timeout", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		i.timeout = time.Minute
+		i.router = true
+		i.available = true
+		i.lastSeen = time.Now().Add(-2 * time.Minute)
+
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false)
+		mzi.On("SendEvent", mock.AnythingOfType("extcaps.AvailabilityUpdate"))
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.BasicId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), mock.Anything).Return([]global.ReadAttributeResponseRecord(nil), io.EOF)
+
+		i.check(context.TODO())
+
+		available, _ := i.Available(context.TODO())
+		assert.False(t, available)
+	})
+}
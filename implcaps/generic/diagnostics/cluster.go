@@ -0,0 +1,19 @@
+package diagnostics
+
+import (
+	"github.com/shimmeringbee/zcl"
+)
+
+/* The zcl library does not yet provide the Diagnostics cluster, the subset of attributes required is defined here. */
+
+const (
+	NumberOfResets                   = zcl.AttributeID(0x0000)
+	MACTxUnicastRetry                = zcl.AttributeID(0x0104)
+	MACTxUnicastFail                 = zcl.AttributeID(0x0105)
+	APSTxUnicastSuccess              = zcl.AttributeID(0x0109)
+	APSTxUnicastRetry                = zcl.AttributeID(0x010a)
+	APSTxUnicastFail                 = zcl.AttributeID(0x010b)
+	AverageMACRetryPerAPSMessageSent = zcl.AttributeID(0x011b)
+	LastMessageLQI                   = zcl.AttributeID(0x011c)
+	LastMessageRSSI                  = zcl.AttributeID(0x011d)
+)
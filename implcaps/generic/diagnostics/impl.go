@@ -0,0 +1,276 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ extcaps.Diagnostics = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	ClusterPresentKey = "ClusterPresent"
+	ReadIntervalKey   = "ReadInterval"
+	RSSIKey           = "RSSI"
+	AverageRSSIKey    = "AverageRSSI"
+	CountersKey       = "Counters"
+)
+
+// DefaultReadInterval is how often the Diagnostics cluster counters are read, if not set by the DiagnosticsReadInterval
+// setting in seconds.
+const DefaultReadInterval = 1 * time.Hour
+
+// RSSIAverageWeight is the weight given to each new RSSI read in the rolling average, reads are infrequent so each
+// has a greater weight than frames do in the LQI average.
+const RSSIAverageWeight = 0.25
+
+var ErrClusterNotPresent = errors.New("device does not support the diagnostics cluster")
+
+// counterAttributes maps the Diagnostics cluster attributes read to the persistence key they are stored under.
+var counterAttributes = map[zcl.AttributeID]string{
+	NumberOfResets:                   "NumberOfResets",
+	MACTxUnicastRetry:                "MACTxUnicastRetry",
+	MACTxUnicastFail:                 "MACTxUnicastFail",
+	APSTxUnicastSuccess:              "APSTxUnicastSuccess",
+	APSTxUnicastRetry:                "APSTxUnicastRetry",
+	APSTxUnicastFail:                 "APSTxUnicastFail",
+	AverageMACRetryPerAPSMessageSent: "AverageMACRetryPerAPSMessageSent",
+}
+
+func NewDiagnostics(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi, logger: zi.Logger(), timerMutex: &sync.Mutex{}, readMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+	clusterPresent bool
+	readInterval   time.Duration
+
+	timerMutex *sync.Mutex
+	timer      *time.Timer
+
+	readMutex *sync.Mutex
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.DiagnosticsFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.DiagnosticsFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("diagnostics missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.clusterPresent, _ = i.s.Bool(ClusterPresentKey)
+
+	v, _ := i.s.Int(ReadIntervalKey, int64(DefaultReadInterval/time.Second))
+	i.readInterval = time.Duration(v) * time.Second
+
+	i.scheduleRead()
+
+	return true, nil
+}
+
+// Enumerate records whether the Diagnostics cluster is present, if it is the counters are read immediately and then
+// periodically. Link quality is always available, as it is measured by the gateway from received frames.
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+	i.clusterPresent = implcaps.Get(m, "ZigbeeDiagnosticsClusterPresent", false)
+	i.readInterval = time.Duration(implcaps.Get(m, "DiagnosticsReadInterval", int(DefaultReadInterval/time.Second))) * time.Second
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+	i.s.Set(ClusterPresentKey, i.clusterPresent)
+	i.s.Set(ReadIntervalKey, int(i.readInterval/time.Second))
+
+	if i.clusterPresent {
+		if _, err := i.ReadCounters(ctx); err != nil {
+			i.logger.Warn(ctx, "Failed to read diagnostics counters.", logwrap.Err(err))
+		}
+	}
+
+	i.scheduleRead()
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	if i.timer != nil {
+		i.timer.Stop()
+		i.timer = nil
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "GenericDiagnostics"
+}
+
+func (i *Implementation) scheduleRead() {
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	if i.timer != nil {
+		i.timer.Stop()
+		i.timer = nil
+	}
+
+	if i.clusterPresent && i.readInterval > 0 {
+		i.timer = time.AfterFunc(i.readInterval, i.periodicRead)
+	}
+}
+
+func (i *Implementation) periodicRead() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := i.ReadCounters(ctx); err != nil {
+		i.logger.Warn(ctx, "Failed to periodically read diagnostics counters.", logwrap.Err(err))
+	}
+
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	/* Only reschedule if not detached while reading. */
+	if i.timer != nil {
+		i.timer = time.AfterFunc(i.readInterval, i.periodicRead)
+	}
+}
+
+func (i *Implementation) ReadCounters(ctx context.Context) (extcaps.DiagnosticCounters, error) {
+	if !i.clusterPresent {
+		return extcaps.DiagnosticCounters{}, ErrClusterNotPresent
+	}
+
+	i.readMutex.Lock()
+	defer i.readMutex.Unlock()
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	attributes := []zcl.AttributeID{NumberOfResets, MACTxUnicastRetry, MACTxUnicastFail, APSTxUnicastSuccess, APSTxUnicastRetry, APSTxUnicastFail, AverageMACRetryPerAPSMessageSent, LastMessageRSSI}
+
+	recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.DiagnosticsId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, attributes)
+	if err != nil {
+		return extcaps.DiagnosticCounters{}, err
+	}
+
+	cs := i.s.Section(CountersKey)
+
+	for id, rec := range communicator.ReadResponsesToMap(recs) {
+		if rec.Status != 0 || rec.DataTypeValue == nil {
+			continue
+		}
+
+		if key, found := counterAttributes[id]; found {
+			if v, ok := rec.DataTypeValue.Value.(uint64); ok {
+				cs.Set(key, v)
+			}
+		} else if id == LastMessageRSSI {
+			if v, ok := rec.DataTypeValue.Value.(int64); ok {
+				i.updateRSSI(int8(v))
+			}
+		}
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	counters, _, _ := i.Counters(ctx)
+	lq, _ := i.LinkQuality(ctx)
+
+	i.zi.SendEvent(extcaps.DiagnosticsUpdate{Device: i.d, LinkQuality: lq, Counters: counters})
+
+	return counters, nil
+}
+
+func (i *Implementation) updateRSSI(rssi int8) {
+	average := float64(rssi)
+
+	if previous, found := i.s.Float(AverageRSSIKey); found {
+		average = previous + (float64(rssi)-previous)*RSSIAverageWeight
+	}
+
+	i.s.Set(RSSIKey, int(rssi))
+	i.s.Set(AverageRSSIKey, average)
+}
+
+func (i *Implementation) LinkQuality(_ context.Context) (extcaps.LinkQuality, error) {
+	ieee, _, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	lq := extcaps.LinkQuality{}
+
+	if q, found := i.zi.LinkQuality(ieee); found {
+		lq.LQI = q.Last
+		lq.AverageLQI = q.Average
+		lq.LastReceived = q.Received
+	}
+
+	if rssi, found := i.s.Int(RSSIKey); found {
+		lq.RSSIPresent = true
+		lq.RSSI = int8(rssi)
+		lq.AverageRSSI, _ = i.s.Float(AverageRSSIKey)
+	}
+
+	return lq, nil
+}
+
+func (i *Implementation) Counters(_ context.Context) (extcaps.DiagnosticCounters, bool, error) {
+	updated, found := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	if !found {
+		return extcaps.DiagnosticCounters{}, false, nil
+	}
+
+	cs := i.s.Section(CountersKey)
+	counter := func(id zcl.AttributeID) uint64 {
+		v, _ := cs.UInt(counterAttributes[id])
+		return v
+	}
+
+	return extcaps.DiagnosticCounters{
+		NumberOfResets:                   counter(NumberOfResets),
+		MACTxUnicastRetry:                counter(MACTxUnicastRetry),
+		MACTxUnicastFail:                 counter(MACTxUnicastFail),
+		APSTxUnicastSuccess:              counter(APSTxUnicastSuccess),
+		APSTxUnicastRetry:                counter(APSTxUnicastRetry),
+		APSTxUnicastFail:                 counter(APSTxUnicastFail),
+		AverageMACRetryPerAPSMessageSent: counter(AverageMACRetryPerAPSMessageSent),
+		LastUpdated:                      updated,
+	}, true, nil
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
@@ -0,0 +1,172 @@
+package diagnostics
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewDiagnostics(mzi)
+	i.Init(nil, memory.New())
+	i.remoteEndpoint = 4
+
+	t.Cleanup(func() { _ = i.Detach(context.TODO(), implcaps.DeviceRemoved) })
+
+	return i, mzi, mzc
+}
+
+func counterRecord(id zcl.AttributeID, dt zcl.AttributeDataType, v any) global.ReadAttributeResponseRecord {
+	return global.ReadAttributeResponseRecord{Identifier: id, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: dt, Value: v}}
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		assert.Equal(t, extcaps.DiagnosticsFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.DiagnosticsFlag], i.Name())
+		assert.Equal(t, "GenericDiagnostics", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads configuration, returning true if successful", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+		i.s.Set(ClusterPresentKey, true)
+		i.s.Set(ReadIntervalKey, 60)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.remoteEndpoint)
+		assert.True(t, i.clusterPresent)
+		assert.Equal(t, time.Minute, i.readInterval)
+		assert.NotNil(t, i.timer)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches without the diagnostics cluster, not reading counters", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5), "ZigbeeDiagnosticsClusterPresent": false})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Nil(t, i.timer)
+
+		_, found, _ := i.Counters(context.TODO())
+		assert.False(t, found)
+
+		_, err = i.ReadCounters(context.TODO())
+		assert.ErrorIs(t, err, ErrClusterNotPresent)
+	})
+
+	t.Run("reads the counters if the diagnostics cluster is present, failure is not fatal", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.DiagnosticsId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(5), uint8(3), mock.Anything).Return([]global.ReadAttributeResponseRecord(nil), io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5), "ZigbeeDiagnosticsClusterPresent": true})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.NotNil(t, i.timer)
+		assert.Equal(t, DefaultReadInterval, i.readInterval)
+	})
+}
+
+func TestImplementation_ReadCounters(t *testing.T) {
+	t.Run("reads and persists the counters and rssi, sending an update", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		i.clusterPresent = true
+
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false)
+		mzi.On("SendEvent", mock.AnythingOfType("extcaps.DiagnosticsUpdate"))
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.DiagnosticsId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), mock.Anything).Return([]global.ReadAttributeResponseRecord{
+			counterRecord(NumberOfResets, zcl.TypeUnsignedInt16, uint64(2)),
+			counterRecord(MACTxUnicastRetry, zcl.TypeUnsignedInt16, uint64(40)),
+			counterRecord(APSTxUnicastFail, zcl.TypeUnsignedInt16, uint64(3)),
+			counterRecord(LastMessageRSSI, zcl.TypeSignedInt8, int64(-70)),
+			{Identifier: MACTxUnicastFail, Status: 0x86},
+		}, nil)
+
+		counters, err := i.ReadCounters(context.TODO())
+		assert.NoError(t, err)
+
+		assert.Equal(t, uint64(2), counters.NumberOfResets)
+		assert.Equal(t, uint64(40), counters.MACTxUnicastRetry)
+		assert.Equal(t, uint64(3), counters.APSTxUnicastFail)
+		assert.Equal(t, uint64(0), counters.MACTxUnicastFail)
+		assert.WithinDuration(t, time.Now(), counters.LastUpdated, time.Second)
+
+		stored, found, _ := i.Counters(context.TODO())
+		assert.True(t, found)
+		assert.Equal(t, counters, stored)
+
+		lq, _ := i.LinkQuality(context.TODO())
+		assert.True(t, lq.RSSIPresent)
+		assert.Equal(t, int8(-70), lq.RSSI)
+		assert.Equal(t, -70.0, lq.AverageRSSI)
+	})
+}
+
+func TestImplementation_LinkQuality(t *testing.T) {
+	t.Run("combines the nodes frame link quality with the reported rssi", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+
+		received := time.Now()
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{Last: 120, Average: 130.5, Received: received}, true)
+
+		i.updateRSSI(-60)
+		i.updateRSSI(-80)
+
+		lq, err := i.LinkQuality(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, extcaps.LinkQuality{LQI: 120, AverageLQI: 130.5, LastReceived: received, RSSIPresent: true, RSSI: -80, AverageRSSI: -65}, lq)
+	})
+
+	t.Run("rssi is not present if never reported", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("LinkQuality", zigbee.IEEEAddress(1)).Return(implcaps.LinkQuality{}, false)
+
+		lq, _ := i.LinkQuality(context.TODO())
+		assert.False(t, lq.RSSIPresent)
+	})
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zcl/communicator"
 	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/ota"
 	"github.com/shimmeringbee/zigbee"
+	"time"
 )
 
 const (
@@ -18,6 +20,10 @@ const (
 	DataKeyZCLAttributeID     = "ZCLAttributeID"
 )
 
+// DefaultNetworkTimeout bounds network requests made by capabilities in response to messages from a device, where there
+// is no caller to provide a deadline.
+const DefaultNetworkTimeout = 10 * time.Second
+
 type DetachType int
 
 const (
@@ -50,6 +56,13 @@ type ZDACapability interface {
 	ImplName() string
 }
 
+// GroupCommandObserver is implemented by capabilities whose state is changed by commands multicast to a group the
+// device is a member of, devices rarely report the resulting changes promptly so the state is updated immediately.
+type GroupCommandObserver interface {
+	// GroupCommand is called with each command multicast to a group the device is a member of.
+	GroupCommand(zcl.Message)
+}
+
 type ZDAInterface interface {
 	// NewAttributeMonitor creates a new attribute monitor to be used to listen to an attribute on a device.
 	NewAttributeMonitor() attribute.Monitor
@@ -65,4 +78,15 @@ type ZDAInterface interface {
 	TransmissionLookup(da.Device, zigbee.ProfileID) (zigbee.IEEEAddress, zigbee.Endpoint, bool, uint8)
 	//Logger returns a logger to be used.
 	Logger() logwrap.Logger
+	//AdapterNode returns the node details of the Zigbee adapter the gateway is using.
+	AdapterNode() zigbee.Node
+	//OTAImageStore returns the store of OTA upgrade images, nil if the gateway has not been provided one.
+	OTAImageStore() ota.ImageStore
+	//CommandQueue returns the queue of commands held for a node until it next checks in using Poll Control.
+	CommandQueue(zigbee.IEEEAddress) CommandQueue
+	//LinkQuality returns the quality of frames received from a node, false if no frames have been received since start.
+	LinkQuality(zigbee.IEEEAddress) (LinkQuality, bool)
+	//ExpectedInterval returns the longest a node should be silent for, derived from the reporting and polling intervals
+	//of attribute monitors attached to it, false if no monitor has an interval.
+	ExpectedInterval(zigbee.IEEEAddress) (time.Duration, bool)
 }
@@ -0,0 +1,13 @@
+package implcaps
+
+import "time"
+
+// LinkQuality summarises the link quality indicator (LQI) of frames received directly from a node.
+type LinkQuality struct {
+	// Last is the LQI of the most recently received frame.
+	Last uint8
+	// Average is a rolling average of the LQI of received frames.
+	Average float64
+	// Received is the time the most recent frame was received.
+	Received time.Time
+}
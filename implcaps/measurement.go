@@ -0,0 +1,7 @@
+package implcaps
+
+import "github.com/shimmeringbee/zcl"
+
+// MeasuredValue is the attribute holding the current measurement of the measurement and sensing clusters which the zcl
+// library does not provide.
+const MeasuredValue = zcl.AttributeID(0x0000)
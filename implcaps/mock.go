@@ -6,8 +6,10 @@ import (
 	"github.com/shimmeringbee/zcl"
 	"github.com/shimmeringbee/zcl/communicator"
 	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/ota"
 	"github.com/shimmeringbee/zigbee"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type MockZDAInterface struct {
@@ -43,4 +45,48 @@ func (m *MockZDAInterface) SendEvent(a any) {
 	m.Called(a)
 }
 
+func (m *MockZDAInterface) AdapterNode() zigbee.Node {
+	return m.Called().Get(0).(zigbee.Node)
+}
+
+func (m *MockZDAInterface) OTAImageStore() ota.ImageStore {
+	if s := m.Called().Get(0); s != nil {
+		return s.(ota.ImageStore)
+	}
+
+	return nil
+}
+
+func (m *MockZDAInterface) CommandQueue(address zigbee.IEEEAddress) CommandQueue {
+	return m.Called(address).Get(0).(CommandQueue)
+}
+
+func (m *MockZDAInterface) LinkQuality(address zigbee.IEEEAddress) (LinkQuality, bool) {
+	args := m.Called(address)
+	return args.Get(0).(LinkQuality), args.Bool(1)
+}
+
+func (m *MockZDAInterface) ExpectedInterval(address zigbee.IEEEAddress) (time.Duration, bool) {
+	args := m.Called(address)
+	return args.Get(0).(time.Duration), args.Bool(1)
+}
+
 var _ ZDAInterface = (*MockZDAInterface)(nil)
+
+type MockCommandQueue struct {
+	mock.Mock
+}
+
+func (m *MockCommandQueue) Enable(b bool) {
+	m.Called(b)
+}
+
+func (m *MockCommandQueue) Len() int {
+	return m.Called().Int(0)
+}
+
+func (m *MockCommandQueue) Drain() []QueuedMessage {
+	return m.Called().Get(0).([]QueuedMessage)
+}
+
+var _ CommandQueue = (*MockCommandQueue)(nil)
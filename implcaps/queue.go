@@ -0,0 +1,40 @@
+package implcaps
+
+import (
+	"context"
+	"errors"
+	"github.com/shimmeringbee/zcl"
+)
+
+var ErrCommandQueueFull = errors.New("command queue full")
+
+type queueUntilCheckInKey struct{}
+
+// QueueUntilCheckIn marks a context so that a Request made with it via ZDAInterface.ZCLCommunicator is held until the
+// node next checks in using Poll Control. If the node does not support Poll Control the request is sent immediately.
+func QueueUntilCheckIn(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queueUntilCheckInKey{}, true)
+}
+
+// IsQueuedUntilCheckIn returns true if the context has been marked with QueueUntilCheckIn.
+func IsQueuedUntilCheckIn(ctx context.Context) bool {
+	v, _ := ctx.Value(queueUntilCheckInKey{}).(bool)
+	return v
+}
+
+// QueuedMessage is a ZCL message held in a CommandQueue.
+type QueuedMessage struct {
+	RequireAck bool
+	Message    zcl.Message
+}
+
+// CommandQueue holds outgoing messages for a node until it checks in.
+type CommandQueue interface {
+	// Enable marks the node as checking in, permitting messages to be queued. Disabling the queue discards any
+	// messages held.
+	Enable(bool)
+	// Len returns the number of messages held.
+	Len() int
+	// Drain removes and returns all messages held, in the order they were queued.
+	Drain() []QueuedMessage
+}
@@ -0,0 +1,422 @@
+package alarm_sensor
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/ias_zone"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ capabilities.AlarmSensor = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	EnrolledKey   = "Enrolled"
+	ZoneIDKey     = "ZoneID"
+	ZoneTypeKey   = "ZoneType"
+	ZoneStatusKey = "ZoneStatus"
+)
+
+// DefaultZoneID is the zone ID allocated to every device enrolled by the gateway, the gateway does not act as a
+// full IAS CIE so there is no need to discriminate between zones.
+const DefaultZoneID = uint8(0x00)
+
+const (
+	zoneStateEnrolled = uint8(0x01)
+
+	zoneStatusAlarm1     = uint64(0x0001)
+	zoneStatusAlarm2     = uint64(0x0002)
+	zoneStatusTamper     = uint64(0x0004)
+	zoneStatusBatteryLow = uint64(0x0008)
+)
+
+var zoneTypeMapping = map[uint16]capabilities.SensorType{
+	0x000d: capabilities.SecurityMotion,
+	0x0015: capabilities.SecurityContact,
+	0x0016: capabilities.SecurityContact,
+	0x0028: capabilities.FireOther,
+	0x002a: capabilities.General,
+	0x002b: capabilities.GasCarbonMonoxide,
+	0x002c: capabilities.GeneralEmergency,
+	0x002d: capabilities.SecurityVibration,
+	0x010f: capabilities.SecurityPanic,
+	0x0115: capabilities.SecurityPanic,
+	0x021d: capabilities.SecurityKeypad,
+	0x0225: capabilities.GeneralWarningDevice,
+	0x0226: capabilities.SecurityGlassBreak,
+	0x0229: capabilities.SecurityInfrastructure,
+}
+
+func NewAlarmSensor(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(ias_zone.Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), matchMutex: &sync.Mutex{}, enrollMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	ieeeAddress    zigbee.IEEEAddress
+	remoteEndpoint zigbee.Endpoint
+
+	matchMutex *sync.Mutex
+	match      *communicator.Match
+
+	enrollMutex *sync.Mutex
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.AlarmSensorFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.AlarmSensorFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("alarm sensor missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	if enrolled, _ := i.s.Bool(EnrolledKey); !enrolled {
+		i.logger.Warn(ctx, "Loaded alarm sensor which has not been enrolled, it will be enrolled upon its next enroll request.")
+	}
+
+	i.attachMatch()
+
+	return true, nil
+}
+
+// Enumerate enrolls the sensor with the gateway if it is not already. IAS sensors are usually sleepy, so if the sensor
+// can not be read the capability is attached with its persisted state, and enrollment happens when the sensor is next
+// heard from. Sensors with Poll Control also have their enrollment queued until they next check in.
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.attachMatch()
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	cieAddress := i.zi.AdapterNode().IEEEAddress
+
+	recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.IASZoneId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{ias_zone.ZoneState, ias_zone.ZoneType, ias_zone.ZoneStatus, ias_zone.IASCIEAddress})
+	if err != nil {
+		i.logger.Warn(ctx, "Failed to read ias zone attributes, enrollment deferred until the sensor is next heard from.", logwrap.Err(err))
+
+		if implcaps.Get(m, "ZigbeePollControlClusterPresent", false) {
+			if err := i.queueEnrollment(ctx, cieAddress); err != nil {
+				i.logger.Warn(ctx, "Failed to queue enrollment until the sensor checks in.", logwrap.Err(err))
+			}
+		}
+
+		return true, nil
+	}
+
+	var zoneState uint8
+	var currentCIE zigbee.IEEEAddress
+
+	for id, rec := range communicator.ReadResponsesToMap(recs) {
+		if rec.Status != 0 || rec.DataTypeValue == nil {
+			continue
+		}
+
+		switch id {
+		case ias_zone.ZoneState:
+			zoneState, _ = rec.DataTypeValue.Value.(uint8)
+		case ias_zone.ZoneType:
+			if v, ok := rec.DataTypeValue.Value.(uint16); ok {
+				i.s.Set(ZoneTypeKey, uint64(v))
+			}
+		case ias_zone.ZoneStatus:
+			if v, ok := rec.DataTypeValue.Value.(uint64); ok {
+				i.updateZoneStatus(v)
+			}
+		case ias_zone.IASCIEAddress:
+			currentCIE, _ = rec.DataTypeValue.Value.(zigbee.IEEEAddress)
+		}
+	}
+
+	if zoneState == zoneStateEnrolled && currentCIE == cieAddress {
+		i.logger.Info(ctx, "Alarm sensor already enrolled with this gateway, skipping enrollment.")
+		i.s.Set(EnrolledKey, true)
+		return true, nil
+	}
+
+	i.enrollMutex.Lock()
+	defer i.enrollMutex.Unlock()
+
+	if err := i.enroll(ctx, cieAddress); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// enrollIfRequired enrolls a sensor which has not been enrolled, used once a sensor which could not be enrolled during
+// enumeration is heard from and so is likely to be awake. Nothing is done if an enrollment is already in progress.
+func (i *Implementation) enrollIfRequired(ctx context.Context) {
+	if enrolled, _ := i.s.Bool(EnrolledKey); enrolled {
+		return
+	}
+
+	if !i.enrollMutex.TryLock() {
+		return
+	}
+	defer i.enrollMutex.Unlock()
+
+	if err := i.enroll(ctx, i.zi.AdapterNode().IEEEAddress); err != nil {
+		i.logger.Warn(ctx, "Failed to enroll alarm sensor upon hearing from it.", logwrap.Err(err))
+	}
+}
+
+func (i *Implementation) enroll(ctx context.Context, cieAddress zigbee.IEEEAddress) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	i.logger.Info(ctx, "Enrolling alarm sensor, writing IAS CIE address.", logwrap.Datum("CIEAddress", cieAddress.String()))
+
+	recs, err := i.zi.ZCLCommunicator().WriteAttributes(ctx, ieee, ack, zcl.IASZoneId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+		ias_zone.IASCIEAddress: {
+			DataType: zcl.TypeIEEEAddress,
+			Value:    cieAddress,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write ias cie address: %w", err)
+	}
+
+	if rec, found := communicator.WriteResponsesToMap(recs)[ias_zone.IASCIEAddress]; found && rec.Status != 0 {
+		return fmt.Errorf("failed to write ias cie address: status %d", rec.Status)
+	}
+
+	/* Some devices never send an enroll request, instead waiting for an unsolicited response (auto-enroll-response). */
+	if err := i.sendEnrollResponse(ctx); err != nil {
+		return fmt.Errorf("failed to send zone enroll response: %w", err)
+	}
+
+	return nil
+}
+
+// queueEnrollment holds the writing of the IAS CIE address and an unsolicited zone enroll response until the sensor
+// next checks in. The sensor is not marked as enrolled, as the queued messages may never be delivered, so it will still
+// be enrolled if heard from first.
+func (i *Implementation) queueEnrollment(ctx context.Context, cieAddress zigbee.IEEEAddress) error {
+	qctx := implcaps.QueueUntilCheckIn(ctx)
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if err := i.zi.ZCLCommunicator().Request(qctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameGlobal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.IASZoneId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   global.WriteAttributesID,
+		Command: &global.WriteAttributes{Records: []global.WriteAttributesRecord{
+			{Identifier: ias_zone.IASCIEAddress, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeIEEEAddress, Value: cieAddress}},
+		}},
+	}); err != nil {
+		return fmt.Errorf("failed to queue ias cie address write: %w", err)
+	}
+
+	ieee, localEndpoint, ack, seq = i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if err := i.zi.ZCLCommunicator().Request(qctx, ieee, ack, i.enrollResponse(localEndpoint, seq)); err != nil {
+		return fmt.Errorf("failed to queue zone enroll response: %w", err)
+	}
+
+	return nil
+}
+
+func (i *Implementation) enrollResponse(localEndpoint zigbee.Endpoint, seq uint8) zcl.Message {
+	return zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.IASZoneId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   ias_zone.ZoneEnrollResponseId,
+		Command:             &ias_zone.ZoneEnrollResponse{ResponseCode: 0x00, ZoneID: DefaultZoneID},
+	}
+}
+
+func (i *Implementation) sendEnrollResponse(ctx context.Context) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if err := i.zi.ZCLCommunicator().Request(ctx, ieee, ack, i.enrollResponse(localEndpoint, seq)); err != nil {
+		return err
+	}
+
+	i.s.Set(EnrolledKey, true)
+	i.s.Set(ZoneIDKey, DefaultZoneID)
+
+	return nil
+}
+
+func (i *Implementation) attachMatch() {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+	}
+
+	i.ieeeAddress, _, _, _ = i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	m := communicator.NewMatch(i.zclFilter, i.zclMessage)
+	i.match = &m
+	i.zi.ZCLCommunicator().RegisterMatch(m)
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+		i.match = nil
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLAlarmSensor"
+}
+
+func (i *Implementation) zclFilter(a zigbee.IEEEAddress, _ zigbee.ApplicationMessage, m zcl.Message) bool {
+	return a == i.ieeeAddress &&
+		m.SourceEndpoint == i.remoteEndpoint &&
+		m.Direction == zcl.ServerToClient &&
+		m.ClusterID == zcl.IASZoneId
+}
+
+func (i *Implementation) zclMessage(m communicator.MessageWithSource) {
+	switch cmd := m.Message.Command.(type) {
+	case *ias_zone.ZoneEnrollRequest:
+		i.logger.Info(context.Background(), "Received zone enroll request, responding.", logwrap.Datum("ZoneType", cmd.ZoneType))
+		i.s.Set(ZoneTypeKey, uint64(cmd.ZoneType))
+
+		ctx, cancel := context.WithTimeout(context.Background(), implcaps.DefaultNetworkTimeout)
+		defer cancel()
+
+		i.enrollMutex.Lock()
+		defer i.enrollMutex.Unlock()
+
+		if err := i.sendEnrollResponse(ctx); err != nil {
+			i.logger.Error(ctx, "Failed to respond to zone enroll request.", logwrap.Err(err))
+		}
+	case *ias_zone.ZoneStatusChangeNotification:
+		var status uint64
+
+		if cmd.Alarm1 {
+			status |= zoneStatusAlarm1
+		}
+
+		if cmd.Alarm2 {
+			status |= zoneStatusAlarm2
+		}
+
+		if cmd.Tamper {
+			status |= zoneStatusTamper
+		}
+
+		if cmd.BatteryLow {
+			status |= zoneStatusBatteryLow
+		}
+
+		i.updateZoneStatus(status)
+
+		ctx, cancel := context.WithTimeout(context.Background(), implcaps.DefaultNetworkTimeout)
+		defer cancel()
+
+		i.enrollIfRequired(ctx)
+	case *global.ReportAttributes:
+		for _, r := range cmd.Records {
+			if r.Identifier == ias_zone.ZoneStatus && r.DataTypeValue != nil {
+				if v, ok := r.DataTypeValue.Value.(uint64); ok {
+					i.updateZoneStatus(v)
+				}
+			}
+		}
+	case *global.ReadAttributesResponse:
+		for _, r := range cmd.Records {
+			if r.Identifier == ias_zone.ZoneStatus && r.Status == 0 && r.DataTypeValue != nil {
+				if v, ok := r.DataTypeValue.Value.(uint64); ok {
+					i.updateZoneStatus(v)
+				}
+			}
+		}
+	}
+}
+
+func (i *Implementation) updateZoneStatus(status uint64) {
+	if current, found := i.s.UInt(ZoneStatusKey); !found || current != status {
+		i.s.Set(ZoneStatusKey, status)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		i.zi.SendEvent(capabilities.AlarmSensorUpdate{Device: i.d, States: i.states()})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) sensorType() capabilities.SensorType {
+	zoneType, _ := i.s.UInt(ZoneTypeKey)
+
+	if st, found := zoneTypeMapping[uint16(zoneType)]; found {
+		return st
+	}
+
+	return capabilities.General
+}
+
+func (i *Implementation) states() map[capabilities.SensorType]bool {
+	status, _ := i.s.UInt(ZoneStatusKey)
+
+	return map[capabilities.SensorType]bool{
+		i.sensorType():                status&(zoneStatusAlarm1|zoneStatusAlarm2) != 0,
+		capabilities.DeviceTamper:     status&zoneStatusTamper != 0,
+		capabilities.DeviceBatteryLow: status&zoneStatusBatteryLow != 0,
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (map[capabilities.SensorType]bool, error) {
+	return i.states(), nil
+}
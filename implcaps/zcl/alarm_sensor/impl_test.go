@@ -0,0 +1,320 @@
+package alarm_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/ias_zone"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewAlarmSensor(mzi)
+	i.Init(nil, memory.New())
+
+	return i, mzi, mzc
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewAlarmSensor(newMockZDAInterface(t))
+
+		assert.Equal(t, capabilities.AlarmSensorFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.AlarmSensorFlag], i.Name())
+		assert.Equal(t, "ZCLAlarmSensor", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("registers the match without enrolling, returning true if successful", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 4)
+		i.s.Set(EnrolledKey, true)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(4), i.remoteEndpoint)
+		assert.Equal(t, zigbee.IEEEAddress(1), i.ieeeAddress)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("skips enrollment if already enrolled with the adapter", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		mzi.On("AdapterNode").Return(zigbee.Node{IEEEAddress: 0xaa})
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.IASZoneId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{ias_zone.ZoneState, ias_zone.ZoneType, ias_zone.ZoneStatus, ias_zone.IASCIEAddress}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: ias_zone.ZoneState, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(1)}},
+			{Identifier: ias_zone.ZoneType, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum16, Value: uint16(0x0015)}},
+			{Identifier: ias_zone.ZoneStatus, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeBitmap16, Value: uint64(0x0001)}},
+			{Identifier: ias_zone.IASCIEAddress, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeIEEEAddress, Value: zigbee.IEEEAddress(0xaa)}},
+		}, nil)
+		mzi.On("SendEvent", mock.Anything)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.True(t, enrolled)
+
+		status, _ := i.Status(context.TODO())
+		assert.True(t, status[capabilities.SecurityContact])
+	})
+
+	t.Run("writes the cie address and sends an enroll response if not enrolled", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		mzi.On("AdapterNode").Return(zigbee.Node{IEEEAddress: 0xaa})
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.IASZoneId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), mock.Anything).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: ias_zone.ZoneState, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0)}},
+		}, nil)
+		mzc.On("WriteAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.IASZoneId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			ias_zone.IASCIEAddress: {DataType: zcl.TypeIEEEAddress, Value: zigbee.IEEEAddress(0xaa)},
+		}).Return([]global.WriteAttributesResponseRecord{{Identifier: ias_zone.IASCIEAddress, Status: 0}}, nil)
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			cmd, ok := m.Command.(*ias_zone.ZoneEnrollResponse)
+			return ok && m.CommandIdentifier == ias_zone.ZoneEnrollResponseId && cmd.ZoneID == DefaultZoneID && m.DestinationEndpoint == 4
+		})).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.True(t, enrolled)
+	})
+
+	t.Run("fails if writing the cie address is rejected", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		mzi.On("AdapterNode").Return(zigbee.Node{IEEEAddress: 0xaa})
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, nil)
+		mzc.On("WriteAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.WriteAttributesResponseRecord{{Identifier: ias_zone.IASCIEAddress, Status: 0x86}}, nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+
+	t.Run("attaches without enrolling if the sleeping sensor can not be read", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		mzi.On("AdapterNode").Return(zigbee.Node{IEEEAddress: 0xaa})
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.False(t, enrolled)
+	})
+
+	t.Run("queues enrollment until check in if the sleeping sensor can not be read and has poll control", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		mzi.On("AdapterNode").Return(zigbee.Node{IEEEAddress: 0xaa})
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+
+		var queued []zcl.CommandIdentifier
+
+		mzc.On("Request", mock.MatchedBy(implcaps.IsQueuedUntilCheckIn), zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			return m.ClusterID == zcl.IASZoneId && m.DestinationEndpoint == 4
+		})).Run(func(args mock.Arguments) {
+			m := args.Get(3).(zcl.Message)
+			queued = append(queued, m.CommandIdentifier)
+
+			if cmd, ok := m.Command.(*global.WriteAttributes); ok {
+				assert.Equal(t, zigbee.IEEEAddress(0xaa), cmd.Records[0].DataTypeValue.Value)
+			}
+		}).Return(nil).Twice()
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4), "ZigbeePollControlClusterPresent": true})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, []zcl.CommandIdentifier{global.WriteAttributesID, ias_zone.ZoneEnrollResponseId}, queued)
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.False(t, enrolled)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("unregisters the match", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+
+		i.attachMatch()
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+		assert.Nil(t, i.match)
+	})
+}
+
+func TestImplementation_zclFilter(t *testing.T) {
+	t.Run("only accepts messages from the device's ias zone cluster", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		i.ieeeAddress = 1
+		i.remoteEndpoint = 4
+
+		valid := zcl.Message{SourceEndpoint: 4, Direction: zcl.ServerToClient, ClusterID: zcl.IASZoneId}
+		assert.True(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, valid))
+
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(2), zigbee.ApplicationMessage{}, valid))
+
+		wrongCluster := valid
+		wrongCluster.ClusterID = zcl.OnOffId
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongCluster))
+
+		mzi.AssertNotCalled(t, "TransmissionLookup", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImplementation_zclMessage(t *testing.T) {
+	t.Run("responds to a zone enroll request and records the zone type", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+		i.remoteEndpoint = 4
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			return m.CommandIdentifier == ias_zone.ZoneEnrollResponseId
+		})).Return(nil)
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &ias_zone.ZoneEnrollRequest{ZoneType: 0x000d}}})
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.True(t, enrolled)
+		assert.Equal(t, capabilities.SecurityMotion, i.sensorType())
+	})
+
+	t.Run("updates state from a zone status change notification", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		i.s.Set(EnrolledKey, true)
+		i.s.Set(ZoneTypeKey, uint64(0x002b))
+
+		mzi.On("SendEvent", capabilities.AlarmSensorUpdate{States: map[capabilities.SensorType]bool{
+			capabilities.GasCarbonMonoxide: true,
+			capabilities.DeviceTamper:      true,
+			capabilities.DeviceBatteryLow:  false,
+		}}).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &ias_zone.ZoneStatusChangeNotification{Alarm2: true, Tamper: true}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &ias_zone.ZoneStatusChangeNotification{Alarm2: true, Tamper: true}}})
+
+		lastUpdated, _ := i.LastUpdateTime(context.TODO())
+		lastChanged, _ := i.LastChangeTime(context.TODO())
+		assert.NotZero(t, lastUpdated)
+		assert.NotZero(t, lastChanged)
+	})
+
+	t.Run("enrolls a sensor which has not been enrolled upon a zone status change", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		i.remoteEndpoint = 4
+
+		hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+			_, ok := ctx.Deadline()
+			return ok
+		})
+
+		mzi.On("SendEvent", mock.Anything)
+		mzi.On("AdapterNode").Return(zigbee.Node{IEEEAddress: 0xaa})
+		mzc.On("WriteAttributes", hasDeadline, zigbee.IEEEAddress(1), false, zcl.IASZoneId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			ias_zone.IASCIEAddress: {DataType: zcl.TypeIEEEAddress, Value: zigbee.IEEEAddress(0xaa)},
+		}).Return([]global.WriteAttributesResponseRecord{{Identifier: ias_zone.IASCIEAddress, Status: 0}}, nil).Once()
+		mzc.On("Request", hasDeadline, zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			return m.CommandIdentifier == ias_zone.ZoneEnrollResponseId
+		})).Return(nil).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &ias_zone.ZoneStatusChangeNotification{Alarm1: true}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &ias_zone.ZoneStatusChangeNotification{}}})
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.True(t, enrolled)
+	})
+
+	t.Run("does not enroll upon a zone status change while an enrollment is in progress", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		i.remoteEndpoint = 4
+
+		mzi.On("SendEvent", mock.Anything)
+
+		i.enrollMutex.Lock()
+		defer i.enrollMutex.Unlock()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &ias_zone.ZoneStatusChangeNotification{Alarm1: true}}})
+
+		enrolled, _ := i.s.Bool(EnrolledKey)
+		assert.False(t, enrolled)
+	})
+
+	t.Run("updates state from an attribute report of zone status", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("SendEvent", mock.Anything).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &global.ReportAttributes{Records: []global.ReportAttributesRecord{
+			{Identifier: ias_zone.ZoneStatus, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeBitmap16, Value: uint64(0x0008)}},
+		}}}})
+
+		status, err := i.Status(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, map[capabilities.SensorType]bool{
+			capabilities.General:          false,
+			capabilities.DeviceTamper:     false,
+			capabilities.DeviceBatteryLow: true,
+		}, status)
+	})
+}
@@ -0,0 +1,286 @@
+package alarm_warning_device
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/ias_warning_device"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ capabilities.AlarmWarningDevice = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	EndTimeKey     = "EndTime"
+	MaxDurationKey = "MaxDuration"
+	AlarmTypeKey   = "AlarmType"
+	VolumeKey      = "Volume"
+	VisualKey      = "Visual"
+)
+
+// DefaultMaxDuration is the ZCL default value of the MaxDuration attribute, used if the device does not report one.
+const DefaultMaxDuration = 240 * time.Second
+
+func NewAlarmWarningDevice(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(ias_warning_device.Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), timerMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+
+	timerMutex *sync.Mutex
+	timer      *time.Timer
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.AlarmWarningDeviceFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.AlarmWarningDeviceFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("alarm warning device missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	if i.remaining() > 0 {
+		i.periodicSendEvent()
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	maxDuration := DefaultMaxDuration
+
+	if recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.IASWarningDevicesId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{ias_warning_device.MaxDuration}); err != nil {
+		i.logger.Warn(ctx, "Failed to read MaxDuration from warning device, using default.", logwrap.Err(err))
+	} else if rec, found := communicator.ReadResponsesToMap(recs)[ias_warning_device.MaxDuration]; found && rec.Status == 0 && rec.DataTypeValue != nil {
+		if v, ok := rec.DataTypeValue.Value.(uint64); ok {
+			maxDuration = time.Duration(v) * time.Second
+		}
+	}
+
+	converter.Store(i.s, MaxDurationKey, maxDuration, converter.DurationEncoder)
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	if i.timer != nil {
+		i.timer.Stop()
+		i.timer = nil
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLAlarmWarningDevice"
+}
+
+func (i *Implementation) maxDuration() time.Duration {
+	d, _ := converter.Retrieve(i.s, MaxDurationKey, converter.DurationDecoder, DefaultMaxDuration)
+	return d
+}
+
+func (i *Implementation) remaining() time.Duration {
+	endTime, _ := converter.Retrieve(i.s, EndTimeKey, converter.TimeDecoder, time.Now())
+
+	if remaining := endTime.Sub(time.Now()); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+func (i *Implementation) updateWarning(alarmType capabilities.AlarmType, volume float64, visual bool, duration time.Duration) {
+	i.s.Set(AlarmTypeKey, uint64(alarmType))
+	i.s.Set(VolumeKey, volume)
+	i.s.Set(VisualKey, visual)
+
+	converter.Store(i.s, EndTimeKey, time.Now().Add(duration), converter.TimeEncoder)
+	converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	i.periodicSendEvent()
+}
+
+func (i *Implementation) periodicSendEvent() {
+	warning := i.sendEvent()
+
+	i.timerMutex.Lock()
+	defer i.timerMutex.Unlock()
+
+	if i.timer != nil {
+		i.timer.Stop()
+	}
+
+	if warning {
+		i.timer = time.AfterFunc(1*time.Second, i.periodicSendEvent)
+	} else {
+		i.timer = nil
+	}
+}
+
+func (i *Implementation) sendEvent() bool {
+	state := i.state()
+	i.zi.SendEvent(capabilities.AlarmWarningDeviceUpdate{Device: i.d, State: state})
+	return state.Warning
+}
+
+func (i *Implementation) state() capabilities.WarningDeviceState {
+	remaining := i.remaining()
+
+	alarmType, _ := i.s.UInt(AlarmTypeKey)
+	volume, _ := i.s.Float(VolumeKey)
+	visual, _ := i.s.Bool(VisualKey)
+
+	return capabilities.WarningDeviceState{
+		Warning:           remaining > 0,
+		AlarmType:         capabilities.AlarmType(alarmType),
+		Volume:            volume,
+		Visual:            visual,
+		DurationRemaining: remaining,
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (capabilities.WarningDeviceState, error) {
+	return i.state(), nil
+}
+
+func (i *Implementation) Alarm(ctx context.Context, alarmType capabilities.AlarmType, volume float64, visual bool, duration time.Duration) error {
+	if maxDuration := i.maxDuration(); duration > maxDuration {
+		duration = maxDuration
+	}
+
+	strobe := ias_warning_device.NoStrobe
+	if visual {
+		strobe = ias_warning_device.StrobeWithWarning
+	}
+
+	/* The ZCL library names the siren level bits as reserved, they are the lower two bits of the warning bitmap. */
+	if err := i.sendCommand(ctx, ias_warning_device.StartWarningId, &ias_warning_device.StartWarning{
+		WarningMode:     alarmTypeToWarningMode(alarmType),
+		StrobeMode:      strobe,
+		Reserved:        uint8(volumeToSquawkLevel(volume)),
+		WarningDuration: uint16(duration / time.Second),
+	}); err != nil {
+		return err
+	}
+
+	i.updateWarning(alarmType, volume, visual, duration.Truncate(time.Second))
+	return nil
+}
+
+func (i *Implementation) Clear(ctx context.Context) error {
+	if err := i.sendCommand(ctx, ias_warning_device.StartWarningId, &ias_warning_device.StartWarning{
+		WarningMode:     ias_warning_device.Stop,
+		StrobeMode:      ias_warning_device.NoStrobe,
+		WarningDuration: 0,
+	}); err != nil {
+		return err
+	}
+
+	i.updateWarning(capabilities.GeneralAlarm, 0, false, 0)
+	return nil
+}
+
+func (i *Implementation) Alert(ctx context.Context, _ capabilities.AlarmType, alertType capabilities.AlertType, volume float64, visual bool) error {
+	mode := ias_warning_device.SystemArmed
+	if alertType == capabilities.DisarmAlert {
+		mode = ias_warning_device.SystemDisarmed
+	}
+
+	return i.sendCommand(ctx, ias_warning_device.SquawkId, &ias_warning_device.Squawk{
+		SquawkMode:  mode,
+		Strobe:      visual,
+		SquawkLevel: volumeToSquawkLevel(volume),
+	})
+}
+
+func (i *Implementation) sendCommand(ctx context.Context, id zcl.CommandIdentifier, cmd any) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.IASWarningDevicesId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+}
+
+func alarmTypeToWarningMode(alarmType capabilities.AlarmType) ias_warning_device.WarningMode {
+	switch alarmType {
+	case capabilities.SecurityAlarm, capabilities.TamperAlarm:
+		return ias_warning_device.Burglar
+	case capabilities.FireAlarm:
+		return ias_warning_device.Fire
+	default:
+		return ias_warning_device.Emergency
+	}
+}
+
+func volumeToSquawkLevel(volume float64) ias_warning_device.SquawkLevel {
+	switch {
+	case volume < 0.25:
+		return ias_warning_device.Low
+	case volume < 0.5:
+		return ias_warning_device.Medium
+	case volume < 0.75:
+		return ias_warning_device.High
+	default:
+		return ias_warning_device.VeryHigh
+	}
+}
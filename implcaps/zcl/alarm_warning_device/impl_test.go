@@ -0,0 +1,187 @@
+package alarm_warning_device
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/ias_warning_device"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewAlarmWarningDevice(mzi)
+	i.Init(nil, memory.New())
+	i.remoteEndpoint = 4
+
+	t.Cleanup(func() { _ = i.Detach(context.TODO(), implcaps.NoLongerEnumerated) })
+
+	return i, mzi, mzc
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		assert.Equal(t, capabilities.AlarmWarningDeviceFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.AlarmWarningDeviceFlag], i.Name())
+		assert.Equal(t, "ZCLAlarmWarningDevice", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads the remote endpoint, returning true if successful", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.remoteEndpoint)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads and stores the max duration", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.IASWarningDevicesId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{ias_warning_device.MaxDuration}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: ias_warning_device.MaxDuration, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(60)}},
+		}, nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, 60*time.Second, i.maxDuration())
+	})
+
+	t.Run("uses the default max duration if the read fails", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultMaxDuration, i.maxDuration())
+	})
+}
+
+func TestImplementation_Alarm(t *testing.T) {
+	t.Run("sends a start warning, clamping duration and tracking remaining time", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		converter.Store(i.s, MaxDurationKey, 30*time.Second, converter.DurationEncoder)
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: 3,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.IASWarningDevicesId,
+			SourceEndpoint:      2,
+			DestinationEndpoint: 4,
+			CommandIdentifier:   ias_warning_device.StartWarningId,
+			Command: &ias_warning_device.StartWarning{
+				WarningMode:     ias_warning_device.Fire,
+				StrobeMode:      ias_warning_device.StrobeWithWarning,
+				Reserved:        uint8(ias_warning_device.VeryHigh),
+				WarningDuration: 30,
+			},
+		}).Return(nil)
+		mzi.On("SendEvent", mock.MatchedBy(func(e capabilities.AlarmWarningDeviceUpdate) bool {
+			return e.State.Warning && e.State.AlarmType == capabilities.FireAlarm && e.State.Visual && e.State.Volume == 1.0
+		}))
+
+		err := i.Alarm(context.TODO(), capabilities.FireAlarm, 1.0, true, time.Minute)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.True(t, state.Warning)
+		assert.InDelta(t, 30*time.Second, state.DurationRemaining, float64(time.Second))
+
+		lastChanged, _ := i.LastChangeTime(context.TODO())
+		assert.NotZero(t, lastChanged)
+	})
+
+	t.Run("returns an error if the request fails", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+		mzc.On("Request", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(io.EOF)
+
+		err := i.Alarm(context.TODO(), capabilities.SecurityAlarm, 0.5, false, time.Second)
+		assert.ErrorIs(t, err, io.EOF)
+
+		state, _ := i.Status(context.TODO())
+		assert.False(t, state.Warning)
+	})
+}
+
+func TestImplementation_Clear(t *testing.T) {
+	t.Run("sends a stop warning and clears remaining time", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		converter.Store(i.s, EndTimeKey, time.Now().Add(time.Minute), converter.TimeEncoder)
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			cmd, ok := m.Command.(*ias_warning_device.StartWarning)
+			return ok && cmd.WarningMode == ias_warning_device.Stop && cmd.WarningDuration == 0
+		})).Return(nil)
+		mzi.On("SendEvent", mock.MatchedBy(func(e capabilities.AlarmWarningDeviceUpdate) bool {
+			return !e.State.Warning
+		}))
+
+		err := i.Clear(context.TODO())
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.False(t, state.Warning)
+		assert.Zero(t, state.DurationRemaining)
+	})
+}
+
+func TestImplementation_Alert(t *testing.T) {
+	t.Run("sends a squawk", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			cmd, ok := m.Command.(*ias_warning_device.Squawk)
+			return ok && m.CommandIdentifier == ias_warning_device.SquawkId && cmd.SquawkMode == ias_warning_device.SystemDisarmed && cmd.Strobe && cmd.SquawkLevel == ias_warning_device.Low
+		})).Return(nil)
+
+		err := i.Alert(context.TODO(), capabilities.SecurityAlarm, capabilities.DisarmAlert, 0.1, true)
+		assert.NoError(t, err)
+	})
+}
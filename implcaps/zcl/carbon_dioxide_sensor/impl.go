@@ -0,0 +1,134 @@
+package carbon_dioxide_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ extcaps.CarbonDioxideSensor = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+func NewCarbonDioxideSensor(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	am attribute.Monitor
+	zi implcaps.ZDAInterface
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.CarbonDioxideSensorFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.CarbonDioxideSensorFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = i.zi.NewAttributeMonitor()
+	i.am.Init(s.Section("AttributeMonitor", "CarbonDioxideReading"), d, i.update)
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if err := i.am.Load(ctx); err != nil {
+		return false, err
+	} else {
+		return true, nil
+	}
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	endpoint := implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	/* MeasuredValue is a fraction of one, a change of 0.00002 represents 20 parts per million. */
+	reporting := attribute.ReportingConfig{
+		Mode:             attribute.AttemptConfigureReporting,
+		MinimumInterval:  1 * time.Minute,
+		MaximumInterval:  5 * time.Minute,
+		ReportableChange: 0.00002,
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	if err := i.am.Attach(ctx, endpoint, zcl.AirConcentrationCarbonDioxideId, implcaps.MeasuredValue, zcl.TypeFloatSingle, reporting, polling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	if err := i.am.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLCarbonDioxideSensor"
+}
+
+func (i *Implementation) update(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType == zcl.TypeFloatSingle {
+		if value, ok := v.Value.(float32); ok {
+			/* An invalid measurement is reported as NaN. */
+			if math.IsNaN(float64(value)) {
+				return
+			}
+
+			newValue := float64(value) * 1000000.0
+			currentValue, _ := i.s.Float(implcaps.ReadingKey)
+
+			if math.Abs(newValue-currentValue) > 1.0 {
+				i.s.Set(implcaps.ReadingKey, newValue)
+				converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+				i.zi.SendEvent(extcaps.CarbonDioxideSensorUpdate{Device: i.d, State: []extcaps.CarbonDioxideReading{{Value: newValue}}})
+			}
+
+			converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+		}
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Reading(_ context.Context) ([]extcaps.CarbonDioxideReading, error) {
+	v, _ := i.s.Float(implcaps.ReadingKey)
+
+	return []extcaps.CarbonDioxideReading{
+		{
+			Value: v,
+		},
+	}, nil
+}
@@ -0,0 +1,54 @@
+package door_lock
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the Door Lock cluster, the subset required is defined here. */
+
+const (
+	LockState = zcl.AttributeID(0x0000)
+	DoorState = zcl.AttributeID(0x0003)
+)
+
+const (
+	LockDoorId                   = zcl.CommandIdentifier(0x00)
+	UnlockDoorId                 = zcl.CommandIdentifier(0x01)
+	LockDoorResponseId           = zcl.CommandIdentifier(0x00)
+	UnlockDoorResponseId         = zcl.CommandIdentifier(0x01)
+	OperationEventNotificationId = zcl.CommandIdentifier(0x20)
+)
+
+type LockDoor struct {
+	PINCode string
+}
+
+type UnlockDoor struct {
+	PINCode string
+}
+
+type LockDoorResponse struct {
+	Status uint8
+}
+
+type UnlockDoorResponse struct {
+	Status uint8
+}
+
+type OperationEventNotification struct {
+	OperationEventSource uint8
+	OperationEventCode   uint8
+	UserID               uint16
+	PIN                  string
+	LocalTime            uint32
+	Data                 string
+}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.DoorLockId, zigbee.NoManufacturer, zcl.ClientToServer, LockDoorId, &LockDoor{})
+	cr.RegisterLocal(zcl.DoorLockId, zigbee.NoManufacturer, zcl.ClientToServer, UnlockDoorId, &UnlockDoor{})
+	cr.RegisterLocal(zcl.DoorLockId, zigbee.NoManufacturer, zcl.ServerToClient, LockDoorResponseId, &LockDoorResponse{})
+	cr.RegisterLocal(zcl.DoorLockId, zigbee.NoManufacturer, zcl.ServerToClient, UnlockDoorResponseId, &UnlockDoorResponse{})
+	cr.RegisterLocal(zcl.DoorLockId, zigbee.NoManufacturer, zcl.ServerToClient, OperationEventNotificationId, &OperationEventNotification{})
+}
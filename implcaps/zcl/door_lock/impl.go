@@ -0,0 +1,326 @@
+package door_lock
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ extcaps.DoorLock = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	LockStateKey          = "LockState"
+	DoorStateKey          = "DoorState"
+	DoorStateSupportedKey = "DoorStateSupported"
+)
+
+func NewDoorLock(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), matchMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	ieeeAddress    zigbee.IEEEAddress
+	remoteEndpoint zigbee.Endpoint
+
+	lockMonitor attribute.Monitor
+	doorMonitor attribute.Monitor
+
+	doorStateSupported bool
+
+	matchMutex *sync.Mutex
+	match      *communicator.Match
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.DoorLockFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.DoorLockFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.lockMonitor = i.zi.NewAttributeMonitor()
+	i.lockMonitor.Init(s.Section("AttributeMonitor", "LockState"), d, i.update)
+	i.doorMonitor = i.zi.NewAttributeMonitor()
+	i.doorMonitor.Init(s.Section("AttributeMonitor", "DoorState"), d, i.update)
+}
+
+// monitors returns the attribute monitors in use, DoorState is optional and only monitored if the lock supports it.
+func (i *Implementation) monitors() map[string]attribute.Monitor {
+	m := map[string]attribute.Monitor{
+		"LockState": i.lockMonitor,
+	}
+
+	if i.doorStateSupported {
+		m["DoorState"] = i.doorMonitor
+	}
+
+	return m
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("door lock missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.doorStateSupported, _ = i.s.Bool(DoorStateSupportedKey)
+
+	for name, m := range i.monitors() {
+		if err := m.Load(ctx); err != nil {
+			return false, fmt.Errorf("%s monitor, load failed: %w", name, err)
+		}
+	}
+
+	i.attachMatch()
+
+	return true, nil
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.attachMatch()
+
+	i.doorStateSupported = false
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	if recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.DoorLockId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{LockState, DoorState}); err != nil {
+		i.logger.Warn(ctx, "Failed to read door lock attributes, assuming door state is unsupported.", logwrap.Err(err))
+	} else {
+		for id, rec := range communicator.ReadResponsesToMap(recs) {
+			if rec.Status != 0 || rec.DataTypeValue == nil {
+				continue
+			}
+
+			if id == DoorState {
+				i.doorStateSupported = true
+			}
+
+			i.update(id, *rec.DataTypeValue)
+		}
+	}
+
+	i.s.Set(DoorStateSupportedKey, i.doorStateSupported)
+
+	reporting := attribute.ReportingConfig{
+		Mode:             attribute.AttemptConfigureReporting,
+		MinimumInterval:  0 * time.Second,
+		MaximumInterval:  5 * time.Minute,
+		ReportableChange: nil,
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 30 * time.Second,
+	}
+
+	attributes := map[string]zcl.AttributeID{
+		"LockState": LockState,
+		"DoorState": DoorState,
+	}
+
+	for name, mon := range i.monitors() {
+		if err := mon.Attach(ctx, i.remoteEndpoint, zcl.DoorLockId, attributes[name], zcl.TypeEnum8, reporting, polling); err != nil {
+			i.logger.Warn(ctx, "Failed to attach door lock attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", name))
+			return false, fmt.Errorf("%s monitor, attach failed: %w", name, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) attachMatch() {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+	}
+
+	i.ieeeAddress, _, _, _ = i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	m := communicator.NewMatch(i.zclFilter, i.zclMessage)
+	i.match = &m
+	i.zi.ZCLCommunicator().RegisterMatch(m)
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	i.matchMutex.Lock()
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+		i.match = nil
+	}
+	i.matchMutex.Unlock()
+
+	for name, m := range i.monitors() {
+		if err := m.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+			return fmt.Errorf("%s monitor, detach failed: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLDoorLock"
+}
+
+func (i *Implementation) zclFilter(a zigbee.IEEEAddress, _ zigbee.ApplicationMessage, m zcl.Message) bool {
+	return a == i.ieeeAddress &&
+		m.SourceEndpoint == i.remoteEndpoint &&
+		m.Direction == zcl.ServerToClient &&
+		m.ClusterID == zcl.DoorLockId
+}
+
+func (i *Implementation) zclMessage(m communicator.MessageWithSource) {
+	if cmd, ok := m.Message.Command.(*OperationEventNotification); ok {
+		source := extcaps.LockOperationSource(cmd.OperationEventSource)
+		operation := extcaps.LockOperation(cmd.OperationEventCode)
+
+		i.logger.Info(context.Background(), "Door lock operated.", logwrap.Datum("Source", source.String()), logwrap.Datum("Operation", operation.String()), logwrap.Datum("UserID", cmd.UserID))
+		i.zi.SendEvent(extcaps.DoorLockOperated{Device: i.d, Source: source, Operation: operation, UserID: cmd.UserID})
+	}
+}
+
+func (i *Implementation) update(id zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType != zcl.TypeEnum8 {
+		return
+	}
+
+	raw, ok := v.Value.(uint8)
+	if !ok {
+		return
+	}
+
+	value := uint64(raw)
+
+	var key string
+
+	switch id {
+	case LockState:
+		key = LockStateKey
+	case DoorState:
+		key = DoorStateKey
+	default:
+		return
+	}
+
+	if current, found := i.s.UInt(key); !found || current != value {
+		i.s.Set(key, value)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		i.zi.SendEvent(extcaps.DoorLockUpdate{Device: i.d, State: i.state()})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) state() extcaps.DoorLockState {
+	state := extcaps.DoorLockState{Lock: extcaps.LockUnknown, Door: extcaps.DoorUnsupported}
+
+	if v, found := i.s.UInt(LockStateKey); found {
+		state.Lock = extcaps.LockState(v)
+	}
+
+	if i.doorStateSupported {
+		state.Door = extcaps.DoorUnknown
+
+		if v, found := i.s.UInt(DoorStateKey); found {
+			state.Door = extcaps.DoorState(v)
+		}
+	}
+
+	return state
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (extcaps.DoorLockState, error) {
+	return i.state(), nil
+}
+
+func (i *Implementation) Lock(ctx context.Context, pin string) error {
+	return i.sendCommand(ctx, LockDoorId, &LockDoor{PINCode: pin})
+}
+
+func (i *Implementation) Unlock(ctx context.Context, pin string) error {
+	return i.sendCommand(ctx, UnlockDoorId, &UnlockDoor{PINCode: pin})
+}
+
+// sendCommand sends a lock or unlock command and waits for the locks response, the resulting lock state will be
+// reported back by the attribute monitor.
+func (i *Implementation) sendCommand(ctx context.Context, id zcl.CommandIdentifier, cmd any) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	resp, err := i.zi.ZCLCommunicator().RequestResponse(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.DoorLockId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+	if err != nil {
+		return err
+	}
+
+	var status uint8
+
+	switch r := resp.Command.(type) {
+	case *LockDoorResponse:
+		status = r.Status
+	case *UnlockDoorResponse:
+		status = r.Status
+	case *global.DefaultResponse:
+		status = r.Status
+	default:
+		return fmt.Errorf("unexpected response to door lock command: %T", resp.Command)
+	}
+
+	if status != 0 {
+		return fmt.Errorf("door lock command failed: status %d", status)
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package door_lock
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator, *attribute.MockMonitor, *attribute.MockMonitor) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	lock := &attribute.MockMonitor{}
+	t.Cleanup(func() { lock.AssertExpectations(t) })
+
+	door := &attribute.MockMonitor{}
+	t.Cleanup(func() { door.AssertExpectations(t) })
+
+	i := NewDoorLock(mzi)
+	i.s = memory.New()
+	i.lockMonitor = lock
+	i.doorMonitor = door
+
+	return i, mzi, mzc, lock, door
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewDoorLock(newMockZDAInterface(t))
+
+		assert.Equal(t, extcaps.DoorLockFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.DoorLockFlag], i.Name())
+		assert.Equal(t, "ZCLDoorLock", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs attribute monitors correctly initialising them", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm).Twice()
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		mm.On("Init", mock.Anything, md, mock.Anything).Twice()
+
+		i := NewDoorLock(mzi)
+		i.Init(md, memory.New())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads monitors and registers the match, returning true if successful", func(t *testing.T) {
+		i, _, mzc, lock, door := newImplementation(t)
+		lock.On("Load", mock.Anything).Return(nil)
+		door.On("Load", mock.Anything).Return(nil)
+		mzc.On("RegisterMatch", mock.Anything)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 4)
+		i.s.Set(DoorStateSupportedKey, true)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(4), i.remoteEndpoint)
+		assert.Equal(t, zigbee.IEEEAddress(1), i.ieeeAddress)
+	})
+
+	t.Run("fails if the lock state monitor fails to load", func(t *testing.T) {
+		i, _, _, lock, _ := newImplementation(t)
+		lock.On("Load", mock.Anything).Return(io.EOF)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 4)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads current state and attaches monitors for supported attributes", func(t *testing.T) {
+		i, mzi, mzc, lock, door := newImplementation(t)
+
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.DoorLockId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{LockState, DoorState}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: LockState, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(1)}},
+			{Identifier: DoorState, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(1)}},
+		}, nil)
+		mzi.On("SendEvent", mock.Anything)
+
+		lock.On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.DoorLockId, LockState, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(nil)
+		door.On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.DoorLockId, DoorState, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.DoorLockState{Lock: extcaps.Locked, Door: extcaps.DoorClosed}, state)
+	})
+
+	t.Run("does not monitor door state if it is unsupported", func(t *testing.T) {
+		i, mzi, mzc, lock, _ := newImplementation(t)
+
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: LockState, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(2)}},
+			{Identifier: DoorState, Status: 0x86},
+		}, nil)
+		mzi.On("SendEvent", mock.Anything)
+
+		lock.On("Attach", mock.Anything, zigbee.Endpoint(1), zcl.DoorLockId, LockState, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.DoorLockState{Lock: extcaps.Unlocked, Door: extcaps.DoorUnsupported}, state)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("unregisters the match and detaches monitors", func(t *testing.T) {
+		i, _, mzc, lock, _ := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+		lock.On("Detach", mock.Anything, false).Return(nil)
+
+		i.attachMatch()
+
+		err := i.Detach(context.TODO(), implcaps.DeviceRemoved)
+		assert.NoError(t, err)
+		assert.Nil(t, i.match)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates lock state, sending an event on change", func(t *testing.T) {
+		i, mzi, _, _, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.DoorLockUpdate{State: extcaps.DoorLockState{Lock: extcaps.Locked, Door: extcaps.DoorUnsupported}}).Once()
+
+		i.update(LockState, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(1)})
+		i.update(LockState, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(1)})
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.NotZero(t, lct)
+	})
+}
+
+func TestImplementation_zclMessage(t *testing.T) {
+	t.Run("operation event notifications are sent as events", func(t *testing.T) {
+		i, mzi, _, _, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.DoorLockOperated{Source: extcaps.SourceKeypad, Operation: extcaps.OperationUnlock, UserID: 3})
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &OperationEventNotification{OperationEventSource: 0x00, OperationEventCode: 0x02, UserID: 3}}})
+	})
+
+	t.Run("filter only matches messages from the lock", func(t *testing.T) {
+		i, mzi, _, _, _ := newImplementation(t)
+		i.ieeeAddress = 1
+		i.remoteEndpoint = 4
+
+		assert.True(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, zcl.Message{SourceEndpoint: 4, Direction: zcl.ServerToClient, ClusterID: zcl.DoorLockId}))
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(2), zigbee.ApplicationMessage{}, zcl.Message{SourceEndpoint: 4, Direction: zcl.ServerToClient, ClusterID: zcl.DoorLockId}))
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, zcl.Message{SourceEndpoint: 4, Direction: zcl.ServerToClient, ClusterID: zcl.OnOffId}))
+
+		mzi.AssertNotCalled(t, "TransmissionLookup", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImplementation_LockUnlock(t *testing.T) {
+	t.Run("lock sends lock door with pin and succeeds on successful response", func(t *testing.T) {
+		i, _, mzc, _, _ := newImplementation(t)
+		i.remoteEndpoint = 4
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: 3,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.DoorLockId,
+			SourceEndpoint:      2,
+			DestinationEndpoint: 4,
+			CommandIdentifier:   LockDoorId,
+			Command:             &LockDoor{PINCode: "1234"},
+		}).Return(zcl.Message{Command: &LockDoorResponse{Status: 0}}, nil)
+
+		assert.NoError(t, i.Lock(context.TODO(), "1234"))
+	})
+
+	t.Run("unlock fails if the lock responds with a failure", func(t *testing.T) {
+		i, _, mzc, _, _ := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(m zcl.Message) bool {
+			return m.CommandIdentifier == UnlockDoorId
+		})).Return(zcl.Message{Command: &UnlockDoorResponse{Status: 1}}, nil)
+
+		assert.Error(t, i.Unlock(context.TODO(), ""))
+	})
+
+	t.Run("lock fails if the lock responds with a failing default response", func(t *testing.T) {
+		i, _, mzc, _, _ := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(zcl.Message{Command: &global.DefaultResponse{Status: 0x81}}, nil)
+
+		assert.Error(t, i.Lock(context.TODO(), ""))
+	})
+
+	t.Run("lock fails if the request errors", func(t *testing.T) {
+		i, _, mzc, _, _ := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(zcl.Message{}, io.EOF)
+
+		assert.ErrorIs(t, i.Lock(context.TODO(), ""), io.EOF)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i, _, _, _, _ := newImplementation(t)
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
@@ -0,0 +1,30 @@
+package energy_measurement
+
+import (
+	"github.com/shimmeringbee/zcl"
+)
+
+/* The zcl library does not yet provide the Electrical Measurement or Metering clusters, the subset required is defined here. */
+
+const (
+	RMSVoltage          = zcl.AttributeID(0x0505)
+	RMSCurrent          = zcl.AttributeID(0x0508)
+	ActivePower         = zcl.AttributeID(0x050b)
+	ACVoltageMultiplier = zcl.AttributeID(0x0600)
+	ACVoltageDivisor    = zcl.AttributeID(0x0601)
+	ACCurrentMultiplier = zcl.AttributeID(0x0602)
+	ACCurrentDivisor    = zcl.AttributeID(0x0603)
+	ACPowerMultiplier   = zcl.AttributeID(0x0604)
+	ACPowerDivisor      = zcl.AttributeID(0x0605)
+)
+
+const (
+	CurrentSummationDelivered = zcl.AttributeID(0x0000)
+	UnitOfMeasure             = zcl.AttributeID(0x0300)
+	Multiplier                = zcl.AttributeID(0x0301)
+	Divisor                   = zcl.AttributeID(0x0302)
+)
+
+// UnitOfMeasureKilowattHours is the Metering UnitOfMeasure for electrical energy, the top bit indicates BCD formatting
+// for display purposes only and is ignored.
+const UnitOfMeasureKilowattHours = uint64(0x00)
@@ -0,0 +1,348 @@
+package energy_measurement
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"sort"
+	"time"
+)
+
+var _ extcaps.EnergyMeasurement = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const ElectricalMeasurementPresentKey = "ElectricalMeasurementPresent"
+const MeteringPresentKey = "MeteringPresent"
+
+const VoltageMultiplierKey = "VoltageMultiplier"
+const VoltageDivisorKey = "VoltageDivisor"
+const CurrentMultiplierKey = "CurrentMultiplier"
+const CurrentDivisorKey = "CurrentDivisor"
+const PowerMultiplierKey = "PowerMultiplier"
+const PowerDivisorKey = "PowerDivisor"
+const EnergyMultiplierKey = "EnergyMultiplier"
+const EnergyDivisorKey = "EnergyDivisor"
+
+const joulesPerKilowattHour = 3600000.0
+
+type measurement struct {
+	name          string
+	cluster       zigbee.ClusterID
+	id            zcl.AttributeID
+	dataType      zcl.AttributeDataType
+	multiplierKey string
+	divisorKey    string
+	// unit converts the scaled attribute value into SI units.
+	unit float64
+	// threshold is the change in SI units which devices are asked to report upon.
+	threshold float64
+	present   extcaps.EnergyMeasurementPresent
+}
+
+var measurements = []measurement{
+	{name: "ActivePower", cluster: zcl.ElectricalMeasurementId, id: ActivePower, dataType: zcl.TypeSignedInt16, multiplierKey: PowerMultiplierKey, divisorKey: PowerDivisorKey, unit: 1, threshold: 1, present: extcaps.ActivePowerPresent},
+	{name: "RMSVoltage", cluster: zcl.ElectricalMeasurementId, id: RMSVoltage, dataType: zcl.TypeUnsignedInt16, multiplierKey: VoltageMultiplierKey, divisorKey: VoltageDivisorKey, unit: 1, threshold: 1, present: extcaps.VoltagePresent},
+	{name: "RMSCurrent", cluster: zcl.ElectricalMeasurementId, id: RMSCurrent, dataType: zcl.TypeUnsignedInt16, multiplierKey: CurrentMultiplierKey, divisorKey: CurrentDivisorKey, unit: 1, threshold: 0.01, present: extcaps.CurrentPresent},
+	{name: "CurrentSummationDelivered", cluster: zcl.MeteringId, id: CurrentSummationDelivered, dataType: zcl.TypeUnsignedInt48, multiplierKey: EnergyMultiplierKey, divisorKey: EnergyDivisorKey, unit: joulesPerKilowattHour, threshold: 0.01 * joulesPerKilowattHour, present: extcaps.EnergyPresent},
+}
+
+func NewEnergyMeasurement(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi, l: zi.Logger()}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	zi implcaps.ZDAInterface
+	l  logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+
+	am map[string]attribute.Monitor
+
+	electricalMeasurementPresent bool
+	meteringPresent              bool
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.EnergyMeasurementFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.EnergyMeasurementFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = make(map[string]attribute.Monitor)
+
+	for _, m := range measurements {
+		m := m
+
+		i.am[m.name] = i.zi.NewAttributeMonitor()
+		i.am[m.name].Init(s.Section("AttributeMonitor", m.name), d, func(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+			i.update(m, v)
+		})
+	}
+}
+
+// active returns the measurements which are provided by the clusters present on the device.
+func (i *Implementation) active() []measurement {
+	var active []measurement
+
+	for _, m := range measurements {
+		if (m.cluster == zcl.ElectricalMeasurementId && i.electricalMeasurementPresent) || (m.cluster == zcl.MeteringId && i.meteringPresent) {
+			active = append(active, m)
+		}
+	}
+
+	return active
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("energy measurement missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.electricalMeasurementPresent, _ = i.s.Bool(ElectricalMeasurementPresentKey)
+	i.meteringPresent, _ = i.s.Bool(MeteringPresentKey)
+
+	for _, m := range i.active() {
+		if err := i.am[m.name].Load(ctx); err != nil {
+			return false, fmt.Errorf("%s monitor, load failed: %w", m.name, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	var lastError error
+
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.electricalMeasurementPresent = implcaps.Get(m, "ZigbeeElectricalMeasurementClusterPresent", false)
+	i.meteringPresent = implcaps.Get(m, "ZigbeeMeteringClusterPresent", false)
+
+	if i.electricalMeasurementPresent {
+		i.enumerateScaling(ctx, zcl.ElectricalMeasurementId, map[zcl.AttributeID]string{
+			ACVoltageMultiplier: VoltageMultiplierKey,
+			ACVoltageDivisor:    VoltageDivisorKey,
+			ACCurrentMultiplier: CurrentMultiplierKey,
+			ACCurrentDivisor:    CurrentDivisorKey,
+			ACPowerMultiplier:   PowerMultiplierKey,
+			ACPowerDivisor:      PowerDivisorKey,
+		})
+	}
+
+	if i.meteringPresent {
+		if unit := i.enumerateScaling(ctx, zcl.MeteringId, map[zcl.AttributeID]string{
+			Multiplier: EnergyMultiplierKey,
+			Divisor:    EnergyDivisorKey,
+		}); unit&0x7f != UnitOfMeasureKilowattHours {
+			i.l.Info(ctx, "Metering cluster does not measure electrical energy, ignoring.", logwrap.Datum("UnitOfMeasure", unit))
+			i.meteringPresent = false
+		}
+	}
+
+	i.s.Set(ElectricalMeasurementPresentKey, i.electricalMeasurementPresent)
+	i.s.Set(MeteringPresentKey, i.meteringPresent)
+
+	polling := attribute.PollingConfig{Mode: attribute.PollIfReportingFailed, Interval: 1 * time.Minute}
+
+	attached := false
+
+	for _, ms := range i.active() {
+		reporting := attribute.ReportingConfig{
+			Mode:             attribute.AttemptConfigureReporting,
+			MinimumInterval:  10 * time.Second,
+			MaximumInterval:  5 * time.Minute,
+			ReportableChange: i.reportableChange(ms),
+		}
+
+		if err := i.am[ms.name].Attach(ctx, i.remoteEndpoint, ms.cluster, ms.id, ms.dataType, reporting, polling); err != nil {
+			lastError = err
+			i.l.Warn(ctx, "Errored attaching energy measurement attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", ms.name))
+		} else {
+			attached = true
+		}
+	}
+
+	return attached, lastError
+}
+
+// enumerateScaling reads the multiplier and divisor attributes of a cluster, storing them against the keys provided. If
+// the Metering cluster is being read its UnitOfMeasure is also read and returned, missing values are defaulted.
+func (i *Implementation) enumerateScaling(ctx context.Context, cluster zigbee.ClusterID, keys map[zcl.AttributeID]string) uint64 {
+	var unit uint64
+
+	attributes := []zcl.AttributeID{}
+	for id, key := range keys {
+		attributes = append(attributes, id)
+		i.s.Set(key, uint64(1))
+	}
+
+	if cluster == zcl.MeteringId {
+		attributes = append(attributes, UnitOfMeasure)
+	}
+
+	sort.Slice(attributes, func(a, b int) bool { return attributes[a] < attributes[b] })
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, cluster, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, attributes)
+	if err != nil {
+		i.l.Warn(ctx, "Failed to read energy measurement scaling attributes, assuming unscaled.", logwrap.Err(err), logwrap.Datum("Cluster", cluster))
+		return unit
+	}
+
+	for id, rec := range communicator.ReadResponsesToMap(recs) {
+		if rec.Status != 0 || rec.DataTypeValue == nil {
+			continue
+		}
+
+		switch id {
+		case UnitOfMeasure:
+			if v, ok := rec.DataTypeValue.Value.(uint8); ok {
+				unit = uint64(v)
+			}
+		default:
+			if v, ok := rec.DataTypeValue.Value.(uint64); ok && v != 0 {
+				if key, found := keys[id]; found {
+					i.s.Set(key, v)
+				}
+			}
+		}
+	}
+
+	return unit
+}
+
+// scale returns the factor which converts a raw attribute value into SI units.
+func (i *Implementation) scale(m measurement) float64 {
+	multiplier, _ := i.s.UInt(m.multiplierKey)
+	divisor, _ := i.s.UInt(m.divisorKey)
+
+	if multiplier == 0 {
+		multiplier = 1
+	}
+
+	if divisor == 0 {
+		divisor = 1
+	}
+
+	return float64(multiplier) / float64(divisor) * m.unit
+}
+
+// reportableChange converts the SI threshold of a measurement into the raw attribute value, using the devices scaling.
+func (i *Implementation) reportableChange(m measurement) any {
+	change := math.Max(1, math.Round(m.threshold/i.scale(m)))
+
+	if m.dataType == zcl.TypeSignedInt16 {
+		return int(change)
+	}
+
+	return uint(change)
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	var lastError error
+
+	for _, m := range i.active() {
+		if err := i.am[m.name].Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+			lastError = err
+			i.l.Warn(ctx, "Failed to detach energy measurement attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", m.name))
+		}
+	}
+
+	return lastError
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLEnergyMeasurement"
+}
+
+func (i *Implementation) update(m measurement, v zcl.AttributeDataTypeValue) {
+	if v.DataType != m.dataType {
+		return
+	}
+
+	var raw float64
+
+	switch value := v.Value.(type) {
+	case uint64:
+		/* 0xffff indicates an invalid RMS measurement. */
+		if m.dataType == zcl.TypeUnsignedInt16 && value == 0xffff {
+			return
+		}
+
+		raw = float64(value)
+	case int64:
+		/* 0x8000 indicates an invalid active power measurement. */
+		if value == math.MinInt16 {
+			return
+		}
+
+		raw = float64(value)
+	default:
+		return
+	}
+
+	newValue := raw * i.scale(m)
+
+	if current, found := i.s.Float(m.name); !found || current != newValue {
+		i.s.Set(m.name, newValue)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		i.zi.SendEvent(extcaps.EnergyMeasurementUpdate{Device: i.d, State: i.state()})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) state() extcaps.EnergyMeasurementState {
+	var state extcaps.EnergyMeasurementState
+
+	for _, m := range i.active() {
+		state.Present |= m.present
+	}
+
+	state.ActivePower, _ = i.s.Float("ActivePower")
+	state.Voltage, _ = i.s.Float("RMSVoltage")
+	state.Current, _ = i.s.Float("RMSCurrent")
+	state.Energy, _ = i.s.Float("CurrentSummationDelivered")
+
+	return state
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Reading(_ context.Context) (extcaps.EnergyMeasurementState, error) {
+	return i.state(), nil
+}
@@ -0,0 +1,271 @@
+package energy_measurement
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator, map[string]*attribute.MockMonitor) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewEnergyMeasurement(mzi)
+	i.s = memory.New()
+	i.am = make(map[string]attribute.Monitor)
+
+	mms := make(map[string]*attribute.MockMonitor)
+
+	for _, m := range measurements {
+		mm := &attribute.MockMonitor{}
+		t.Cleanup(func() { mm.AssertExpectations(t) })
+
+		mms[m.name] = mm
+		i.am[m.name] = mm
+	}
+
+	return i, mzi, mzc, mms
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewEnergyMeasurement(newMockZDAInterface(t))
+
+		assert.Equal(t, capabilities.EnergyMeasurementFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.EnergyMeasurementFlag], i.Name())
+		assert.Equal(t, "ZCLEnergyMeasurement", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs attribute monitors correctly initialising them", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm).Times(len(measurements))
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		mm.On("Init", mock.Anything, md, mock.Anything).Times(len(measurements))
+
+		i := NewEnergyMeasurement(mzi)
+		i.Init(md, memory.New())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads only the monitors for present clusters", func(t *testing.T) {
+		i, _, _, mms := newImplementation(t)
+		mms["CurrentSummationDelivered"].On("Load", mock.Anything).Return(nil)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+		i.s.Set(MeteringPresentKey, true)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails if a monitor fails to load", func(t *testing.T) {
+		i, _, _, mms := newImplementation(t)
+		mms["ActivePower"].On("Load", mock.Anything).Return(io.EOF)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+		i.s.Set(ElectricalMeasurementPresentKey, true)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads and stores scaling, attaching monitors with scaled reportable changes", func(t *testing.T) {
+		i, _, mzc, mms := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.ElectricalMeasurementId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{ACVoltageMultiplier, ACVoltageDivisor, ACCurrentMultiplier, ACCurrentDivisor, ACPowerMultiplier, ACPowerDivisor}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: ACVoltageMultiplier, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(1)}},
+			{Identifier: ACVoltageDivisor, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(10)}},
+			{Identifier: ACCurrentMultiplier, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(1)}},
+			{Identifier: ACCurrentDivisor, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(1000)}},
+			{Identifier: ACPowerMultiplier, Status: 0x86},
+			{Identifier: ACPowerDivisor, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(0)}},
+		}, nil)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.MeteringId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{UnitOfMeasure, Multiplier, Divisor}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: UnitOfMeasure, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0x80)}},
+			{Identifier: Multiplier, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt24, Value: uint64(1)}},
+			{Identifier: Divisor, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt24, Value: uint64(1000)}},
+		}, nil)
+
+		expectReportableChange := func(change any) any {
+			return mock.MatchedBy(func(rc attribute.ReportingConfig) bool {
+				return rc.ReportableChange == change
+			})
+		}
+
+		mms["ActivePower"].On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.ElectricalMeasurementId, ActivePower, zcl.TypeSignedInt16, expectReportableChange(1), mock.Anything).Return(nil)
+		mms["RMSVoltage"].On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.ElectricalMeasurementId, RMSVoltage, zcl.TypeUnsignedInt16, expectReportableChange(uint(10)), mock.Anything).Return(nil)
+		mms["RMSCurrent"].On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.ElectricalMeasurementId, RMSCurrent, zcl.TypeUnsignedInt16, expectReportableChange(uint(10)), mock.Anything).Return(nil)
+		mms["CurrentSummationDelivered"].On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.MeteringId, CurrentSummationDelivered, zcl.TypeUnsignedInt48, expectReportableChange(uint(10)), mock.Anything).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4), "ZigbeeElectricalMeasurementClusterPresent": true, "ZigbeeMeteringClusterPresent": true})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		v, _ := i.s.UInt(VoltageDivisorKey)
+		assert.Equal(t, uint64(10), v)
+
+		v, _ = i.s.UInt(PowerMultiplierKey)
+		assert.Equal(t, uint64(1), v)
+
+		v, _ = i.s.UInt(PowerDivisorKey)
+		assert.Equal(t, uint64(1), v)
+
+		present, _ := i.s.Bool(MeteringPresentKey)
+		assert.True(t, present)
+	})
+
+	t.Run("ignores metering clusters which do not measure electrical energy", func(t *testing.T) {
+		/* 0x01 is cubic meters, as reported by gas and water meters. */
+		i, _, mzc, _ := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, zcl.MeteringId, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: UnitOfMeasure, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0x01)}},
+		}, nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeMeteringClusterPresent": true})
+
+		assert.False(t, attached)
+		assert.NoError(t, err)
+
+		present, _ := i.s.Bool(MeteringPresentKey)
+		assert.False(t, present)
+	})
+
+	t.Run("assumes unscaled values if scaling can not be read", func(t *testing.T) {
+		i, _, mzc, mms := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, zcl.ElectricalMeasurementId, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+
+		mms["ActivePower"].On("Attach", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mms["RMSVoltage"].On("Attach", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mms["RMSCurrent"].On("Attach", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeElectricalMeasurementClusterPresent": true})
+
+		assert.True(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+
+		v, _ := i.s.UInt(CurrentDivisorKey)
+		assert.Equal(t, uint64(1), v)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detaches the monitors in use", func(t *testing.T) {
+		i, _, _, mms := newImplementation(t)
+		i.meteringPresent = true
+
+		mms["CurrentSummationDelivered"].On("Detach", mock.Anything, true).Return(nil)
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("scales readings into SI units, sending an event on change", func(t *testing.T) {
+		i, mzi, _, _ := newImplementation(t)
+		i.electricalMeasurementPresent = true
+		i.meteringPresent = true
+
+		i.s.Set(VoltageMultiplierKey, uint64(1))
+		i.s.Set(VoltageDivisorKey, uint64(10))
+		i.s.Set(EnergyMultiplierKey, uint64(1))
+		i.s.Set(EnergyDivisorKey, uint64(1000))
+
+		mzi.On("SendEvent", mock.Anything).Twice()
+
+		i.update(measurements[1], zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(2301)})
+		i.update(measurements[1], zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(2301)})
+		i.update(measurements[3], zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt48, Value: uint64(1500)})
+
+		state, err := i.Reading(context.TODO())
+		assert.NoError(t, err)
+		assert.InDelta(t, 230.1, state.Voltage, 0.001)
+		assert.InDelta(t, 1.5*joulesPerKilowattHour, state.Energy, 0.001)
+		assert.Equal(t, extcaps.ActivePowerPresent|extcaps.VoltagePresent|extcaps.CurrentPresent|extcaps.EnergyPresent, state.Present)
+	})
+
+	t.Run("handles negative active power and ignores invalid values", func(t *testing.T) {
+		i, mzi, _, _ := newImplementation(t)
+		i.electricalMeasurementPresent = true
+
+		mzi.On("SendEvent", mock.Anything).Once()
+
+		i.update(measurements[0], zcl.AttributeDataTypeValue{DataType: zcl.TypeSignedInt16, Value: int64(-15)})
+		i.update(measurements[0], zcl.AttributeDataTypeValue{DataType: zcl.TypeSignedInt16, Value: int64(-0x8000)})
+		i.update(measurements[2], zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(0xffff)})
+
+		state, _ := i.Reading(context.TODO())
+		assert.Equal(t, -15.0, state.ActivePower)
+		assert.Equal(t, 0.0, state.Current)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
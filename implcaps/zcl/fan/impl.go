@@ -0,0 +1,221 @@
+package fan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"slices"
+	"time"
+)
+
+var _ extcaps.Fan = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	FanMode         = zcl.AttributeID(0x0000)
+	FanModeSequence = zcl.AttributeID(0x0001)
+)
+
+const (
+	FanModeKey         = "FanMode"
+	FanModeSequenceKey = "FanModeSequence"
+)
+
+// DefaultFanModeSequence is the default value of FanModeSequence as per ZCL 6.4.2.2.2, Low/Med/High/Auto.
+const DefaultFanModeSequence = uint64(0x02)
+
+var ErrFanModeNotSupported = errors.New("fan does not support mode")
+
+// fanModeSequences maps each FanModeSequence to the modes it permits, Off is always permitted.
+var fanModeSequences = map[uint64][]extcaps.FanMode{
+	0x00: {extcaps.FanOff, extcaps.FanLow, extcaps.FanMedium, extcaps.FanHigh},
+	0x01: {extcaps.FanOff, extcaps.FanLow, extcaps.FanHigh},
+	0x02: {extcaps.FanOff, extcaps.FanLow, extcaps.FanMedium, extcaps.FanHigh, extcaps.FanAuto},
+	0x03: {extcaps.FanOff, extcaps.FanLow, extcaps.FanHigh, extcaps.FanAuto},
+	0x04: {extcaps.FanOff, extcaps.FanOn, extcaps.FanAuto},
+}
+
+func NewFan(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi, l: zi.Logger()}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	am attribute.Monitor
+	zi implcaps.ZDAInterface
+	l  logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.FanFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.FanFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = i.zi.NewAttributeMonitor()
+	i.am.Init(s.Section("AttributeMonitor", "FanMode"), d, i.update)
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("fan missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	if err := i.am.Load(ctx); err != nil {
+		return false, err
+	} else {
+		return true, nil
+	}
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	sequence := DefaultFanModeSequence
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	if recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.FanControlId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{FanModeSequence}); err != nil {
+		i.l.Warn(ctx, "Failed to read fan mode sequence, assuming default.", logwrap.Err(err))
+	} else if rec, found := communicator.ReadResponsesToMap(recs)[FanModeSequence]; found && rec.Status == 0 && rec.DataTypeValue != nil {
+		if v, ok := rec.DataTypeValue.Value.(uint8); ok {
+			if _, known := fanModeSequences[uint64(v)]; known {
+				sequence = uint64(v)
+			}
+		}
+	}
+
+	i.s.Set(FanModeSequenceKey, sequence)
+
+	reporting := attribute.ReportingConfig{
+		Mode:            attribute.AttemptConfigureReporting,
+		MinimumInterval: 1 * time.Second,
+		MaximumInterval: 5 * time.Minute,
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	if err := i.am.Attach(ctx, i.remoteEndpoint, zcl.FanControlId, FanMode, zcl.TypeEnum8, reporting, polling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	if err := i.am.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLFan"
+}
+
+func (i *Implementation) update(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType == zcl.TypeEnum8 {
+		if value, ok := v.Value.(uint8); ok {
+			i.storeMode(uint64(value))
+		}
+	}
+}
+
+func (i *Implementation) storeMode(mode uint64) {
+	if current, found := i.s.UInt(FanModeKey); !found || current != mode {
+		i.s.Set(FanModeKey, mode)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		i.zi.SendEvent(extcaps.FanUpdate{Device: i.d, State: i.state()})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) supportedModes() []extcaps.FanMode {
+	sequence, found := i.s.UInt(FanModeSequenceKey)
+	if !found {
+		sequence = DefaultFanModeSequence
+	}
+
+	return fanModeSequences[sequence]
+}
+
+func (i *Implementation) state() extcaps.FanState {
+	mode, _ := i.s.UInt(FanModeKey)
+
+	return extcaps.FanState{
+		Mode:           extcaps.FanMode(mode),
+		SupportedModes: i.supportedModes(),
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (extcaps.FanState, error) {
+	return i.state(), nil
+}
+
+// SetMode writes the FanMode attribute, the mode must be one permitted by the fans FanModeSequence.
+func (i *Implementation) SetMode(ctx context.Context, mode extcaps.FanMode) error {
+	if !slices.Contains(i.supportedModes(), mode) {
+		return fmt.Errorf("%w: %s", ErrFanModeNotSupported, mode)
+	}
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	recs, err := i.zi.ZCLCommunicator().WriteAttributes(ctx, ieee, ack, zcl.FanControlId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+		FanMode: {
+			DataType: zcl.TypeEnum8,
+			Value:    uint64(mode),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if rec, found := communicator.WriteResponsesToMap(recs)[FanMode]; found && rec.Status != 0 {
+		return fmt.Errorf("failed to write fan mode: status %d", rec.Status)
+	}
+
+	i.storeMode(uint64(mode))
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package fan
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator, *attribute.MockMonitor) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	mm := &attribute.MockMonitor{}
+	t.Cleanup(func() { mm.AssertExpectations(t) })
+
+	i := NewFan(mzi)
+	i.s = memory.New()
+	i.am = mm
+
+	return i, mzi, mzc, mm
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewFan(newMockZDAInterface(t))
+
+		assert.Equal(t, extcaps.FanFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.FanFlag], i.Name())
+		assert.Equal(t, "ZCLFan", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs a new attribute monitor correctly initialising it", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		mm.On("Init", mock.Anything, md, mock.Anything)
+
+		i := NewFan(mzi)
+		i.Init(md, memory.New())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads attribute monitor functionality, returning true if successful", func(t *testing.T) {
+		i, _, _, mm := newImplementation(t)
+		mm.On("Load", mock.Anything).Return(nil)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 4)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(4), i.remoteEndpoint)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads the fan mode sequence and attaches the monitor", func(t *testing.T) {
+		i, _, mzc, mm := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.FanControlId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{FanModeSequence}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: FanModeSequence, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0x04)}},
+		}, nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.FanControlId, FanMode, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, []extcaps.FanMode{extcaps.FanOff, extcaps.FanOn, extcaps.FanAuto}, state.SupportedModes)
+	})
+
+	t.Run("assumes the default sequence if it can not be read, failing if the monitor fails to attach", func(t *testing.T) {
+		i, _, mzc, mm := newImplementation(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(1), zcl.FanControlId, FanMode, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+
+		sequence, _ := i.s.UInt(FanModeSequenceKey)
+		assert.Equal(t, DefaultFanModeSequence, sequence)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detached attribute monitor", func(t *testing.T) {
+		i, _, _, mm := newImplementation(t)
+		mm.On("Detach", mock.Anything, true).Return(nil)
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates fan mode, sending an event on change", func(t *testing.T) {
+		i, mzi, _, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.FanUpdate{State: extcaps.FanState{Mode: extcaps.FanHigh, SupportedModes: fanModeSequences[DefaultFanModeSequence]}}).Once()
+
+		i.update(FanMode, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0x03)})
+		i.update(FanMode, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0x03)})
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.NotZero(t, lct)
+	})
+}
+
+func TestImplementation_SetMode(t *testing.T) {
+	t.Run("writes the fan mode if supported", func(t *testing.T) {
+		i, mzi, mzc, _ := newImplementation(t)
+		i.remoteEndpoint = 4
+
+		mzc.On("WriteAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.FanControlId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			FanMode: {DataType: zcl.TypeEnum8, Value: uint64(extcaps.FanLow)},
+		}).Return([]global.WriteAttributesResponseRecord{{Identifier: FanMode, Status: 0}}, nil)
+		mzi.On("SendEvent", mock.Anything)
+
+		err := i.SetMode(context.TODO(), extcaps.FanLow)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.FanLow, state.Mode)
+	})
+
+	t.Run("fails without writing if the mode is not supported", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+		i.s.Set(FanModeSequenceKey, uint64(0x01))
+
+		err := i.SetMode(context.TODO(), extcaps.FanMedium)
+		assert.ErrorIs(t, err, ErrFanModeNotSupported)
+	})
+
+	t.Run("fails if the device rejects the write", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+
+		mzc.On("WriteAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.WriteAttributesResponseRecord{{Identifier: FanMode, Status: 0x87}}, nil)
+
+		err := i.SetMode(context.TODO(), extcaps.FanAuto)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
@@ -0,0 +1,65 @@
+package groups
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the Groups cluster, the subset required is defined here. */
+
+const (
+	AddGroupId           = zcl.CommandIdentifier(0x00)
+	GetGroupMembershipId = zcl.CommandIdentifier(0x02)
+	RemoveGroupId        = zcl.CommandIdentifier(0x03)
+)
+
+const (
+	AddGroupResponseId           = zcl.CommandIdentifier(0x00)
+	GetGroupMembershipResponseId = zcl.CommandIdentifier(0x02)
+	RemoveGroupResponseId        = zcl.CommandIdentifier(0x03)
+)
+
+const (
+	StatusSuccess           = uint8(0x00)
+	StatusInsufficientSpace = uint8(0x89)
+	StatusDuplicateExists   = uint8(0x8a)
+	StatusNotFound          = uint8(0x8b)
+)
+
+type AddGroup struct {
+	GroupID   uint16
+	GroupName string
+}
+
+type AddGroupResponse struct {
+	Status  uint8
+	GroupID uint16
+}
+
+type GetGroupMembership struct {
+	GroupList []uint16 `bcsliceprefix:"8"`
+}
+
+type GetGroupMembershipResponse struct {
+	Capacity  uint8
+	GroupList []uint16 `bcsliceprefix:"8"`
+}
+
+type RemoveGroup struct {
+	GroupID uint16
+}
+
+type RemoveGroupResponse struct {
+	Status  uint8
+	GroupID uint16
+}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.GroupsId, zigbee.NoManufacturer, zcl.ClientToServer, AddGroupId, &AddGroup{})
+	cr.RegisterLocal(zcl.GroupsId, zigbee.NoManufacturer, zcl.ClientToServer, GetGroupMembershipId, &GetGroupMembership{})
+	cr.RegisterLocal(zcl.GroupsId, zigbee.NoManufacturer, zcl.ClientToServer, RemoveGroupId, &RemoveGroup{})
+
+	cr.RegisterLocal(zcl.GroupsId, zigbee.NoManufacturer, zcl.ServerToClient, AddGroupResponseId, &AddGroupResponse{})
+	cr.RegisterLocal(zcl.GroupsId, zigbee.NoManufacturer, zcl.ServerToClient, GetGroupMembershipResponseId, &GetGroupMembershipResponse{})
+	cr.RegisterLocal(zcl.GroupsId, zigbee.NoManufacturer, zcl.ServerToClient, RemoveGroupResponseId, &RemoveGroupResponse{})
+}
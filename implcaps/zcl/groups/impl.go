@@ -0,0 +1,212 @@
+package groups
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ extcaps.Groups = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const MembershipKey = "Membership"
+
+func NewGroups(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), membershipMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+
+	membershipMutex *sync.Mutex
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.GroupsFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.GroupsFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("groups missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	return true, nil
+}
+
+// Enumerate reads the devices existing group memberships, which may have been configured before it joined.
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	resp, err := i.request(ctx, GetGroupMembershipId, &GetGroupMembership{})
+	if err != nil {
+		i.logger.Warn(ctx, "Failed to read group memberships of device.", logwrap.Err(err))
+		return true, nil
+	}
+
+	if cmd, ok := resp.Command.(*GetGroupMembershipResponse); ok {
+		i.membershipMutex.Lock()
+		i.setMemberships(cmd.GroupList)
+		i.membershipMutex.Unlock()
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLGroups"
+}
+
+func (i *Implementation) request(ctx context.Context, id zcl.CommandIdentifier, cmd any) (zcl.Message, error) {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().RequestResponse(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.GroupsId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+}
+
+// AddGroup adds the device to the group, a device which is already a member is treated as success.
+func (i *Implementation) AddGroup(ctx context.Context, group uint16) error {
+	resp, err := i.request(ctx, AddGroupId, &AddGroup{GroupID: group})
+	if err != nil {
+		return err
+	}
+
+	cmd, ok := resp.Command.(*AddGroupResponse)
+	if !ok {
+		return fmt.Errorf("unexpected response to add group: %T", resp.Command)
+	}
+
+	if cmd.Status != StatusSuccess && cmd.Status != StatusDuplicateExists {
+		return fmt.Errorf("failed to add group %04x: status %02x", group, cmd.Status)
+	}
+
+	i.membershipMutex.Lock()
+	defer i.membershipMutex.Unlock()
+
+	if groups := i.memberships(); !slices.Contains(groups, group) {
+		i.setMemberships(append(groups, group))
+	}
+
+	return nil
+}
+
+// RemoveGroup removes the device from the group, a device which is not a member is treated as success.
+func (i *Implementation) RemoveGroup(ctx context.Context, group uint16) error {
+	resp, err := i.request(ctx, RemoveGroupId, &RemoveGroup{GroupID: group})
+	if err != nil {
+		return err
+	}
+
+	cmd, ok := resp.Command.(*RemoveGroupResponse)
+	if !ok {
+		return fmt.Errorf("unexpected response to remove group: %T", resp.Command)
+	}
+
+	if cmd.Status != StatusSuccess && cmd.Status != StatusNotFound {
+		return fmt.Errorf("failed to remove group %04x: status %02x", group, cmd.Status)
+	}
+
+	i.membershipMutex.Lock()
+	defer i.membershipMutex.Unlock()
+
+	if groups := i.memberships(); slices.Contains(groups, group) {
+		i.setMemberships(slices.DeleteFunc(groups, func(g uint16) bool { return g == group }))
+	}
+
+	return nil
+}
+
+func (i *Implementation) Memberships(_ context.Context) ([]uint16, error) {
+	i.membershipMutex.Lock()
+	defer i.membershipMutex.Unlock()
+
+	return i.memberships(), nil
+}
+
+// RemoteEndpoint returns the endpoint whose group memberships are managed, used to unicast group commands to the
+// device if the zigbee provider can not multicast.
+func (i *Implementation) RemoteEndpoint() zigbee.Endpoint {
+	return i.remoteEndpoint
+}
+
+func (i *Implementation) memberships() []uint16 {
+	var groups []uint16
+
+	for _, k := range i.s.Section(MembershipKey).Keys() {
+		if g, err := strconv.ParseUint(k, 10, 16); err == nil {
+			groups = append(groups, uint16(g))
+		}
+	}
+
+	slices.Sort(groups)
+	return groups
+}
+
+func (i *Implementation) setMemberships(groups []uint16) {
+	groups = slices.Clone(groups)
+	slices.Sort(groups)
+	groups = slices.Compact(groups)
+
+	if slices.Equal(groups, i.memberships()) {
+		return
+	}
+
+	i.s.SectionDelete(MembershipKey)
+	s := i.s.Section(MembershipKey)
+
+	for _, g := range groups {
+		s.Set(strconv.Itoa(int(g)), true)
+	}
+
+	converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+	i.zi.SendEvent(extcaps.GroupsUpdate{Device: i.d, Groups: groups})
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
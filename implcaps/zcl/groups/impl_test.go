@@ -0,0 +1,188 @@
+package groups
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewGroups(mzi)
+	i.Init(nil, memory.New())
+	i.remoteEndpoint = 4
+
+	return i, mzi, mzc
+}
+
+func request(id zcl.CommandIdentifier, cmd any) zcl.Message {
+	return zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: 3,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.GroupsId,
+		SourceEndpoint:      2,
+		DestinationEndpoint: 4,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	}
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewGroups(newMockZDAInterface(t))
+
+		assert.Equal(t, extcaps.GroupsFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.GroupsFlag], i.Name())
+		assert.Equal(t, "ZCLGroups", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads the remote endpoint, returning true if successful", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.RemoteEndpoint())
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads the devices existing group memberships", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		mzi.On("SendEvent", extcaps.GroupsUpdate{Groups: []uint16{1, 7}})
+
+		req := request(GetGroupMembershipId, &GetGroupMembership{})
+		req.DestinationEndpoint = 5
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, req).Return(zcl.Message{Command: &GetGroupMembershipResponse{GroupList: []uint16{7, 1}}}, nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		groups, _ := i.Memberships(context.TODO())
+		assert.Equal(t, []uint16{1, 7}, groups)
+	})
+
+	t.Run("failure to read memberships is not fatal", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{}, io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_AddGroup(t *testing.T) {
+	t.Run("adds the group and records the membership", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		mzi.On("SendEvent", extcaps.GroupsUpdate{Groups: []uint16{0x1234}})
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, request(AddGroupId, &AddGroup{GroupID: 0x1234})).Return(zcl.Message{Command: &AddGroupResponse{Status: StatusSuccess, GroupID: 0x1234}}, nil)
+
+		err := i.AddGroup(context.TODO(), 0x1234)
+		assert.NoError(t, err)
+
+		groups, _ := i.Memberships(context.TODO())
+		assert.Equal(t, []uint16{0x1234}, groups)
+
+		changed, _ := i.LastChangeTime(context.TODO())
+		assert.False(t, changed.IsZero())
+	})
+
+	t.Run("treats an existing membership as success", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		mzi.On("SendEvent", extcaps.GroupsUpdate{Groups: []uint16{0x1234}})
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{Command: &AddGroupResponse{Status: StatusDuplicateExists, GroupID: 0x1234}}, nil)
+
+		err := i.AddGroup(context.TODO(), 0x1234)
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors if the device has no space for the group", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{Command: &AddGroupResponse{Status: StatusInsufficientSpace, GroupID: 0x1234}}, nil)
+
+		err := i.AddGroup(context.TODO(), 0x1234)
+		assert.Error(t, err)
+
+		groups, _ := i.Memberships(context.TODO())
+		assert.Empty(t, groups)
+	})
+}
+
+func TestImplementation_RemoveGroup(t *testing.T) {
+	t.Run("removes the group and the membership", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		mzi.On("SendEvent", extcaps.GroupsUpdate{Groups: []uint16{1, 2}}).Once()
+		mzi.On("SendEvent", extcaps.GroupsUpdate{Groups: []uint16{2}}).Once()
+
+		i.setMemberships([]uint16{1, 2})
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, request(RemoveGroupId, &RemoveGroup{GroupID: 1})).Return(zcl.Message{Command: &RemoveGroupResponse{Status: StatusSuccess, GroupID: 1}}, nil)
+
+		err := i.RemoveGroup(context.TODO(), 1)
+		assert.NoError(t, err)
+
+		groups, _ := i.Memberships(context.TODO())
+		assert.Equal(t, []uint16{2}, groups)
+	})
+
+	t.Run("treats a missing membership as success", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{Command: &RemoveGroupResponse{Status: StatusNotFound, GroupID: 1}}, nil)
+
+		err := i.RemoveGroup(context.TODO(), 1)
+		assert.NoError(t, err)
+	})
+}
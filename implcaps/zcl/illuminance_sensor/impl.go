@@ -0,0 +1,148 @@
+package illuminance_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ capabilities.IlluminationSensor = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	MeasuredValue = zcl.AttributeID(0x0000)
+)
+
+const (
+	// measuredValueTooLow indicates the illuminance is too low to be measured, it is treated as 0 lux.
+	measuredValueTooLow = uint64(0x0000)
+	// measuredValueInvalid indicates the measurement is invalid, it is ignored.
+	measuredValueInvalid = uint64(0xffff)
+)
+
+func NewIlluminanceSensor(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	am attribute.Monitor
+	zi implcaps.ZDAInterface
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.IlluminationSensorFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.IlluminationSensorFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = i.zi.NewAttributeMonitor()
+	i.am.Init(s.Section("AttributeMonitor", "IlluminanceReading"), d, i.update)
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if err := i.am.Load(ctx); err != nil {
+		return false, err
+	} else {
+		return true, nil
+	}
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	endpoint := implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	/* MeasuredValue is logarithmic, a change of 414 represents a change of 10% in lux regardless of brightness. */
+	reporting := attribute.ReportingConfig{
+		Mode:             attribute.AttemptConfigureReporting,
+		MinimumInterval:  1 * time.Minute,
+		MaximumInterval:  5 * time.Minute,
+		ReportableChange: uint(414),
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	if err := i.am.Attach(ctx, endpoint, zcl.IlluminanceMeasurementId, MeasuredValue, zcl.TypeUnsignedInt16, reporting, polling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	if err := i.am.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLIlluminanceSensor"
+}
+
+func (i *Implementation) update(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType == zcl.TypeUnsignedInt16 {
+		if value, ok := v.Value.(uint64); ok {
+			if value == measuredValueInvalid {
+				return
+			}
+
+			newLux := 0.0
+
+			if value != measuredValueTooLow {
+				newLux = math.Pow(10, (float64(value)-1)/10000.0)
+			}
+
+			currentLux, _ := i.s.Float(implcaps.ReadingKey)
+
+			if math.Abs(newLux-currentLux) > currentLux*0.01 {
+				i.s.Set(implcaps.ReadingKey, newLux)
+				converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+				i.zi.SendEvent(capabilities.IlluminationSensorUpdate{Device: i.d, State: []capabilities.IlluminationReading{{Value: newLux}}})
+			}
+
+			converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+		}
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Reading(_ context.Context) ([]capabilities.IlluminationReading, error) {
+	lux, _ := i.s.Float(implcaps.ReadingKey)
+
+	return []capabilities.IlluminationReading{
+		{
+			Value: lux,
+		},
+	}, nil
+}
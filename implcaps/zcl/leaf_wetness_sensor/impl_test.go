@@ -0,0 +1,98 @@
+package leaf_wetness_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches to the attribute monitor, reporting changes of 1% as an unsigned integer", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(0x01), zcl.LeafWetnessId, implcaps.MeasuredValue, zcl.TypeUnsignedInt16, mock.MatchedBy(func(rc attribute.ReportingConfig) bool {
+			return rc.ReportableChange == uint(100)
+		}), mock.Anything).Return(nil)
+
+		i := NewLeafWetnessSensor(nil)
+		i.am = mm
+		attached, err := i.Enumerate(context.TODO(), make(map[string]any))
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates the state scaled from hundredths of a percent to a ratio, sending event if change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(extcaps.LeafWetnessSensorUpdate)
+			assert.True(t, ok)
+			assert.InEpsilon(t, 0.50, e.State[0].Value, 0.001)
+		})
+
+		i := NewLeafWetnessSensor(mzi)
+		i.s = memory.New()
+
+		i.s.Set(implcaps.ReadingKey, 0.51)
+
+		lastUpdated := time.Now().Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeUnsignedInt16,
+			Value:    uint64(5000),
+		})
+
+		temp, _ := i.Reading(context.TODO())
+		assert.InEpsilon(t, 0.50, temp[0].Value, 0.001)
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Greater(t, lct, lastUpdated)
+	})
+
+	t.Run("updates the state correctly, no event if no change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		i := NewLeafWetnessSensor(mzi)
+		i.s = memory.New()
+
+		i.s.Set(implcaps.ReadingKey, 0.50)
+
+		lastUpdated := time.UnixMilli(time.Now().UnixMilli()).Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeUnsignedInt16,
+			Value:    uint64(5000),
+		})
+
+		temp, _ := i.Reading(context.TODO())
+		assert.InEpsilon(t, 0.50, temp[0].Value, 0.001)
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Equal(t, lct, lastUpdated)
+	})
+}
@@ -0,0 +1,556 @@
+package light
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/da/capabilities/color"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/color_control"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ capabilities.Light = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+var _ implcaps.GroupCommandObserver = (*Implementation)(nil)
+
+const OnOffPresentKey = "OnOffPresent"
+const LevelPresentKey = "LevelPresent"
+const ColorPresentKey = "ColorPresent"
+const ColorCapabilitiesKey = "ColorCapabilities"
+const ColorTemperatureMinimumKey = "ColorTemperatureMinimumMireds"
+const ColorTemperatureMaximumKey = "ColorTemperatureMaximumMireds"
+
+const OnKey = "On"
+const LevelKey = "Level"
+const HueKey = "Hue"
+const SaturationKey = "Saturation"
+const XKey = "X"
+const YKey = "Y"
+const TemperatureKey = "Temperature"
+const ColorModeKey = "ColorMode"
+
+// ColorCapabilities is the bitmap of color features a Color Control cluster supports, as per ZCL 5.2.2.2.1.
+type ColorCapabilities uint16
+
+const (
+	HueSaturationSupported    ColorCapabilities = 0x0001
+	EnhancedHueSupported      ColorCapabilities = 0x0002
+	ColorLoopSupported        ColorCapabilities = 0x0004
+	XYSupported               ColorCapabilities = 0x0008
+	ColorTemperatureSupported ColorCapabilities = 0x0010
+)
+
+// Values of the ColorMode attribute of the Color Control cluster.
+const (
+	ZCLColorModeHueSaturation    = uint8(0x00)
+	ZCLColorModeXY               = uint8(0x01)
+	ZCLColorModeColorTemperature = uint8(0x02)
+)
+
+const (
+	maximumLevel         = 254
+	maximumHueSaturation = 254
+	maximumXY            = 0xfeff
+	defaultMinimumMireds = 153
+	defaultMaximumMireds = 500
+)
+
+func NewLight(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(onoff.Register)
+	zi.ZCLRegister(level.Register)
+	zi.ZCLRegister(color_control.Register)
+	return &Implementation{zi: zi, l: zi.Logger()}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	zi implcaps.ZDAInterface
+	l  logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+
+	onOffMonitor       attribute.Monitor
+	levelMonitor       attribute.Monitor
+	hueMonitor         attribute.Monitor
+	saturationMonitor  attribute.Monitor
+	xMonitor           attribute.Monitor
+	yMonitor           attribute.Monitor
+	temperatureMonitor attribute.Monitor
+	colorModeMonitor   attribute.Monitor
+
+	onOffPresent      bool
+	levelPresent      bool
+	colorPresent      bool
+	colorCapabilities ColorCapabilities
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.LightFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.LightFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.onOffMonitor = i.zi.NewAttributeMonitor()
+	i.onOffMonitor.Init(s.Section("AttributeMonitor", "OnOff"), d, i.updateOnOff)
+	i.levelMonitor = i.zi.NewAttributeMonitor()
+	i.levelMonitor.Init(s.Section("AttributeMonitor", "CurrentLevel"), d, i.updateLevel)
+	i.hueMonitor = i.zi.NewAttributeMonitor()
+	i.hueMonitor.Init(s.Section("AttributeMonitor", "CurrentHue"), d, i.updateColor)
+	i.saturationMonitor = i.zi.NewAttributeMonitor()
+	i.saturationMonitor.Init(s.Section("AttributeMonitor", "CurrentSaturation"), d, i.updateColor)
+	i.xMonitor = i.zi.NewAttributeMonitor()
+	i.xMonitor.Init(s.Section("AttributeMonitor", "CurrentX"), d, i.updateColor)
+	i.yMonitor = i.zi.NewAttributeMonitor()
+	i.yMonitor.Init(s.Section("AttributeMonitor", "CurrentY"), d, i.updateColor)
+	i.temperatureMonitor = i.zi.NewAttributeMonitor()
+	i.temperatureMonitor.Init(s.Section("AttributeMonitor", "ColorTemperatureMireds"), d, i.updateColor)
+	i.colorModeMonitor = i.zi.NewAttributeMonitor()
+	i.colorModeMonitor.Init(s.Section("AttributeMonitor", "ColorMode"), d, i.updateColor)
+}
+
+// monitors returns the attribute monitors that are in use given the clusters and color capabilities present.
+func (i *Implementation) monitors() map[string]attribute.Monitor {
+	m := map[string]attribute.Monitor{}
+
+	if i.onOffPresent {
+		m["OnOff"] = i.onOffMonitor
+	}
+
+	if i.levelPresent {
+		m["CurrentLevel"] = i.levelMonitor
+	}
+
+	if i.colorPresent {
+		m["ColorMode"] = i.colorModeMonitor
+
+		if i.colorCapabilities&HueSaturationSupported == HueSaturationSupported {
+			m["CurrentHue"] = i.hueMonitor
+			m["CurrentSaturation"] = i.saturationMonitor
+		}
+
+		if i.colorCapabilities&XYSupported == XYSupported {
+			m["CurrentX"] = i.xMonitor
+			m["CurrentY"] = i.yMonitor
+		}
+
+		if i.colorCapabilities&ColorTemperatureSupported == ColorTemperatureSupported {
+			m["ColorTemperatureMireds"] = i.temperatureMonitor
+		}
+	}
+
+	return m
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("monitor missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.onOffPresent, _ = i.s.Bool(OnOffPresentKey)
+	i.levelPresent, _ = i.s.Bool(LevelPresentKey)
+	i.colorPresent, _ = i.s.Bool(ColorPresentKey)
+
+	if v, ok := i.s.Int(ColorCapabilitiesKey); ok {
+		i.colorCapabilities = ColorCapabilities(v)
+	}
+
+	for name, m := range i.monitors() {
+		if err := m.Load(ctx); err != nil {
+			i.l.Warn(ctx, "Failed to load light attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", name))
+			return false, fmt.Errorf("%s monitor, load failed: %w", name, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) enumerateColorCluster(pctx context.Context) error {
+	ctx, done := context.WithTimeout(pctx, 5*time.Second)
+	defer done()
+
+	i.colorCapabilities = XYSupported
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	i.l.Info(ctx, "Reading color capabilities of light.")
+	resp, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.ColorControlId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{color_control.ColorCapabilities, color_control.ColorTempPhysicalMinMireds, color_control.ColorTempPhysicalMaxMireds})
+	if err != nil {
+		i.l.Warn(ctx, "Failed to read color capabilities, assuming XY only.", logwrap.Err(err))
+		return err
+	}
+
+	records := communicator.ReadResponsesToMap(resp)
+
+	if r, found := records[color_control.ColorCapabilities]; found && r.Status == 0 {
+		if v, ok := r.DataTypeValue.Value.(uint64); ok {
+			i.colorCapabilities = ColorCapabilities(v)
+		}
+	} else {
+		i.l.Info(ctx, "Device does not support ColorCapabilities attribute, assuming XY only.")
+	}
+
+	minMireds, maxMireds := uint64(defaultMinimumMireds), uint64(defaultMaximumMireds)
+
+	if r, found := records[color_control.ColorTempPhysicalMinMireds]; found && r.Status == 0 {
+		if v, ok := r.DataTypeValue.Value.(uint64); ok && v > 0 {
+			minMireds = v
+		}
+	}
+
+	if r, found := records[color_control.ColorTempPhysicalMaxMireds]; found && r.Status == 0 {
+		if v, ok := r.DataTypeValue.Value.(uint64); ok && v > minMireds {
+			maxMireds = v
+		}
+	}
+
+	i.s.Set(ColorTemperatureMinimumKey, int(minMireds))
+	i.s.Set(ColorTemperatureMaximumKey, int(maxMireds))
+
+	return nil
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	var lastError error
+
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.onOffPresent = implcaps.Get(m, "ZigbeeOnOffClusterPresent", false)
+	i.levelPresent = implcaps.Get(m, "ZigbeeLevelClusterPresent", false)
+	i.colorPresent = implcaps.Get(m, "ZigbeeColorClusterPresent", false)
+
+	if i.colorPresent {
+		if err := i.enumerateColorCluster(ctx); err != nil {
+			lastError = fmt.Errorf("enumerating color cluster: %w", err)
+		}
+	}
+
+	i.s.Set(OnOffPresentKey, i.onOffPresent)
+	i.s.Set(LevelPresentKey, i.levelPresent)
+	i.s.Set(ColorPresentKey, i.colorPresent)
+	i.s.Set(ColorCapabilitiesKey, int(i.colorCapabilities))
+
+	reporting := attribute.ReportingConfig{Mode: attribute.AttemptConfigureReporting, MinimumInterval: 1 * time.Second, MaximumInterval: 5 * time.Minute}
+	polling := attribute.PollingConfig{Mode: attribute.PollIfReportingFailed, Interval: 1 * time.Minute}
+
+	type attach struct {
+		cluster          zigbee.ClusterID
+		attribute        zcl.AttributeID
+		dataType         zcl.AttributeDataType
+		reportableChange any
+	}
+
+	attachments := map[string]attach{
+		"OnOff":                  {zcl.OnOffId, onoff.OnOff, zcl.TypeBoolean, nil},
+		"CurrentLevel":           {zcl.LevelControlId, level.CurrentLevel, zcl.TypeUnsignedInt8, uint(1)},
+		"ColorMode":              {zcl.ColorControlId, color_control.ColorMode, zcl.TypeEnum8, nil},
+		"CurrentHue":             {zcl.ColorControlId, color_control.CurrentHue, zcl.TypeUnsignedInt8, uint(1)},
+		"CurrentSaturation":      {zcl.ColorControlId, color_control.CurrentSaturation, zcl.TypeUnsignedInt8, uint(1)},
+		"CurrentX":               {zcl.ColorControlId, color_control.CurrentX, zcl.TypeUnsignedInt16, uint(16)},
+		"CurrentY":               {zcl.ColorControlId, color_control.CurrentY, zcl.TypeUnsignedInt16, uint(16)},
+		"ColorTemperatureMireds": {zcl.ColorControlId, color_control.ColorTemperatureMireds, zcl.TypeUnsignedInt16, uint(1)},
+	}
+
+	attached := false
+
+	for name, mon := range i.monitors() {
+		a := attachments[name]
+		reporting.ReportableChange = a.reportableChange
+
+		if err := mon.Attach(ctx, i.remoteEndpoint, a.cluster, a.attribute, a.dataType, reporting, polling); err != nil {
+			lastError = err
+			i.l.Warn(ctx, "Errored attaching light attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", name))
+		} else {
+			attached = true
+		}
+	}
+
+	return attached, lastError
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	var lastError error
+
+	for name, m := range i.monitors() {
+		if err := m.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+			lastError = err
+			i.l.Warn(ctx, "Failed to detach light attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", name))
+		}
+	}
+
+	return lastError
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLLight"
+}
+
+func (i *Implementation) updateOnOff(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if value, ok := v.Value.(bool); ok {
+		current, found := i.s.Bool(OnKey)
+		i.s.Set(OnKey, value)
+		i.announce(!found || current != value)
+	}
+}
+
+func (i *Implementation) updateLevel(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if value, ok := v.Value.(uint64); ok {
+		current, found := i.s.Int(LevelKey)
+		i.s.Set(LevelKey, int(value))
+		i.announce(!found || uint64(current) != value)
+	}
+}
+
+func (i *Implementation) updateColor(id zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	var key string
+	var value int64
+
+	switch raw := v.Value.(type) {
+	case uint64:
+		value = int64(raw)
+	case uint8:
+		value = int64(raw)
+	default:
+		return
+	}
+
+	switch id {
+	case color_control.CurrentHue:
+		key = HueKey
+	case color_control.CurrentSaturation:
+		key = SaturationKey
+	case color_control.CurrentX:
+		key = XKey
+	case color_control.CurrentY:
+		key = YKey
+	case color_control.ColorTemperatureMireds:
+		key = TemperatureKey
+	case color_control.ColorMode:
+		key = ColorModeKey
+	default:
+		return
+	}
+
+	current, found := i.s.Int(key)
+	i.s.Set(key, int(value))
+	i.announce(!found || current != value)
+}
+
+func (i *Implementation) announce(changed bool) {
+	if changed {
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+		s, _ := i.Status(context.Background())
+		i.zi.SendEvent(capabilities.LightStatusUpdate{Device: i.d, State: s})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (capabilities.LightStatus, error) {
+	state := capabilities.LightState{}
+
+	on := true
+	if i.onOffPresent {
+		on, _ = i.s.Bool(OnKey)
+	}
+
+	if on {
+		if i.levelPresent {
+			lvl, _ := i.s.Int(LevelKey)
+			state.Brightness = float64(lvl) / maximumLevel
+		} else {
+			state.Brightness = 1.0
+		}
+	}
+
+	if i.colorPresent {
+		mode, _ := i.s.Int(ColorModeKey)
+
+		switch uint8(mode) {
+		case ZCLColorModeHueSaturation:
+			state.Mode = capabilities.ColorMode
+			hue, _ := i.s.Int(HueKey)
+			sat, _ := i.s.Int(SaturationKey)
+			state.Color = color.HSVColor{Hue: float64(hue) * 360.0 / maximumHueSaturation, Sat: float64(sat) / maximumHueSaturation, Value: 1.0}
+		case ZCLColorModeXY:
+			state.Mode = capabilities.ColorMode
+			x, _ := i.s.Int(XKey)
+			y, _ := i.s.Int(YKey)
+			state.Color = color.XYColor{X: float64(x) / 65536.0, Y: float64(y) / 65536.0, Y2: 1.0}
+		case ZCLColorModeColorTemperature:
+			state.Mode = capabilities.TemperatureMode
+		}
+
+		if mireds, _ := i.s.Int(TemperatureKey); mireds > 0 {
+			state.Temperature = 1000000.0 / float64(mireds)
+		}
+	}
+
+	return capabilities.LightStatus{Current: state, Target: state}, nil
+}
+
+func (i *Implementation) SupportsBrightness(_ context.Context) (bool, error) {
+	return i.levelPresent, nil
+}
+
+func (i *Implementation) SupportsColor(_ context.Context) (bool, error) {
+	return i.colorPresent && i.colorCapabilities&(HueSaturationSupported|XYSupported) != 0, nil
+}
+
+func (i *Implementation) SupportsTemperature(_ context.Context) (bool, error) {
+	return i.colorPresent && i.colorCapabilities&ColorTemperatureSupported == ColorTemperatureSupported, nil
+}
+
+// On turns the light on, restoring its previous level.
+func (i *Implementation) On(ctx context.Context) error {
+	if !i.onOffPresent {
+		return fmt.Errorf("light does not support on off cluster")
+	}
+
+	return i.sendCommand(ctx, zcl.OnOffId, onoff.OnId, &onoff.On{})
+}
+
+// Off turns the light off.
+func (i *Implementation) Off(ctx context.Context) error {
+	if !i.onOffPresent {
+		return fmt.Errorf("light does not support on off cluster")
+	}
+
+	return i.sendCommand(ctx, zcl.OnOffId, onoff.OffId, &onoff.Off{})
+}
+
+func (i *Implementation) SetBrightness(ctx context.Context, brightness float64, duration time.Duration) error {
+	if !i.levelPresent {
+		if brightness > 0 {
+			return i.On(ctx)
+		} else {
+			return i.Off(ctx)
+		}
+	}
+
+	lvl := uint8(math.Round(math.Max(0, math.Min(1.0, brightness)) * maximumLevel))
+
+	return i.sendCommand(ctx, zcl.LevelControlId, level.MoveToLevelWithOnOffId, &level.MoveToLevelWithOnOff{Level: lvl, TransitionTime: transitionTime(duration)})
+}
+
+func (i *Implementation) SetColor(ctx context.Context, c color.ConvertibleColor, duration time.Duration) error {
+	if !i.colorPresent {
+		return fmt.Errorf("light does not support color cluster")
+	}
+
+	if i.colorCapabilities&XYSupported == XYSupported {
+		x, y, _ := c.XYY()
+		return i.sendCommand(ctx, zcl.ColorControlId, color_control.MoveToColorId, &color_control.MoveToColor{
+			ColorX:         uint16(math.Round(math.Max(0, math.Min(maximumXY, x*65536.0)))),
+			ColorY:         uint16(math.Round(math.Max(0, math.Min(maximumXY, y*65536.0)))),
+			TransitionTime: transitionTime(duration),
+		})
+	} else if i.colorCapabilities&HueSaturationSupported == HueSaturationSupported {
+		h, s, _ := c.HSV()
+		return i.sendCommand(ctx, zcl.ColorControlId, color_control.MoveToHueAndSaturationId, &color_control.MoveToHueAndSaturation{
+			Hue:            uint8(math.Round(math.Max(0, math.Min(maximumHueSaturation, h/360.0*maximumHueSaturation)))),
+			Saturation:     uint8(math.Round(math.Max(0, math.Min(maximumHueSaturation, s*maximumHueSaturation)))),
+			TransitionTime: transitionTime(duration),
+		})
+	}
+
+	return fmt.Errorf("light does not support hue/saturation or xy color")
+}
+
+func (i *Implementation) SetTemperature(ctx context.Context, kelvin float64, duration time.Duration) error {
+	if supported, _ := i.SupportsTemperature(ctx); !supported || kelvin <= 0 {
+		return fmt.Errorf("light does not support color temperature")
+	}
+
+	minMireds, _ := i.s.Int(ColorTemperatureMinimumKey, defaultMinimumMireds)
+	maxMireds, _ := i.s.Int(ColorTemperatureMaximumKey, defaultMaximumMireds)
+
+	mireds := math.Round(1000000.0 / kelvin)
+	mireds = math.Max(float64(minMireds), math.Min(float64(maxMireds), mireds))
+
+	return i.sendCommand(ctx, zcl.ColorControlId, color_control.MoveToColorTemperatureId, &color_control.MoveToColorTemperature{ColorTemperatureMireds: uint16(mireds), TransitionTime: transitionTime(duration)})
+}
+
+// GroupCommand updates the state of the light from commands multicast to its groups, commands for clusters the light
+// does not have are ignored by the light and so are ignored here.
+func (i *Implementation) GroupCommand(m zcl.Message) {
+	switch cmd := m.Command.(type) {
+	case *onoff.On:
+		i.groupOnOff(true)
+	case *onoff.Off:
+		i.groupOnOff(false)
+	case *onoff.Toggle:
+		on, _ := i.s.Bool(OnKey)
+		i.groupOnOff(!on)
+	case *level.MoveToLevel:
+		i.groupLevel(cmd.Level)
+	case *level.MoveToLevelWithOnOff:
+		i.groupLevel(cmd.Level)
+		i.groupOnOff(cmd.Level > 0)
+	}
+}
+
+func (i *Implementation) groupOnOff(on bool) {
+	if i.onOffPresent {
+		i.updateOnOff(onoff.OnOff, zcl.AttributeDataTypeValue{DataType: zcl.TypeBoolean, Value: on})
+	}
+}
+
+func (i *Implementation) groupLevel(lvl uint8) {
+	if i.levelPresent {
+		i.updateLevel(level.CurrentLevel, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(lvl)})
+	}
+}
+
+func (i *Implementation) sendCommand(ctx context.Context, cluster zigbee.ClusterID, id zcl.CommandIdentifier, cmd any) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           cluster,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+}
+
+// transitionTime converts a duration into the tenths of a second used by ZCL transition times.
+func transitionTime(d time.Duration) uint16 {
+	return uint16(math.Min(0xfffe, math.Round(float64(d)/float64(100*time.Millisecond))))
+}
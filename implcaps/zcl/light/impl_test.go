@@ -0,0 +1,422 @@
+package light
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/da/capabilities/color"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/color_control"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything).Times(3)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewLight(newMockZDAInterface(t))
+
+		assert.Equal(t, capabilities.LightFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.LightFlag], i.Name())
+		assert.Equal(t, "ZCLLight", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs attribute monitors correctly initialising them", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm).Times(8)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		mm.On("Init", mock.Anything, md, mock.Anything).Times(8)
+
+		i := NewLight(mzi)
+		i.Init(md, memory.New())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads only the monitors for present clusters and capabilities", func(t *testing.T) {
+		onOff := &attribute.MockMonitor{}
+		defer onOff.AssertExpectations(t)
+		onOff.On("Load", mock.Anything).Return(nil)
+
+		temp := &attribute.MockMonitor{}
+		defer temp.AssertExpectations(t)
+		temp.On("Load", mock.Anything).Return(nil)
+
+		mode := &attribute.MockMonitor{}
+		defer mode.AssertExpectations(t)
+		mode.On("Load", mock.Anything).Return(nil)
+
+		i := NewLight(newMockZDAInterface(t))
+		i.s = memory.New()
+		i.s.Set(implcaps.RemoteEndpointKey, 3)
+		i.s.Set(OnOffPresentKey, true)
+		i.s.Set(ColorPresentKey, true)
+		i.s.Set(ColorCapabilitiesKey, int(ColorTemperatureSupported))
+
+		i.onOffMonitor = onOff
+		i.temperatureMonitor = temp
+		i.colorModeMonitor = mode
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(3), i.remoteEndpoint)
+		assert.False(t, i.levelPresent)
+	})
+
+	t.Run("returns false if a monitor fails to load", func(t *testing.T) {
+		onOff := &attribute.MockMonitor{}
+		defer onOff.AssertExpectations(t)
+		onOff.On("Load", mock.Anything).Return(io.EOF)
+
+		i := NewLight(newMockZDAInterface(t))
+		i.s = memory.New()
+		i.s.Set(implcaps.RemoteEndpointKey, 3)
+		i.s.Set(OnOffPresentKey, true)
+		i.onOffMonitor = onOff
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads color capabilities and attaches monitors for supported attributes", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+		mzi.On("ZCLCommunicator").Return(mzc)
+
+		ieee := zigbee.GenerateLocalAdministeredIEEEAddress()
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(ieee, zigbee.Endpoint(1), false, 1)
+
+		mzc.On("ReadAttributes", mock.Anything, ieee, false, zcl.ColorControlId, zigbee.NoManufacturer, zigbee.Endpoint(1), zigbee.Endpoint(2), uint8(1), []zcl.AttributeID{color_control.ColorCapabilities, color_control.ColorTempPhysicalMinMireds, color_control.ColorTempPhysicalMaxMireds}).
+			Return([]global.ReadAttributeResponseRecord{
+				{Identifier: color_control.ColorCapabilities, Status: 0, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeBitmap16, Value: uint64(HueSaturationSupported | ColorTemperatureSupported)}},
+				{Identifier: color_control.ColorTempPhysicalMinMireds, Status: 0, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(200)}},
+				{Identifier: color_control.ColorTempPhysicalMaxMireds, Status: 0, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(400)}},
+			}, nil)
+
+		attachedMonitor := func(c zigbee.ClusterID, a zcl.AttributeID, dt zcl.AttributeDataType) *attribute.MockMonitor {
+			mm := &attribute.MockMonitor{}
+			t.Cleanup(func() { mm.AssertExpectations(t) })
+			mm.On("Attach", mock.Anything, zigbee.Endpoint(2), c, a, dt, mock.Anything, mock.Anything).Return(nil)
+			return mm
+		}
+
+		i := NewLight(mzi)
+		i.s = memory.New()
+		i.onOffMonitor = attachedMonitor(zcl.OnOffId, onoff.OnOff, zcl.TypeBoolean)
+		i.levelMonitor = attachedMonitor(zcl.LevelControlId, level.CurrentLevel, zcl.TypeUnsignedInt8)
+		i.colorModeMonitor = attachedMonitor(zcl.ColorControlId, color_control.ColorMode, zcl.TypeEnum8)
+		i.hueMonitor = attachedMonitor(zcl.ColorControlId, color_control.CurrentHue, zcl.TypeUnsignedInt8)
+		i.saturationMonitor = attachedMonitor(zcl.ColorControlId, color_control.CurrentSaturation, zcl.TypeUnsignedInt8)
+		i.temperatureMonitor = attachedMonitor(zcl.ColorControlId, color_control.ColorTemperatureMireds, zcl.TypeUnsignedInt16)
+		i.xMonitor = &attribute.MockMonitor{}
+		i.yMonitor = &attribute.MockMonitor{}
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{
+			"ZigbeeEndpoint":            zigbee.Endpoint(2),
+			"ZigbeeOnOffClusterPresent": true,
+			"ZigbeeLevelClusterPresent": true,
+			"ZigbeeColorClusterPresent": true,
+		})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		caps, _ := i.s.Int(ColorCapabilitiesKey)
+		assert.Equal(t, int64(HueSaturationSupported|ColorTemperatureSupported), caps)
+
+		minMireds, _ := i.s.Int(ColorTemperatureMinimumKey)
+		assert.Equal(t, int64(200), minMireds)
+
+		maxMireds, _ := i.s.Int(ColorTemperatureMaximumKey)
+		assert.Equal(t, int64(400), maxMireds)
+	})
+
+	t.Run("does not attach if monitors fail to attach", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(1), zcl.OnOffId, onoff.OnOff, zcl.TypeBoolean, mock.Anything, mock.Anything).Return(io.EOF)
+
+		i := NewLight(newMockZDAInterface(t))
+		i.s = memory.New()
+		i.onOffMonitor = mm
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeOnOffClusterPresent": true})
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detaches present monitors", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+		mm.On("Detach", mock.Anything, true).Return(nil).Twice()
+
+		i := NewLight(newMockZDAInterface(t))
+		i.onOffPresent = true
+		i.levelPresent = true
+		i.onOffMonitor = mm
+		i.levelMonitor = mm
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates level and sends event on change", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(capabilities.LightStatusUpdate)
+			assert.True(t, ok)
+			assert.InDelta(t, 0.5, e.State.Current.Brightness, 0.01)
+		}).Once()
+
+		i := NewLight(mzi)
+		i.s = memory.New()
+		i.levelPresent = true
+
+		i.updateLevel(level.CurrentLevel, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(127)})
+		i.updateLevel(level.CurrentLevel, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(127)})
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.False(t, lct.IsZero())
+	})
+
+	t.Run("reports zero brightness while off", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		mzi.On("SendEvent", mock.Anything)
+
+		i := NewLight(mzi)
+		i.s = memory.New()
+		i.onOffPresent = true
+		i.levelPresent = true
+
+		i.updateLevel(level.CurrentLevel, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(254)})
+		i.updateOnOff(onoff.OnOff, zcl.AttributeDataTypeValue{DataType: zcl.TypeBoolean, Value: false})
+
+		s, _ := i.Status(context.TODO())
+		assert.Equal(t, 0.0, s.Current.Brightness)
+
+		i.updateOnOff(onoff.OnOff, zcl.AttributeDataTypeValue{DataType: zcl.TypeBoolean, Value: true})
+
+		s, _ = i.Status(context.TODO())
+		assert.Equal(t, 1.0, s.Current.Brightness)
+	})
+
+	t.Run("reports color and temperature according to color mode", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		mzi.On("SendEvent", mock.Anything)
+
+		i := NewLight(mzi)
+		i.s = memory.New()
+		i.colorPresent = true
+
+		i.updateColor(color_control.CurrentX, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(32768)})
+		i.updateColor(color_control.CurrentY, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(16384)})
+		i.updateColor(color_control.ColorTemperatureMireds, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(250)})
+		i.updateColor(color_control.ColorMode, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: ZCLColorModeXY})
+
+		s, _ := i.Status(context.TODO())
+		assert.Equal(t, capabilities.ColorMode, s.Current.Mode)
+		x, y, _ := s.Current.Color.XYY()
+		assert.InDelta(t, 0.5, x, 0.001)
+		assert.InDelta(t, 0.25, y, 0.001)
+		assert.InDelta(t, 4000, s.Current.Temperature, 0.1)
+
+		i.updateColor(color_control.ColorMode, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: ZCLColorModeColorTemperature})
+
+		s, _ = i.Status(context.TODO())
+		assert.Equal(t, capabilities.TemperatureMode, s.Current.Mode)
+	})
+}
+
+func TestImplementation_GroupCommand(t *testing.T) {
+	t.Run("updates on off and level from commands multicast to the lights groups", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		mzi.On("SendEvent", mock.Anything)
+
+		i := NewLight(mzi)
+		i.s = memory.New()
+		i.onOffPresent = true
+		i.levelPresent = true
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.LevelControlId, Command: &level.MoveToLevelWithOnOff{Level: 127}})
+
+		s, _ := i.Status(context.TODO())
+		assert.InDelta(t, 0.5, s.Current.Brightness, 0.01)
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.OnOffId, Command: &onoff.Off{}})
+
+		s, _ = i.Status(context.TODO())
+		assert.Equal(t, 0.0, s.Current.Brightness)
+	})
+
+	t.Run("ignores commands for clusters the light does not have", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		i := NewLight(mzi)
+		i.s = memory.New()
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.OnOffId, Command: &onoff.On{}})
+		i.GroupCommand(zcl.Message{ClusterID: zcl.LevelControlId, Command: &level.MoveToLevel{Level: 127}})
+
+		_, found := i.s.Int(LevelKey)
+		assert.False(t, found)
+	})
+}
+
+func TestImplementation_Supports(t *testing.T) {
+	t.Run("reports support based upon clusters and color capabilities", func(t *testing.T) {
+		i := &Implementation{levelPresent: true, colorPresent: true, colorCapabilities: ColorTemperatureSupported}
+
+		b, _ := i.SupportsBrightness(context.TODO())
+		assert.True(t, b)
+
+		c, _ := i.SupportsColor(context.TODO())
+		assert.False(t, c)
+
+		tmp, _ := i.SupportsTemperature(context.TODO())
+		assert.True(t, tmp)
+	})
+}
+
+func TestImplementation_Commands(t *testing.T) {
+	expectCommand := func(t *testing.T, i *Implementation, mzi *implcaps.MockZDAInterface, cluster zigbee.ClusterID, id zcl.CommandIdentifier, cmd any) {
+		mzc := &mocks.MockZCLCommunicator{}
+		t.Cleanup(func() { mzc.AssertExpectations(t) })
+		mzi.On("ZCLCommunicator").Return(mzc)
+
+		md := &mocks2.MockDevice{}
+		i.d = md
+
+		ieee := zigbee.GenerateLocalAdministeredIEEEAddress()
+		mzi.On("TransmissionLookup", md, zigbee.ProfileHomeAutomation).Return(ieee, zigbee.Endpoint(1), false, 6)
+
+		expectedMsg := zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: 6,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           cluster,
+			SourceEndpoint:      zigbee.Endpoint(1),
+			DestinationEndpoint: i.remoteEndpoint,
+			CommandIdentifier:   id,
+			Command:             cmd,
+		}
+
+		mzc.On("Request", mock.Anything, ieee, false, mock.MatchedBy(func(actualMsg zcl.Message) bool {
+			return assert.ObjectsAreEqual(expectedMsg, actualMsg)
+		})).Return(nil)
+	}
+
+	t.Run("SetBrightness sends MoveToLevelWithOnOff with transition time", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i := NewLight(mzi)
+		i.remoteEndpoint = 2
+		i.levelPresent = true
+
+		expectCommand(t, i, mzi, zcl.LevelControlId, level.MoveToLevelWithOnOffId, &level.MoveToLevelWithOnOff{Level: 127, TransitionTime: 15})
+
+		assert.NoError(t, i.SetBrightness(context.TODO(), 0.5, 1500*time.Millisecond))
+	})
+
+	t.Run("SetBrightness falls back to On if level is not present", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i := NewLight(mzi)
+		i.remoteEndpoint = 2
+		i.onOffPresent = true
+
+		expectCommand(t, i, mzi, zcl.OnOffId, onoff.OnId, &onoff.On{})
+
+		assert.NoError(t, i.SetBrightness(context.TODO(), 0.5, 0))
+	})
+
+	t.Run("SetColor sends MoveToColor if XY is supported", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i := NewLight(mzi)
+		i.remoteEndpoint = 2
+		i.colorPresent = true
+		i.colorCapabilities = XYSupported | HueSaturationSupported
+
+		expectCommand(t, i, mzi, zcl.ColorControlId, color_control.MoveToColorId, &color_control.MoveToColor{ColorX: 32768, ColorY: 16384, TransitionTime: 10})
+
+		assert.NoError(t, i.SetColor(context.TODO(), color.XYColor{X: 0.5, Y: 0.25, Y2: 1.0}, 1*time.Second))
+	})
+
+	t.Run("SetColor sends MoveToHueAndSaturation if only hue and saturation are supported", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i := NewLight(mzi)
+		i.remoteEndpoint = 2
+		i.colorPresent = true
+		i.colorCapabilities = HueSaturationSupported
+
+		expectCommand(t, i, mzi, zcl.ColorControlId, color_control.MoveToHueAndSaturationId, &color_control.MoveToHueAndSaturation{Hue: 127, Saturation: 254, TransitionTime: 0})
+
+		assert.NoError(t, i.SetColor(context.TODO(), color.HSVColor{Hue: 180, Sat: 1.0, Value: 1.0}, 0))
+	})
+
+	t.Run("SetTemperature clamps to the physical range of the light", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i := NewLight(mzi)
+		i.s = memory.New()
+		i.remoteEndpoint = 2
+		i.colorPresent = true
+		i.colorCapabilities = ColorTemperatureSupported
+		i.s.Set(ColorTemperatureMinimumKey, 200)
+		i.s.Set(ColorTemperatureMaximumKey, 400)
+
+		expectCommand(t, i, mzi, zcl.ColorControlId, color_control.MoveToColorTemperatureId, &color_control.MoveToColorTemperature{ColorTemperatureMireds: 400, TransitionTime: 0})
+
+		assert.NoError(t, i.SetTemperature(context.TODO(), 2000, 0))
+	})
+
+	t.Run("SetTemperature errors if color temperature is unsupported", func(t *testing.T) {
+		i := NewLight(newMockZDAInterface(t))
+		i.colorPresent = true
+		i.colorCapabilities = XYSupported
+
+		assert.Error(t, i.SetTemperature(context.TODO(), 2700, 0))
+	})
+}
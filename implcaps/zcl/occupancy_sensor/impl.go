@@ -0,0 +1,215 @@
+package occupancy_sensor
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ capabilities.OccupancySensor = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	Occupancy                    = zcl.AttributeID(0x0000)
+	OccupancySensorType          = zcl.AttributeID(0x0001)
+	PIROccupiedToUnoccupiedDelay = zcl.AttributeID(0x0010)
+)
+
+const (
+	OccupiedKey                     = "Occupied"
+	PIROccupiedToUnoccupiedDelayKey = "PIROccupiedToUnoccupiedDelay"
+)
+
+func NewOccupancySensor(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	am attribute.Monitor
+	zi implcaps.ZDAInterface
+
+	remoteEndpoint zigbee.Endpoint
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.OccupancySensorFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.OccupancySensorFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = i.zi.NewAttributeMonitor()
+	i.am.Init(s.Section("AttributeMonitor", "Occupancy"), d, i.update)
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("monitor missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	if err := i.am.Load(ctx); err != nil {
+		return false, err
+	} else {
+		return true, nil
+	}
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	reporting := attribute.ReportingConfig{
+		Mode:            attribute.AttemptConfigureReporting,
+		MinimumInterval: 0 * time.Second,
+		MaximumInterval: 5 * time.Minute,
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 30 * time.Second,
+	}
+
+	if err := i.am.Attach(ctx, i.remoteEndpoint, zcl.OccupancySensingId, Occupancy, zcl.TypeBitmap8, reporting, polling); err != nil {
+		return false, err
+	}
+
+	/* Not all occupancy sensors are PIR based, failure to read the delay is not fatal. */
+	_, _ = i.PIROccupiedToUnoccupiedDelay(ctx)
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	if err := i.am.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLOccupancySensor"
+}
+
+func (i *Implementation) update(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType == zcl.TypeBitmap8 {
+		if value, ok := v.Value.(uint64); ok {
+			occupied := value&0x01 == 0x01
+
+			if current, found := i.s.Bool(OccupiedKey); !found || current != occupied {
+				i.s.Set(OccupiedKey, occupied)
+				converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+				i.zi.SendEvent(capabilities.OccupancySensorUpdate{Device: i.d, State: i.readings()})
+			}
+
+			converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+		}
+	}
+}
+
+func (i *Implementation) readings() []capabilities.OccupancyReading {
+	occupied, _ := i.s.Bool(OccupiedKey)
+
+	var duration time.Duration
+
+	if occupied {
+		if t, found := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder); found {
+			duration = time.Since(t)
+		}
+	}
+
+	return []capabilities.OccupancyReading{
+		{
+			Occupied: occupied,
+			Duration: duration,
+		},
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Reading(_ context.Context) ([]capabilities.OccupancyReading, error) {
+	return i.readings(), nil
+}
+
+// PIROccupiedToUnoccupiedDelay reads the delay between the PIR sensor last detecting movement and the sensor
+// reporting unoccupied from the device, the last known value is returned if the device can not be read.
+func (i *Implementation) PIROccupiedToUnoccupiedDelay(ctx context.Context) (time.Duration, error) {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.OccupancySensingId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{PIROccupiedToUnoccupiedDelay})
+	if err != nil {
+		d, _ := converter.Retrieve(i.s, PIROccupiedToUnoccupiedDelayKey, converter.DurationDecoder)
+		return d, err
+	}
+
+	rec, found := communicator.ReadResponsesToMap(recs)[PIROccupiedToUnoccupiedDelay]
+	if !found || rec.Status != 0 || rec.DataTypeValue == nil {
+		d, _ := converter.Retrieve(i.s, PIROccupiedToUnoccupiedDelayKey, converter.DurationDecoder)
+		return d, fmt.Errorf("device did not return pir occupied to unoccupied delay")
+	}
+
+	seconds, _ := rec.DataTypeValue.Value.(uint64)
+	delay := time.Duration(seconds) * time.Second
+
+	converter.Store(i.s, PIROccupiedToUnoccupiedDelayKey, delay, converter.DurationEncoder)
+
+	return delay, nil
+}
+
+// SetPIROccupiedToUnoccupiedDelay configures the delay between the PIR sensor last detecting movement and the sensor
+// reporting unoccupied, the delay is set with a resolution of one second.
+func (i *Implementation) SetPIROccupiedToUnoccupiedDelay(ctx context.Context, delay time.Duration) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	seconds := uint64(math.Min(0xffff, math.Max(0, delay.Seconds())))
+
+	recs, err := i.zi.ZCLCommunicator().WriteAttributes(ctx, ieee, ack, zcl.OccupancySensingId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+		PIROccupiedToUnoccupiedDelay: {
+			DataType: zcl.TypeUnsignedInt16,
+			Value:    seconds,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if rec, found := communicator.WriteResponsesToMap(recs)[PIROccupiedToUnoccupiedDelay]; found && rec.Status != 0 {
+		return fmt.Errorf("failed to write pir occupied to unoccupied delay: status %d", rec.Status)
+	}
+
+	converter.Store(i.s, PIROccupiedToUnoccupiedDelayKey, time.Duration(seconds)*time.Second, converter.DurationEncoder)
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package occupancy_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewOccupancySensor(nil)
+
+		assert.Equal(t, capabilities.OccupancySensorFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.OccupancySensorFlag], i.Name())
+		assert.Equal(t, "ZCLOccupancySensor", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs a new attribute monitor correctly initialising it", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		s := memory.New()
+		es := s.Section("AttributeMonitor", "Occupancy")
+
+		mm.On("Init", es, md, mock.Anything)
+
+		i := NewOccupancySensor(mzi)
+		i.Init(md, s)
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads attribute monitor functionality, returning true if successful", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Load", mock.Anything).Return(nil)
+
+		i := NewOccupancySensor(nil)
+		i.am = mm
+		i.s = memory.New()
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(2), i.remoteEndpoint)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i := NewOccupancySensor(nil)
+		i.s = memory.New()
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+
+	t.Run("loads attribute monitor functionality, returning false if error", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Load", mock.Anything).Return(io.EOF)
+
+		i := NewOccupancySensor(nil)
+		i.am = mm
+		i.s = memory.New()
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches to the attribute monitor and reads the pir delay", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(0x01), zcl.OccupancySensingId, Occupancy, zcl.TypeBitmap8, mock.Anything, mock.Anything).Return(nil)
+
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.OccupancySensingId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(1), uint8(3), []zcl.AttributeID{PIROccupiedToUnoccupiedDelay}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: PIROccupiedToUnoccupiedDelay, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(30)}},
+		}, nil)
+
+		i := NewOccupancySensor(mzi)
+		i.am = mm
+		i.s = memory.New()
+		attached, err := i.Enumerate(context.TODO(), make(map[string]any))
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		delay, _ := converter.Retrieve(i.s, PIROccupiedToUnoccupiedDelayKey, converter.DurationDecoder)
+		assert.Equal(t, 30*time.Second, delay)
+	})
+
+	t.Run("fails if attach to the attribute monitor fails", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(0x01), zcl.OccupancySensingId, Occupancy, zcl.TypeBitmap8, mock.Anything, mock.Anything).Return(io.EOF)
+
+		i := NewOccupancySensor(nil)
+		i.am = mm
+		i.s = memory.New()
+		attached, err := i.Enumerate(context.TODO(), make(map[string]any))
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detached attribute monitor on detach", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Detach", mock.Anything, true).Return(nil)
+
+		i := NewOccupancySensor(nil)
+		i.am = mm
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates the state correctly, sending event if change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(capabilities.OccupancySensorUpdate)
+			assert.True(t, ok)
+			assert.True(t, e.State[0].Occupied)
+		})
+
+		i := NewOccupancySensor(mzi)
+		i.s = memory.New()
+
+		i.s.Set(OccupiedKey, false)
+
+		lastUpdated := time.Now().Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeBitmap8,
+			Value:    uint64(0x01),
+		})
+
+		r, _ := i.Reading(context.TODO())
+		assert.True(t, r[0].Occupied)
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Greater(t, lct, lastUpdated)
+	})
+
+	t.Run("updates the state correctly, no event if no change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		i := NewOccupancySensor(mzi)
+		i.s = memory.New()
+
+		i.s.Set(OccupiedKey, false)
+
+		lastUpdated := time.UnixMilli(time.Now().UnixMilli()).Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeBitmap8,
+			Value:    uint64(0x00),
+		})
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Equal(t, lct, lastUpdated)
+	})
+}
+
+func TestImplementation_Reading(t *testing.T) {
+	t.Run("returns occupancy with the duration since it became occupied", func(t *testing.T) {
+		i := NewOccupancySensor(nil)
+		i.s = memory.New()
+
+		i.s.Set(OccupiedKey, true)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now().Add(-1*time.Minute), converter.TimeEncoder)
+
+		r, err := i.Reading(context.TODO())
+		assert.NoError(t, err)
+		assert.Len(t, r, 1)
+		assert.True(t, r[0].Occupied)
+		assert.GreaterOrEqual(t, r[0].Duration, time.Minute)
+	})
+
+	t.Run("returns no duration when unoccupied", func(t *testing.T) {
+		i := NewOccupancySensor(nil)
+		i.s = memory.New()
+
+		i.s.Set(OccupiedKey, false)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now().Add(-1*time.Minute), converter.TimeEncoder)
+
+		r, err := i.Reading(context.TODO())
+		assert.NoError(t, err)
+		assert.False(t, r[0].Occupied)
+		assert.Zero(t, r[0].Duration)
+	})
+}
+
+func TestImplementation_SetPIROccupiedToUnoccupiedDelay(t *testing.T) {
+	t.Run("writes the delay to the device and stores it", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzc.On("WriteAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.OccupancySensingId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			PIROccupiedToUnoccupiedDelay: {DataType: zcl.TypeUnsignedInt16, Value: uint64(90)},
+		}).Return([]global.WriteAttributesResponseRecord{{Identifier: PIROccupiedToUnoccupiedDelay}}, nil)
+
+		i := NewOccupancySensor(mzi)
+		i.s = memory.New()
+		i.remoteEndpoint = 4
+
+		err := i.SetPIROccupiedToUnoccupiedDelay(context.TODO(), 90*time.Second)
+		assert.NoError(t, err)
+
+		delay, _ := converter.Retrieve(i.s, PIROccupiedToUnoccupiedDelayKey, converter.DurationDecoder)
+		assert.Equal(t, 90*time.Second, delay)
+	})
+
+	t.Run("returns an error if the device rejects the write", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzc.On("WriteAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.WriteAttributesResponseRecord{{Identifier: PIROccupiedToUnoccupiedDelay, Status: 0x88}}, nil)
+
+		i := NewOccupancySensor(mzi)
+		i.s = memory.New()
+
+		err := i.SetPIROccupiedToUnoccupiedDelay(context.TODO(), 90*time.Second)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i := NewOccupancySensor(nil)
+		i.s = memory.New()
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
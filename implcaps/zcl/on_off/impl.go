@@ -0,0 +1,189 @@
+package on_off
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"time"
+)
+
+var _ capabilities.OnOff = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+var _ implcaps.GroupCommandObserver = (*Implementation)(nil)
+
+const StateKey = "State"
+
+func NewOnOff(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(onoff.Register)
+	return &Implementation{zi: zi}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	am attribute.Monitor
+	zi implcaps.ZDAInterface
+
+	remoteEndpoint zigbee.Endpoint
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.OnOffFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.OnOffFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = i.zi.NewAttributeMonitor()
+	i.am.Init(s.Section("AttributeMonitor", "OnOff"), d, i.update)
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("monitor missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	if err := i.am.Load(ctx); err != nil {
+		return false, err
+	} else {
+		return true, nil
+	}
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	reporting := attribute.ReportingConfig{
+		Mode:            attribute.AttemptConfigureReporting,
+		MinimumInterval: 1 * time.Second,
+		MaximumInterval: 5 * time.Minute,
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	if err := i.am.Attach(ctx, i.remoteEndpoint, zcl.OnOffId, onoff.OnOff, zcl.TypeBoolean, reporting, polling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	if err := i.am.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLOnOff"
+}
+
+func (i *Implementation) update(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType == zcl.TypeBoolean {
+		if value, ok := v.Value.(bool); ok {
+			i.updateState(value)
+		}
+	}
+}
+
+func (i *Implementation) updateState(on bool) {
+	if current, found := i.s.Bool(StateKey); !found || current != on {
+		i.s.Set(StateKey, on)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		i.zi.SendEvent(capabilities.OnOffUpdate{Device: i.d, State: capabilities.OnOffState{On: on}})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (bool, error) {
+	on, _ := i.s.Bool(StateKey)
+	return on, nil
+}
+
+func (i *Implementation) On(ctx context.Context) error {
+	if err := i.sendCommand(ctx, onoff.OnId, &onoff.On{}); err != nil {
+		return err
+	}
+
+	i.updateState(true)
+	return nil
+}
+
+func (i *Implementation) Off(ctx context.Context) error {
+	if err := i.sendCommand(ctx, onoff.OffId, &onoff.Off{}); err != nil {
+		return err
+	}
+
+	i.updateState(false)
+	return nil
+}
+
+// Toggle inverts the current state of the device, the resulting state will be reported back by the attribute monitor.
+func (i *Implementation) Toggle(ctx context.Context) error {
+	return i.sendCommand(ctx, onoff.ToggleId, &onoff.Toggle{})
+}
+
+// GroupCommand updates the state from commands multicast to the devices groups.
+func (i *Implementation) GroupCommand(m zcl.Message) {
+	switch m.Command.(type) {
+	case *onoff.On:
+		i.updateState(true)
+	case *onoff.Off:
+		i.updateState(false)
+	case *onoff.Toggle:
+		on, _ := i.s.Bool(StateKey)
+		i.updateState(!on)
+	}
+}
+
+func (i *Implementation) sendCommand(ctx context.Context, id zcl.CommandIdentifier, cmd any) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.OnOffId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+}
@@ -0,0 +1,382 @@
+package on_off
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+		mzi.On("ZCLRegister", mock.Anything)
+
+		i := NewOnOff(mzi)
+
+		assert.Equal(t, capabilities.OnOffFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.OnOffFlag], i.Name())
+		assert.Equal(t, "ZCLOnOff", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs a new attribute monitor correctly initialising it", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+		mzi.On("ZCLRegister", mock.Anything)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		s := memory.New()
+		es := s.Section("AttributeMonitor", "OnOff")
+
+		mm.On("Init", es, md, mock.Anything)
+
+		i := NewOnOff(mzi)
+		i.Init(md, s)
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads attribute monitor functionality, returning true if successful", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Load", mock.Anything).Return(nil)
+
+		i := &Implementation{}
+		i.am = mm
+		i.s = memory.New()
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(2), i.remoteEndpoint)
+	})
+
+	t.Run("returns false if the remote endpoint is missing from persistence", func(t *testing.T) {
+		i := &Implementation{}
+		i.s = memory.New()
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+
+	t.Run("loads attribute monitor functionality, returning false if error", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Load", mock.Anything).Return(io.EOF)
+
+		i := &Implementation{}
+		i.am = mm
+		i.s = memory.New()
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches to the attribute monitor", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(0x02), zcl.OnOffId, onoff.OnOff, zcl.TypeBoolean, mock.Anything, mock.Anything).Return(nil)
+
+		i := &Implementation{}
+		i.am = mm
+		i.s = memory.New()
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(0x02)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		ep, _ := i.s.Int(implcaps.RemoteEndpointKey)
+		assert.Equal(t, int64(2), ep)
+	})
+
+	t.Run("fails if attach to the attribute monitor fails", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(0x01), zcl.OnOffId, onoff.OnOff, zcl.TypeBoolean, mock.Anything, mock.Anything).Return(io.EOF)
+
+		i := &Implementation{}
+		i.am = mm
+		i.s = memory.New()
+
+		attached, err := i.Enumerate(context.TODO(), make(map[string]any))
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detached attribute monitor on detach", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Detach", mock.Anything, true).Return(nil)
+
+		i := &Implementation{}
+		i.am = mm
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates the state correctly, sending event if change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(capabilities.OnOffUpdate)
+			assert.True(t, ok)
+			assert.True(t, e.State.On)
+		})
+
+		i := &Implementation{zi: mzi}
+		i.s = memory.New()
+		i.s.Set(StateKey, false)
+
+		lastUpdated := time.Now().Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(onoff.OnOff, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeBoolean,
+			Value:    true,
+		})
+
+		state, _ := i.Status(context.TODO())
+		assert.True(t, state)
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Greater(t, lct, lastUpdated)
+	})
+
+	t.Run("updates the state correctly, no event if no change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		i := &Implementation{zi: mzi}
+		i.s = memory.New()
+		i.s.Set(StateKey, true)
+
+		lastUpdated := time.UnixMilli(time.Now().UnixMilli()).Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(onoff.OnOff, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeBoolean,
+			Value:    true,
+		})
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Equal(t, lct, lastUpdated)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i := &Implementation{}
+		i.s = memory.New()
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
+
+func TestImplementation_OnOff(t *testing.T) {
+	sendsCommand := func(t *testing.T, expectedId zcl.CommandIdentifier, expectedCmd any, fn func(*Implementation) error, expectedState bool) {
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+		mzi.On("ZCLRegister", mock.Anything)
+		mzi.On("ZCLCommunicator").Return(mzc)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		i := NewOnOff(mzi)
+		i.d = md
+		i.s = memory.New()
+		i.s.Set(StateKey, !expectedState)
+		i.remoteEndpoint = 4
+
+		ieee := zigbee.GenerateLocalAdministeredIEEEAddress()
+		localEndpoint := zigbee.Endpoint(0x01)
+		seq := 8
+
+		mzi.On("TransmissionLookup", md, zigbee.ProfileHomeAutomation).Return(ieee, localEndpoint, false, seq)
+
+		expectedMsg := zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: uint8(seq),
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.OnOffId,
+			SourceEndpoint:      localEndpoint,
+			DestinationEndpoint: i.remoteEndpoint,
+			CommandIdentifier:   expectedId,
+			Command:             expectedCmd,
+		}
+
+		mzc.On("Request", mock.Anything, ieee, false, mock.MatchedBy(func(actualMsg zcl.Message) bool {
+			return assert.ObjectsAreEqual(expectedMsg, actualMsg)
+		})).Return(nil)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(capabilities.OnOffUpdate)
+			assert.True(t, ok)
+			assert.Equal(t, expectedState, e.State.On)
+		})
+
+		err := fn(i)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, expectedState, state)
+	}
+
+	t.Run("sends On command to device and updates state", func(t *testing.T) {
+		sendsCommand(t, onoff.OnId, &onoff.On{}, func(i *Implementation) error { return i.On(context.TODO()) }, true)
+	})
+
+	t.Run("sends Off command to device and updates state", func(t *testing.T) {
+		sendsCommand(t, onoff.OffId, &onoff.Off{}, func(i *Implementation) error { return i.Off(context.TODO()) }, false)
+	})
+
+	t.Run("sends Toggle command to device, leaving state for the monitor", func(t *testing.T) {
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+		mzi.On("ZCLRegister", mock.Anything)
+		mzi.On("ZCLCommunicator").Return(mzc)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		i := NewOnOff(mzi)
+		i.d = md
+		i.s = memory.New()
+
+		ieee := zigbee.GenerateLocalAdministeredIEEEAddress()
+		mzi.On("TransmissionLookup", md, zigbee.ProfileHomeAutomation).Return(ieee, zigbee.Endpoint(0x01), false, 1)
+
+		mzc.On("Request", mock.Anything, ieee, false, mock.MatchedBy(func(actualMsg zcl.Message) bool {
+			return actualMsg.CommandIdentifier == onoff.ToggleId
+		})).Return(nil)
+
+		err := i.Toggle(context.TODO())
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns an error and does not update state if the command fails", func(t *testing.T) {
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+		mzi.On("ZCLRegister", mock.Anything)
+		mzi.On("ZCLCommunicator").Return(mzc)
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		i := NewOnOff(mzi)
+		i.d = md
+		i.s = memory.New()
+
+		ieee := zigbee.GenerateLocalAdministeredIEEEAddress()
+		mzi.On("TransmissionLookup", md, zigbee.ProfileHomeAutomation).Return(ieee, zigbee.Endpoint(0x01), false, 1)
+		mzc.On("Request", mock.Anything, ieee, false, mock.Anything).Return(io.EOF)
+
+		err := i.On(context.TODO())
+		assert.Error(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.False(t, state)
+	})
+}
+
+func TestImplementation_GroupCommand(t *testing.T) {
+	t.Run("updates the state from commands multicast to the devices groups", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzi.On("SendEvent", mock.Anything).Times(3)
+
+		i := &Implementation{zi: mzi}
+		i.s = memory.New()
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.OnOffId, Command: &onoff.On{}})
+		state, _ := i.Status(context.TODO())
+		assert.True(t, state)
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.OnOffId, Command: &onoff.Toggle{}})
+		state, _ = i.Status(context.TODO())
+		assert.False(t, state)
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.OnOffId, Command: &onoff.Off{}})
+		state, _ = i.Status(context.TODO())
+		assert.False(t, state)
+
+		i.GroupCommand(zcl.Message{ClusterID: zcl.OnOffId, Command: &onoff.On{}})
+		state, _ = i.Status(context.TODO())
+		assert.True(t, state)
+	})
+}
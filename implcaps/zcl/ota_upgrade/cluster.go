@@ -0,0 +1,93 @@
+package ota_upgrade
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the OTA Upgrade cluster, the subset required is defined here. */
+
+const (
+	ImageNotifyId            = zcl.CommandIdentifier(0x00)
+	QueryNextImageRequestId  = zcl.CommandIdentifier(0x01)
+	QueryNextImageResponseId = zcl.CommandIdentifier(0x02)
+	ImageBlockRequestId      = zcl.CommandIdentifier(0x03)
+	ImageBlockResponseId     = zcl.CommandIdentifier(0x05)
+	UpgradeEndRequestId      = zcl.CommandIdentifier(0x06)
+	UpgradeEndResponseId     = zcl.CommandIdentifier(0x07)
+)
+
+const (
+	StatusSuccess          = uint8(0x00)
+	StatusAbort            = uint8(0x95)
+	StatusNoImageAvailable = uint8(0x98)
+)
+
+type ImageNotify struct {
+	PayloadType uint8
+	QueryJitter uint8
+}
+
+type QueryNextImageRequest struct {
+	Reserved               uint8 `bcfieldwidth:"7"`
+	HardwareVersionPresent bool  `bcfieldwidth:"1"`
+	ManufacturerCode       uint16
+	ImageType              uint16
+	CurrentFileVersion     uint32
+	HardwareVersion        uint16 `bcincludeif:"HardwareVersionPresent"`
+}
+
+type QueryNextImageResponse struct {
+	Status           uint8
+	ManufacturerCode uint16 `bcincludeif:"Status==0"`
+	ImageType        uint16 `bcincludeif:"Status==0"`
+	FileVersion      uint32 `bcincludeif:"Status==0"`
+	ImageSize        uint32 `bcincludeif:"Status==0"`
+}
+
+type ImageBlockRequest struct {
+	Reserved                  uint8 `bcfieldwidth:"6"`
+	MinimumBlockPeriodPresent bool  `bcfieldwidth:"1"`
+	RequestNodeAddressPresent bool  `bcfieldwidth:"1"`
+	ManufacturerCode          uint16
+	ImageType                 uint16
+	FileVersion               uint32
+	FileOffset                uint32
+	MaximumDataSize           uint8
+	RequestNodeAddress        zigbee.IEEEAddress `bcincludeif:"RequestNodeAddressPresent"`
+	MinimumBlockPeriod        uint16             `bcincludeif:"MinimumBlockPeriodPresent"`
+}
+
+type ImageBlockResponse struct {
+	Status           uint8
+	ManufacturerCode uint16 `bcincludeif:"Status==0"`
+	ImageType        uint16 `bcincludeif:"Status==0"`
+	FileVersion      uint32 `bcincludeif:"Status==0"`
+	FileOffset       uint32 `bcincludeif:"Status==0"`
+	ImageData        []byte `bcincludeif:"Status==0" bcsliceprefix:"8"`
+}
+
+type UpgradeEndRequest struct {
+	Status           uint8
+	ManufacturerCode uint16
+	ImageType        uint16
+	FileVersion      uint32
+}
+
+type UpgradeEndResponse struct {
+	ManufacturerCode uint16
+	ImageType        uint16
+	FileVersion      uint32
+	CurrentTime      uint32
+	UpgradeTime      uint32
+}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ServerToClient, ImageNotifyId, &ImageNotify{})
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ClientToServer, QueryNextImageRequestId, &QueryNextImageRequest{})
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ServerToClient, QueryNextImageResponseId, &QueryNextImageResponse{})
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ClientToServer, ImageBlockRequestId, &ImageBlockRequest{})
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ServerToClient, ImageBlockResponseId, &ImageBlockResponse{})
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ClientToServer, UpgradeEndRequestId, &UpgradeEndRequest{})
+	cr.RegisterLocal(zcl.OTAUpgradeId, zigbee.NoManufacturer, zcl.ServerToClient, UpgradeEndResponseId, &UpgradeEndResponse{})
+}
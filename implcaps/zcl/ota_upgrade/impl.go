@@ -0,0 +1,426 @@
+package ota_upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/ota"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ extcaps.OTAUpgrade = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	StatusKey           = "Status"
+	CurrentVersionKey   = "CurrentVersion"
+	TargetVersionKey    = "TargetVersion"
+	OffsetKey           = "Offset"
+	SizeKey             = "Size"
+	ManufacturerCodeKey = "ManufacturerCode"
+	ImageTypeKey        = "ImageType"
+	ImageReferenceKey   = "ImageReference"
+)
+
+// MaximumBlockSize is the largest image block sent to a device, regardless of its requested maximum, so that blocks
+// fit within a single unfragmented APS frame.
+const MaximumBlockSize = uint8(64)
+
+// DefaultQueryJitter is sent with Image Notify, at its maximum value every device notified will query for an image.
+const DefaultQueryJitter = uint8(100)
+
+var ErrNoImageStore = errors.New("gateway has no ota image store")
+
+func NewOTAUpgrade(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), matchMutex: &sync.Mutex{}, stateMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	ieeeAddress    zigbee.IEEEAddress
+	remoteEndpoint zigbee.Endpoint
+
+	matchMutex *sync.Mutex
+	match      *communicator.Match
+
+	stateMutex *sync.Mutex
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.OTAUpgradeFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.OTAUpgradeFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("ota upgrade missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.attachMatch()
+
+	return true, nil
+}
+
+func (i *Implementation) Enumerate(_ context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.attachMatch()
+
+	return true, nil
+}
+
+func (i *Implementation) attachMatch() {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+	}
+
+	i.ieeeAddress, _, _, _ = i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	m := communicator.NewMatch(i.zclFilter, i.zclMessage)
+	i.match = &m
+	i.zi.ZCLCommunicator().RegisterMatch(m)
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+		i.match = nil
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLOTAUpgrade"
+}
+
+func (i *Implementation) zclFilter(a zigbee.IEEEAddress, _ zigbee.ApplicationMessage, m zcl.Message) bool {
+	return a == i.ieeeAddress &&
+		m.SourceEndpoint == i.remoteEndpoint &&
+		m.Direction == zcl.ClientToServer &&
+		m.ClusterID == zcl.OTAUpgradeId
+}
+
+func (i *Implementation) zclMessage(m communicator.MessageWithSource) {
+	ctx := context.Background()
+
+	var err error
+
+	switch cmd := m.Message.Command.(type) {
+	case *QueryNextImageRequest:
+		err = i.queryNextImage(ctx, m.Message, cmd)
+	case *ImageBlockRequest:
+		err = i.imageBlock(ctx, m.Message, cmd)
+	case *UpgradeEndRequest:
+		err = i.upgradeEnd(ctx, m.Message, cmd)
+	}
+
+	if err != nil {
+		i.logger.Error(ctx, "Failed to respond to ota upgrade request.", logwrap.Err(err))
+	}
+}
+
+func (i *Implementation) queryNextImage(ctx context.Context, req zcl.Message, cmd *QueryNextImageRequest) error {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	i.s.Set(CurrentVersionKey, uint64(cmd.CurrentFileVersion))
+	i.s.Set(ManufacturerCodeKey, uint64(cmd.ManufacturerCode))
+	i.s.Set(ImageTypeKey, uint64(cmd.ImageType))
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	/* Images are only offered to devices which have been requested to upgrade. */
+	if status := i.status(); status != extcaps.OTARequested && status != extcaps.OTADownloading {
+		return i.respond(ctx, req, QueryNextImageResponseId, &QueryNextImageResponse{Status: StatusNoImageAvailable})
+	}
+
+	image, found, err := i.latestImage(ctx, cmd.ManufacturerCode, cmd.ImageType)
+	if err != nil {
+		i.logger.Warn(ctx, "Failed to find ota image for device.", logwrap.Err(err))
+	}
+
+	if !found || image.FileVersion <= cmd.CurrentFileVersion {
+		i.setStatus(extcaps.OTAUpToDate)
+		return i.respond(ctx, req, QueryNextImageResponseId, &QueryNextImageResponse{Status: StatusNoImageAvailable})
+	}
+
+	i.s.Set(TargetVersionKey, uint64(image.FileVersion))
+	i.s.Set(SizeKey, uint64(image.TotalImageSize))
+	i.s.Set(OffsetKey, uint64(0))
+	i.s.Set(ImageReferenceKey, image.Reference)
+	i.setStatus(extcaps.OTADownloading)
+
+	return i.respond(ctx, req, QueryNextImageResponseId, &QueryNextImageResponse{
+		Status:           StatusSuccess,
+		ManufacturerCode: image.ManufacturerCode,
+		ImageType:        image.ImageType,
+		FileVersion:      image.FileVersion,
+		ImageSize:        image.TotalImageSize,
+	})
+}
+
+func (i *Implementation) imageBlock(ctx context.Context, req zcl.Message, cmd *ImageBlockRequest) error {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	image := i.currentImage()
+
+	/* Aborting informs the device to stop requesting blocks, this is how a cancelled upgrade is stopped. */
+	if i.status() != extcaps.OTADownloading || cmd.ManufacturerCode != image.ManufacturerCode || cmd.ImageType != image.ImageType || cmd.FileVersion != image.FileVersion {
+		return i.respond(ctx, req, ImageBlockResponseId, &ImageBlockResponse{Status: StatusAbort})
+	}
+
+	size := min(cmd.MaximumDataSize, MaximumBlockSize)
+
+	data, err := i.readImage(ctx, image, cmd.FileOffset, int(size))
+	if err != nil {
+		i.logger.Warn(ctx, "Failed to read ota image block, aborting upgrade.", logwrap.Datum("Offset", cmd.FileOffset), logwrap.Err(err))
+		i.setStatus(extcaps.OTAFailed)
+		return i.respond(ctx, req, ImageBlockResponseId, &ImageBlockResponse{Status: StatusAbort})
+	}
+
+	i.setOffset(cmd.FileOffset + uint32(len(data)))
+
+	return i.respond(ctx, req, ImageBlockResponseId, &ImageBlockResponse{
+		Status:           StatusSuccess,
+		ManufacturerCode: image.ManufacturerCode,
+		ImageType:        image.ImageType,
+		FileVersion:      image.FileVersion,
+		FileOffset:       cmd.FileOffset,
+		ImageData:        data,
+	})
+}
+
+func (i *Implementation) upgradeEnd(ctx context.Context, req zcl.Message, cmd *UpgradeEndRequest) error {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	/* A device ending an upgrade which is not in progress, such as one which has been cancelled, is told to abort. */
+	if i.status() != extcaps.OTADownloading {
+		return i.defaultResponse(ctx, req, StatusAbort)
+	}
+
+	/* A failed download is acknowledged with its status, the device will then resume normal operation. */
+	if cmd.Status != StatusSuccess {
+		i.logger.Warn(ctx, "Device failed to download ota image.", logwrap.Datum("Status", cmd.Status))
+		i.setStatus(extcaps.OTAFailed)
+		return i.defaultResponse(ctx, req, cmd.Status)
+	}
+
+	i.setStatus(extcaps.OTAComplete)
+
+	/* A current time and upgrade time of zero instructs the device to apply the upgrade immediately. */
+	return i.respond(ctx, req, UpgradeEndResponseId, &UpgradeEndResponse{
+		ManufacturerCode: cmd.ManufacturerCode,
+		ImageType:        cmd.ImageType,
+		FileVersion:      cmd.FileVersion,
+	})
+}
+
+func (i *Implementation) respond(ctx context.Context, req zcl.Message, id zcl.CommandIdentifier, cmd any) error {
+	ieee, localEndpoint, ack, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ServerToClient,
+		TransactionSequence: req.TransactionSequence,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.OTAUpgradeId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: req.SourceEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+}
+
+// defaultResponse replies to a request with a ZCL Default Response, used where the cluster defines no response.
+func (i *Implementation) defaultResponse(ctx context.Context, req zcl.Message, status uint8) error {
+	ieee, localEndpoint, ack, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameGlobal,
+		Direction:           zcl.ServerToClient,
+		TransactionSequence: req.TransactionSequence,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.OTAUpgradeId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: req.SourceEndpoint,
+		CommandIdentifier:   global.DefaultResponseID,
+		Command:             &global.DefaultResponse{CommandIdentifier: uint8(req.CommandIdentifier), Status: status},
+	})
+}
+
+func (i *Implementation) latestImage(ctx context.Context, manufacturer uint16, imageType uint16) (ota.Image, bool, error) {
+	store := i.zi.OTAImageStore()
+	if store == nil {
+		return ota.Image{}, false, ErrNoImageStore
+	}
+
+	return store.Latest(ctx, manufacturer, imageType)
+}
+
+func (i *Implementation) readImage(ctx context.Context, image ota.Image, offset uint32, size int) ([]byte, error) {
+	store := i.zi.OTAImageStore()
+	if store == nil {
+		return nil, ErrNoImageStore
+	}
+
+	return store.Read(ctx, image, offset, size)
+}
+
+func (i *Implementation) currentImage() ota.Image {
+	manufacturer, _ := i.s.UInt(ManufacturerCodeKey)
+	imageType, _ := i.s.UInt(ImageTypeKey)
+	version, _ := i.s.UInt(TargetVersionKey)
+	size, _ := i.s.UInt(SizeKey)
+	reference, _ := i.s.String(ImageReferenceKey)
+
+	return ota.Image{
+		Header: ota.Header{
+			ManufacturerCode: uint16(manufacturer),
+			ImageType:        uint16(imageType),
+			FileVersion:      uint32(version),
+			TotalImageSize:   uint32(size),
+		},
+		Reference: reference,
+	}
+}
+
+func (i *Implementation) status() extcaps.OTAUpgradeStatus {
+	status, _ := i.s.UInt(StatusKey)
+	return extcaps.OTAUpgradeStatus(status)
+}
+
+func (i *Implementation) setStatus(status extcaps.OTAUpgradeStatus) {
+	i.s.Set(StatusKey, uint64(status))
+	converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+	i.zi.SendEvent(extcaps.OTAUpgradeUpdate{Device: i.d, State: i.state()})
+}
+
+func (i *Implementation) setOffset(offset uint32) {
+	previous := i.state()
+	i.s.Set(OffsetKey, uint64(offset))
+	current := i.state()
+
+	/* Progress events are limited to each whole percent, rather than every block. */
+	if int(previous.Progress()*100) != int(current.Progress()*100) {
+		i.zi.SendEvent(extcaps.OTAUpgradeUpdate{Device: i.d, State: current})
+	}
+}
+
+func (i *Implementation) state() extcaps.OTAUpgradeState {
+	current, _ := i.s.UInt(CurrentVersionKey)
+	target, _ := i.s.UInt(TargetVersionKey)
+	offset, _ := i.s.UInt(OffsetKey)
+	size, _ := i.s.UInt(SizeKey)
+
+	return extcaps.OTAUpgradeState{
+		Status:         i.status(),
+		CurrentVersion: uint32(current),
+		TargetVersion:  uint32(target),
+		Offset:         uint32(offset),
+		Size:           uint32(size),
+	}
+}
+
+// Start marks the device as requested to upgrade, and notifies the device that an image may be available. Devices
+// which are asleep will begin the upgrade when they next periodically query for an image.
+func (i *Implementation) Start(ctx context.Context) error {
+	if i.zi.OTAImageStore() == nil {
+		return ErrNoImageStore
+	}
+
+	i.stateMutex.Lock()
+	i.s.Set(OffsetKey, uint64(0))
+	i.setStatus(extcaps.OTARequested)
+	i.stateMutex.Unlock()
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if err := i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ServerToClient,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.OTAUpgradeId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   ImageNotifyId,
+		Command:             &ImageNotify{PayloadType: 0x00, QueryJitter: DefaultQueryJitter},
+	}); err != nil {
+		i.logger.Warn(ctx, "Failed to send image notify, upgrade will start upon the devices next query.", logwrap.Err(err))
+	}
+
+	return nil
+}
+
+// Cancel stops any upgrade in progress, the device is told to abort upon requesting its next image block.
+func (i *Implementation) Cancel(_ context.Context) error {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	if status := i.status(); status == extcaps.OTARequested || status == extcaps.OTADownloading {
+		i.setStatus(extcaps.OTACancelled)
+	}
+
+	return nil
+}
+
+func (i *Implementation) Status(_ context.Context) (extcaps.OTAUpgradeState, error) {
+	i.stateMutex.Lock()
+	defer i.stateMutex.Unlock()
+
+	return i.state(), nil
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
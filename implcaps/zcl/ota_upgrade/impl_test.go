@@ -0,0 +1,368 @@
+package ota_upgrade
+
+import (
+	"context"
+	"github.com/shimmeringbee/bytecodec"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zda/ota"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator, *ota.MockImageStore) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mis := &ota.MockImageStore{}
+	t.Cleanup(func() { mis.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("OTAImageStore").Return(mis).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewOTAUpgrade(mzi)
+	i.Init(nil, memory.New())
+	i.remoteEndpoint = 4
+
+	return i, mzi, mzc, mis
+}
+
+func expectResponse(mzc *mocks.MockZCLCommunicator, id zcl.CommandIdentifier, cmd any) {
+	mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ServerToClient,
+		TransactionSequence: 9,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.OTAUpgradeId,
+		SourceEndpoint:      2,
+		DestinationEndpoint: 4,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	}).Return(nil).Once()
+}
+
+func request(cmd any) communicator.MessageWithSource {
+	return communicator.MessageWithSource{Message: zcl.Message{TransactionSequence: 9, SourceEndpoint: 4, Command: cmd}}
+}
+
+func expectDefaultResponse(mzc *mocks.MockZCLCommunicator, status uint8) {
+	mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+		FrameType:           zcl.FrameGlobal,
+		Direction:           zcl.ServerToClient,
+		TransactionSequence: 9,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.OTAUpgradeId,
+		SourceEndpoint:      2,
+		DestinationEndpoint: 4,
+		CommandIdentifier:   global.DefaultResponseID,
+		Command:             &global.DefaultResponse{CommandIdentifier: uint8(UpgradeEndRequestId), Status: status},
+	}).Return(nil).Once()
+}
+
+func upgradeEndRequest(status uint8) communicator.MessageWithSource {
+	m := request(&UpgradeEndRequest{Status: status, ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20})
+	m.Message.CommandIdentifier = UpgradeEndRequestId
+	return m
+}
+
+var testImage = ota.Image{
+	Header:    ota.Header{ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20, TotalImageSize: 200},
+	Reference: "test.ota",
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewOTAUpgrade(newMockZDAInterface(t))
+
+		assert.Equal(t, extcaps.OTAUpgradeFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.OTAUpgradeFlag], i.Name())
+		assert.Equal(t, "ZCLOTAUpgrade", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("registers the match, returning true if successful", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.remoteEndpoint)
+		assert.Equal(t, zigbee.IEEEAddress(1), i.ieeeAddress)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("stores the endpoint and registers the match", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		ep, _ := i.s.Int(implcaps.RemoteEndpointKey)
+		assert.Equal(t, int64(5), ep)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("unregisters the match", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+
+		i.attachMatch()
+
+		err := i.Detach(context.TODO(), implcaps.DeviceRemoved)
+		assert.NoError(t, err)
+		assert.Nil(t, i.match)
+	})
+}
+
+func TestImplementation_zclFilter(t *testing.T) {
+	t.Run("only accepts client to server messages from the device's ota upgrade cluster", func(t *testing.T) {
+		i, mzi, _, _ := newImplementation(t)
+		i.ieeeAddress = 1
+
+		valid := zcl.Message{SourceEndpoint: 4, Direction: zcl.ClientToServer, ClusterID: zcl.OTAUpgradeId}
+		assert.True(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, valid))
+
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(2), zigbee.ApplicationMessage{}, valid))
+
+		wrongCluster := valid
+		wrongCluster.ClusterID = zcl.OnOffId
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongCluster))
+
+		mzi.AssertNotCalled(t, "TransmissionLookup", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImplementation_Start(t *testing.T) {
+	t.Run("marks the upgrade as requested and notifies the device", func(t *testing.T) {
+		i, mzi, mzc, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.OTAUpgradeUpdate{State: extcaps.OTAUpgradeState{Status: extcaps.OTARequested}}).Once()
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, mock.MatchedBy(func(m zcl.Message) bool {
+			return m.CommandIdentifier == ImageNotifyId && m.DestinationEndpoint == 4 && m.Direction == zcl.ServerToClient
+		})).Return(io.EOF)
+
+		err := i.Start(context.TODO())
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTARequested, state.Status)
+	})
+
+	t.Run("fails if the gateway has no image store", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		mzi.On("OTAImageStore").Return(nil)
+
+		i := NewOTAUpgrade(mzi)
+		i.Init(nil, memory.New())
+
+		err := i.Start(context.TODO())
+		assert.ErrorIs(t, err, ErrNoImageStore)
+	})
+}
+
+func TestImplementation_Cancel(t *testing.T) {
+	t.Run("cancels an upgrade in progress", func(t *testing.T) {
+		i, mzi, _, _ := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTADownloading))
+
+		mzi.On("SendEvent", mock.Anything).Once()
+
+		err := i.Cancel(context.TODO())
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTACancelled, state.Status)
+	})
+
+	t.Run("does nothing if no upgrade is in progress", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+
+		err := i.Cancel(context.TODO())
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTAIdle, state.Status)
+	})
+}
+
+func TestImplementation_queryNextImage(t *testing.T) {
+	t.Run("responds with no image available if an upgrade has not been requested", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+
+		expectResponse(mzc, QueryNextImageResponseId, &QueryNextImageResponse{Status: StatusNoImageAvailable})
+
+		i.zclMessage(request(&QueryNextImageRequest{ManufacturerCode: 0x1234, ImageType: 0x0001, CurrentFileVersion: 0x10}))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, uint32(0x10), state.CurrentVersion)
+	})
+
+	t.Run("offers a newer image if an upgrade has been requested", func(t *testing.T) {
+		i, mzi, mzc, mis := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTARequested))
+
+		mis.On("Latest", mock.Anything, uint16(0x1234), uint16(0x0001)).Return(testImage, true, nil)
+		mzi.On("SendEvent", mock.Anything).Once()
+		expectResponse(mzc, QueryNextImageResponseId, &QueryNextImageResponse{Status: StatusSuccess, ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20, ImageSize: 200})
+
+		i.zclMessage(request(&QueryNextImageRequest{ManufacturerCode: 0x1234, ImageType: 0x0001, CurrentFileVersion: 0x10}))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTAUpgradeState{Status: extcaps.OTADownloading, CurrentVersion: 0x10, TargetVersion: 0x20, Size: 200}, state)
+	})
+
+	t.Run("marks the device up to date if no newer image is available", func(t *testing.T) {
+		i, mzi, mzc, mis := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTARequested))
+
+		mis.On("Latest", mock.Anything, uint16(0x1234), uint16(0x0001)).Return(testImage, true, nil)
+		mzi.On("SendEvent", mock.Anything).Once()
+		expectResponse(mzc, QueryNextImageResponseId, &QueryNextImageResponse{Status: StatusNoImageAvailable})
+
+		i.zclMessage(request(&QueryNextImageRequest{ManufacturerCode: 0x1234, ImageType: 0x0001, CurrentFileVersion: 0x20}))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTAUpToDate, state.Status)
+	})
+}
+
+func TestImplementation_imageBlock(t *testing.T) {
+	t.Run("serves a block of the image limited to the maximum block size, updating progress", func(t *testing.T) {
+		i, mzi, mzc, mis := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTADownloading))
+		i.s.Set(ManufacturerCodeKey, uint64(0x1234))
+		i.s.Set(ImageTypeKey, uint64(0x0001))
+		i.s.Set(TargetVersionKey, uint64(0x20))
+		i.s.Set(SizeKey, uint64(200))
+		i.s.Set(ImageReferenceKey, "test.ota")
+
+		data := make([]byte, MaximumBlockSize)
+
+		mis.On("Read", mock.Anything, testImage, uint32(100), int(MaximumBlockSize)).Return(data, nil)
+		mzi.On("SendEvent", mock.Anything).Once()
+		expectResponse(mzc, ImageBlockResponseId, &ImageBlockResponse{Status: StatusSuccess, ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20, FileOffset: 100, ImageData: data})
+
+		i.zclMessage(request(&ImageBlockRequest{ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20, FileOffset: 100, MaximumDataSize: 0xff}))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, uint32(164), state.Offset)
+	})
+
+	t.Run("aborts the download if the upgrade has been cancelled", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTACancelled))
+
+		expectResponse(mzc, ImageBlockResponseId, &ImageBlockResponse{Status: StatusAbort})
+
+		i.zclMessage(request(&ImageBlockRequest{ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20, MaximumDataSize: 0x40}))
+	})
+}
+
+func TestImplementation_upgradeEnd(t *testing.T) {
+	t.Run("instructs the device to apply the upgrade immediately", func(t *testing.T) {
+		i, mzi, mzc, _ := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTADownloading))
+
+		mzi.On("SendEvent", mock.Anything).Once()
+		expectResponse(mzc, UpgradeEndResponseId, &UpgradeEndResponse{ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20})
+
+		i.zclMessage(request(&UpgradeEndRequest{Status: StatusSuccess, ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20}))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTAComplete, state.Status)
+	})
+
+	t.Run("marks the upgrade failed if the device reports an error, acknowledging with a default response", func(t *testing.T) {
+		i, mzi, mzc, _ := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTADownloading))
+
+		mzi.On("SendEvent", mock.Anything).Once()
+		expectDefaultResponse(mzc, 0x96)
+
+		i.zclMessage(upgradeEndRequest(0x96))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTAFailed, state.Status)
+	})
+
+	t.Run("tells the device to abort if no upgrade is in progress", func(t *testing.T) {
+		i, _, mzc, _ := newImplementation(t)
+		i.s.Set(StatusKey, uint64(extcaps.OTACancelled))
+
+		expectDefaultResponse(mzc, StatusAbort)
+
+		i.zclMessage(upgradeEndRequest(StatusSuccess))
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.OTACancelled, state.Status)
+	})
+}
+
+func TestCluster_Encoding(t *testing.T) {
+	t.Run("image block request decodes optional fields based upon field control", func(t *testing.T) {
+		req := ImageBlockRequest{}
+
+		err := bytecodec.Unmarshal([]byte{0x02, 0x34, 0x12, 0x01, 0x00, 0x20, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x40, 0xe8, 0x03}, &req)
+		assert.NoError(t, err)
+
+		assert.True(t, req.MinimumBlockPeriodPresent)
+		assert.False(t, req.RequestNodeAddressPresent)
+		assert.Equal(t, uint32(0x10), req.FileOffset)
+		assert.Equal(t, uint8(0x40), req.MaximumDataSize)
+		assert.Equal(t, uint16(1000), req.MinimumBlockPeriod)
+	})
+
+	t.Run("responses only include image details upon success", func(t *testing.T) {
+		data, err := bytecodec.Marshal(&QueryNextImageResponse{Status: StatusNoImageAvailable})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{StatusNoImageAvailable}, data)
+
+		data, err = bytecodec.Marshal(&ImageBlockResponse{Status: StatusSuccess, ManufacturerCode: 0x1234, ImageType: 0x0001, FileVersion: 0x20, FileOffset: 0x10, ImageData: []byte{0xaa, 0xbb}})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x00, 0x34, 0x12, 0x01, 0x00, 0x20, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x02, 0xaa, 0xbb}, data)
+	})
+}
@@ -0,0 +1,133 @@
+package particulate_matter_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ extcaps.ParticulateMatterSensor = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+func NewParticulateMatterSensor(zi implcaps.ZDAInterface) *Implementation {
+	return &Implementation{zi: zi}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	am attribute.Monitor
+	zi implcaps.ZDAInterface
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.ParticulateMatterSensorFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.ParticulateMatterSensorFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.am = i.zi.NewAttributeMonitor()
+	i.am.Init(s.Section("AttributeMonitor", "ParticulateMatterReading"), d, i.update)
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if err := i.am.Load(ctx); err != nil {
+		return false, err
+	} else {
+		return true, nil
+	}
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	endpoint := implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	reporting := attribute.ReportingConfig{
+		Mode:             attribute.AttemptConfigureReporting,
+		MinimumInterval:  1 * time.Minute,
+		MaximumInterval:  5 * time.Minute,
+		ReportableChange: 1.0,
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	if err := i.am.Attach(ctx, endpoint, zcl.AirConcentrationPM25Id, implcaps.MeasuredValue, zcl.TypeFloatSingle, reporting, polling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	if err := i.am.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLParticulateMatterSensor"
+}
+
+func (i *Implementation) update(_ zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType == zcl.TypeFloatSingle {
+		if value, ok := v.Value.(float32); ok {
+			/* An invalid measurement is reported as NaN. */
+			if math.IsNaN(float64(value)) {
+				return
+			}
+
+			newValue := float64(value)
+			currentValue, _ := i.s.Float(implcaps.ReadingKey)
+
+			if math.Abs(newValue-currentValue) > 0.1 {
+				i.s.Set(implcaps.ReadingKey, newValue)
+				converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+				i.zi.SendEvent(extcaps.ParticulateMatterSensorUpdate{Device: i.d, State: []extcaps.ParticulateMatterReading{{Value: newValue}}})
+			}
+
+			converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+		}
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Reading(_ context.Context) ([]extcaps.ParticulateMatterReading, error) {
+	v, _ := i.s.Float(implcaps.ReadingKey)
+
+	return []extcaps.ParticulateMatterReading{
+		{
+			Value: v,
+		},
+	}, nil
+}
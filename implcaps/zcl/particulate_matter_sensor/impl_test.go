@@ -0,0 +1,107 @@
+package particulate_matter_sensor
+
+import (
+	"context"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches to the attribute monitor, reporting changes of 1 µg/m³ as a floating point value", func(t *testing.T) {
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(0x01), zcl.AirConcentrationPM25Id, implcaps.MeasuredValue, zcl.TypeFloatSingle, mock.MatchedBy(func(rc attribute.ReportingConfig) bool {
+			return rc.ReportableChange == 1.0
+		}), mock.Anything).Return(nil)
+
+		i := NewParticulateMatterSensor(nil)
+		i.am = mm
+		attached, err := i.Enumerate(context.TODO(), make(map[string]any))
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates the state correctly, sending event if change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(extcaps.ParticulateMatterSensorUpdate)
+			assert.True(t, ok)
+			assert.InEpsilon(t, 12.5, e.State[0].Value, 0.001)
+		})
+
+		i := NewParticulateMatterSensor(mzi)
+		i.s = memory.New()
+
+		lastUpdated := time.Now().Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeFloatSingle,
+			Value:    float32(12.5),
+		})
+
+		v, _ := i.Reading(context.TODO())
+		assert.InEpsilon(t, 12.5, v[0].Value, 0.001)
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Greater(t, lct, lastUpdated)
+	})
+
+	t.Run("updates the state correctly, no event if no change", func(t *testing.T) {
+		mzi := &implcaps.MockZDAInterface{}
+		defer mzi.AssertExpectations(t)
+
+		i := NewParticulateMatterSensor(mzi)
+		i.s = memory.New()
+
+		i.s.Set(implcaps.ReadingKey, 12.5)
+
+		lastUpdated := time.UnixMilli(time.Now().UnixMilli()).Add(-5 * time.Minute)
+		converter.Store(i.s, implcaps.LastUpdatedKey, lastUpdated, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, lastUpdated, converter.TimeEncoder)
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeFloatSingle,
+			Value:    float32(12.5),
+		})
+
+		lut, _ := i.LastUpdateTime(context.TODO())
+		assert.Greater(t, lut, lastUpdated)
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.Equal(t, lct, lastUpdated)
+	})
+
+	t.Run("ignores invalid measurements", func(t *testing.T) {
+		i := NewParticulateMatterSensor(nil)
+		i.s = memory.New()
+
+		i.update(0, zcl.AttributeDataTypeValue{
+			DataType: zcl.TypeFloatSingle,
+			Value:    float32(math.NaN()),
+		})
+
+		_, found := i.s.Float(implcaps.ReadingKey)
+		assert.False(t, found)
+	})
+}
@@ -0,0 +1,37 @@
+package poll_control
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the Poll Control cluster, the subset required is defined here. */
+
+const (
+	CheckInInterval   = zcl.AttributeID(0x0000)
+	LongPollInterval  = zcl.AttributeID(0x0001)
+	ShortPollInterval = zcl.AttributeID(0x0002)
+	FastPollTimeout   = zcl.AttributeID(0x0003)
+)
+
+const (
+	CheckInId         = zcl.CommandIdentifier(0x00)
+	CheckInResponseId = zcl.CommandIdentifier(0x00)
+	FastPollStopId    = zcl.CommandIdentifier(0x01)
+)
+
+type CheckIn struct{}
+
+type CheckInResponse struct {
+	StartFastPolling bool
+	// FastPollTimeout is in quarter seconds, zero requests the device use its own FastPollTimeout attribute.
+	FastPollTimeout uint16
+}
+
+type FastPollStop struct{}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.PollControlId, zigbee.NoManufacturer, zcl.ServerToClient, CheckInId, &CheckIn{})
+	cr.RegisterLocal(zcl.PollControlId, zigbee.NoManufacturer, zcl.ClientToServer, CheckInResponseId, &CheckInResponse{})
+	cr.RegisterLocal(zcl.PollControlId, zigbee.NoManufacturer, zcl.ClientToServer, FastPollStopId, &FastPollStop{})
+}
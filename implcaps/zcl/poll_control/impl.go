@@ -0,0 +1,266 @@
+package poll_control
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ extcaps.PollControl = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	CheckInIntervalKey = "CheckInInterval"
+)
+
+// DefaultFastPollTimeout is how long a device is asked to fast poll for after checking in with commands queued, it
+// is stopped early by Fast Poll Stop once the queue has been flushed.
+const DefaultFastPollTimeout = 10 * time.Second
+
+const quarterSecond = time.Second / 4
+
+func NewPollControl(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), matchMutex: &sync.Mutex{}, checkInMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	ieeeAddress    zigbee.IEEEAddress
+	remoteEndpoint zigbee.Endpoint
+
+	matchMutex *sync.Mutex
+	match      *communicator.Match
+
+	checkInMutex *sync.Mutex
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.PollControlFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.PollControlFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("poll control missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.attachMatch()
+	i.queue().Enable(true)
+
+	return true, nil
+}
+
+// Enumerate binds the Poll Control cluster so check-ins are sent to the gateway. The check-in interval can be set
+// with the PollControlCheckInInterval setting in seconds, otherwise the device's own interval is kept.
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.attachMatch()
+
+	ieee, localEndpoint, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if err := i.zi.NodeBinder().BindNodeToController(ctx, ieee, localEndpoint, i.remoteEndpoint, zcl.PollControlId); err != nil {
+		i.logger.Warn(ctx, "Failed to bind poll control cluster to controller.", logwrap.Err(err))
+	}
+
+	if interval := time.Duration(implcaps.Get(m, "PollControlCheckInInterval", 0)) * time.Second; interval > 0 {
+		if err := i.writeCheckInInterval(ctx, interval); err != nil {
+			i.logger.Warn(ctx, "Failed to set poll control check in interval.", logwrap.Err(err))
+		}
+	} else if err := i.readCheckInInterval(ctx); err != nil {
+		i.logger.Warn(ctx, "Failed to read poll control check in interval.", logwrap.Err(err))
+	}
+
+	i.queue().Enable(true)
+
+	return true, nil
+}
+
+func (i *Implementation) writeCheckInInterval(ctx context.Context, interval time.Duration) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	quarters := uint64(interval / quarterSecond)
+
+	recs, err := i.zi.ZCLCommunicator().WriteAttributes(ctx, ieee, ack, zcl.PollControlId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+		CheckInInterval: {
+			DataType: zcl.TypeUnsignedInt32,
+			Value:    quarters,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if rec, found := communicator.WriteResponsesToMap(recs)[CheckInInterval]; found && rec.Status != 0 {
+		return fmt.Errorf("failed to write check in interval: status %d", rec.Status)
+	}
+
+	i.s.Set(CheckInIntervalKey, quarters)
+
+	return nil
+}
+
+func (i *Implementation) readCheckInInterval(ctx context.Context) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.PollControlId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{CheckInInterval})
+	if err != nil {
+		return err
+	}
+
+	if rec, found := communicator.ReadResponsesToMap(recs)[CheckInInterval]; found && rec.Status == 0 && rec.DataTypeValue != nil {
+		if v, ok := rec.DataTypeValue.Value.(uint64); ok {
+			i.s.Set(CheckInIntervalKey, v)
+		}
+	}
+
+	return nil
+}
+
+func (i *Implementation) attachMatch() {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+	}
+
+	i.ieeeAddress, _, _, _ = i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	m := communicator.NewMatch(i.zclFilter, i.zclMessage)
+	i.match = &m
+	i.zi.ZCLCommunicator().RegisterMatch(m)
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+		i.match = nil
+	}
+
+	/* Without Poll Control nothing will flush the queue, so messages must be sent immediately. */
+	i.queue().Enable(false)
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLPollControl"
+}
+
+func (i *Implementation) queue() implcaps.CommandQueue {
+	return i.zi.CommandQueue(i.ieeeAddress)
+}
+
+func (i *Implementation) zclFilter(a zigbee.IEEEAddress, _ zigbee.ApplicationMessage, m zcl.Message) bool {
+	return a == i.ieeeAddress &&
+		m.SourceEndpoint == i.remoteEndpoint &&
+		m.Direction == zcl.ServerToClient &&
+		m.ClusterID == zcl.PollControlId
+}
+
+func (i *Implementation) zclMessage(m communicator.MessageWithSource) {
+	if _, ok := m.Message.Command.(*CheckIn); ok {
+		if err := i.checkIn(context.Background(), m.Message); err != nil {
+			i.logger.Error(context.Background(), "Failed to respond to poll control check in.", logwrap.Err(err))
+		}
+	}
+}
+
+// checkIn responds to the device checking in, if commands are queued the device is asked to fast poll while the
+// queue is flushed, after which it is told to stop.
+func (i *Implementation) checkIn(ctx context.Context, req zcl.Message) error {
+	i.checkInMutex.Lock()
+	defer i.checkInMutex.Unlock()
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	messages := i.queue().Drain()
+	fastPoll := len(messages) > 0
+
+	ieee, localEndpoint, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	if err := i.zi.ZCLCommunicator().Request(ctx, ieee, false, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: req.TransactionSequence,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.PollControlId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   CheckInResponseId,
+		Command:             &CheckInResponse{StartFastPolling: fastPoll, FastPollTimeout: uint16(DefaultFastPollTimeout / quarterSecond)},
+	}); err != nil {
+		return err
+	}
+
+	if !fastPoll {
+		return nil
+	}
+
+	for _, msg := range messages {
+		if err := i.zi.ZCLCommunicator().Request(ctx, ieee, msg.RequireAck, msg.Message); err != nil {
+			i.logger.Warn(ctx, "Failed to send queued message to device.", logwrap.Datum("ClusterID", msg.Message.ClusterID), logwrap.Datum("CommandIdentifier", msg.Message.CommandIdentifier), logwrap.Err(err))
+		}
+	}
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.PollControlId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   FastPollStopId,
+		Command:             &FastPollStop{},
+	})
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) CheckInInterval(_ context.Context) (time.Duration, error) {
+	v, _ := i.s.UInt(CheckInIntervalKey)
+	return time.Duration(v) * quarterSecond, nil
+}
+
+func (i *Implementation) QueuedCommands(_ context.Context) (int, error) {
+	return i.queue().Len(), nil
+}
@@ -0,0 +1,242 @@
+package poll_control
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator, *implcaps.MockCommandQueue) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mcq := &implcaps.MockCommandQueue{}
+	t.Cleanup(func() { mcq.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("CommandQueue", zigbee.IEEEAddress(1)).Return(mcq).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewPollControl(mzi)
+	i.Init(nil, memory.New())
+	i.ieeeAddress = 1
+	i.remoteEndpoint = 4
+
+	return i, mzi, mzc, mcq
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewPollControl(newMockZDAInterface(t))
+
+		assert.Equal(t, extcaps.PollControlFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.PollControlFlag], i.Name())
+		assert.Equal(t, "ZCLPollControl", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("registers the match and enables the command queue, returning true if successful", func(t *testing.T) {
+		i, _, mzc, mcq := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mcq.On("Enable", true)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.remoteEndpoint)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("binds the cluster, writes the configured check in interval and enables the command queue", func(t *testing.T) {
+		i, mzi, mzc, mcq := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mcq.On("Enable", true)
+
+		mnb := &zigbee.MockProvider{}
+		defer mnb.AssertExpectations(t)
+
+		mzi.On("NodeBinder").Return(mnb)
+		mnb.On("BindNodeToController", mock.Anything, zigbee.IEEEAddress(1), zigbee.Endpoint(2), zigbee.Endpoint(5), zcl.PollControlId).Return(nil)
+
+		mzc.On("WriteAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.PollControlId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(5), uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			CheckInInterval: {DataType: zcl.TypeUnsignedInt32, Value: uint64(1200)},
+		}).Return([]global.WriteAttributesResponseRecord{{Identifier: CheckInInterval, Status: 0}}, nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5), "PollControlCheckInInterval": 300})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		interval, _ := i.CheckInInterval(context.TODO())
+		assert.Equal(t, 5*time.Minute, interval)
+	})
+
+	t.Run("reads the devices check in interval if not configured, failure to bind is not fatal", func(t *testing.T) {
+		i, mzi, mzc, mcq := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mcq.On("Enable", true)
+
+		mnb := &zigbee.MockProvider{}
+		defer mnb.AssertExpectations(t)
+
+		mzi.On("NodeBinder").Return(mnb)
+		mnb.On("BindNodeToController", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(io.EOF)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.PollControlId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(1), uint8(3), []zcl.AttributeID{CheckInInterval}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: CheckInInterval, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt32, Value: uint64(14400)}},
+		}, nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		interval, _ := i.CheckInInterval(context.TODO())
+		assert.Equal(t, time.Hour, interval)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("unregisters the match and disables the command queue", func(t *testing.T) {
+		i, _, mzc, mcq := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+		mcq.On("Enable", false)
+
+		i.attachMatch()
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+		assert.Nil(t, i.match)
+	})
+}
+
+func TestImplementation_zclFilter(t *testing.T) {
+	t.Run("only accepts server to client messages from the device's poll control cluster", func(t *testing.T) {
+		i, mzi, _, _ := newImplementation(t)
+
+		valid := zcl.Message{SourceEndpoint: 4, Direction: zcl.ServerToClient, ClusterID: zcl.PollControlId}
+		assert.True(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, valid))
+
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(2), zigbee.ApplicationMessage{}, valid))
+
+		wrongDirection := valid
+		wrongDirection.Direction = zcl.ClientToServer
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongDirection))
+
+		mzi.AssertNotCalled(t, "TransmissionLookup", mock.Anything, mock.Anything)
+	})
+}
+
+func checkInResponse(fastPoll bool) zcl.Message {
+	return zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: 9,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.PollControlId,
+		SourceEndpoint:      2,
+		DestinationEndpoint: 4,
+		CommandIdentifier:   CheckInResponseId,
+		Command:             &CheckInResponse{StartFastPolling: fastPoll, FastPollTimeout: 40},
+	}
+}
+
+func TestImplementation_checkIn(t *testing.T) {
+	t.Run("responds without fast polling if no commands are queued", func(t *testing.T) {
+		i, _, mzc, mcq := newImplementation(t)
+
+		mcq.On("Drain").Return([]implcaps.QueuedMessage(nil))
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, checkInResponse(false)).Return(nil).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{TransactionSequence: 9, Command: &CheckIn{}}})
+
+		updated, _ := i.LastUpdateTime(context.TODO())
+		assert.WithinDuration(t, time.Now(), updated, time.Second)
+	})
+
+	t.Run("requests fast polling, flushes the queue and then stops fast polling", func(t *testing.T) {
+		i, _, mzc, mcq := newImplementation(t)
+
+		queued := zcl.Message{ClusterID: zcl.OnOffId, CommandIdentifier: onoff.OnId, Command: &onoff.On{}}
+		mcq.On("Drain").Return([]implcaps.QueuedMessage{{RequireAck: true, Message: queued}})
+
+		var order []string
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, checkInResponse(true)).Return(nil).Once().Run(func(mock.Arguments) {
+			order = append(order, "CheckInResponse")
+		})
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), true, queued).Return(nil).Once().Run(func(mock.Arguments) {
+			order = append(order, "Queued")
+		})
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: 3,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.PollControlId,
+			SourceEndpoint:      2,
+			DestinationEndpoint: 4,
+			CommandIdentifier:   FastPollStopId,
+			Command:             &FastPollStop{},
+		}).Return(nil).Once().Run(func(mock.Arguments) {
+			order = append(order, "FastPollStop")
+		})
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{TransactionSequence: 9, Command: &CheckIn{}}})
+
+		assert.Equal(t, []string{"CheckInResponse", "Queued", "FastPollStop"}, order)
+	})
+}
+
+func TestImplementation_QueuedCommands(t *testing.T) {
+	t.Run("returns the length of the nodes command queue", func(t *testing.T) {
+		i, _, _, mcq := newImplementation(t)
+		mcq.On("Len").Return(2)
+
+		n, err := i.QueuedCommands(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+	})
+}
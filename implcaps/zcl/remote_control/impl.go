@@ -0,0 +1,252 @@
+package remote_control
+
+import (
+	"context"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/implcaps/zcl/scenes"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+var _ extcaps.BasicHumanInterfaceDevice = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const (
+	OnOffClusterPresentKey  = "OnOffClusterPresent"
+	LevelClusterPresentKey  = "LevelClusterPresent"
+	ScenesClusterPresentKey = "ScenesClusterPresent"
+)
+
+const (
+	levelModeUp   = uint8(0x00)
+	levelModeDown = uint8(0x01)
+)
+
+func NewRemoteControl(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(onoff.Register)
+	zi.ZCLRegister(level.Register)
+	zi.ZCLRegister(scenes.Register)
+	return &Implementation{zi: zi, logger: zi.Logger(), matchMutex: &sync.Mutex{}, heldMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s      persistence.Section
+	d      da.Device
+	zi     implcaps.ZDAInterface
+	logger logwrap.Logger
+
+	ieeeAddress    zigbee.IEEEAddress
+	remoteEndpoint zigbee.Endpoint
+
+	matchMutex *sync.Mutex
+	match      *communicator.Match
+
+	heldMutex *sync.Mutex
+	held      *extcaps.Button
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.BasicHumanInterfaceDeviceFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.BasicHumanInterfaceDeviceFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+}
+
+func (i *Implementation) Load(_ context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("remote control missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.attachMatch()
+
+	return true, nil
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+	i.s.Set(OnOffClusterPresentKey, implcaps.Get(m, "ZigbeeOnOffClusterPresent", false))
+	i.s.Set(LevelClusterPresentKey, implcaps.Get(m, "ZigbeeLevelClusterPresent", false))
+	i.s.Set(ScenesClusterPresentKey, implcaps.Get(m, "ZigbeeScenesClusterPresent", false))
+
+	i.attachMatch()
+
+	ieee, localEndpoint, _, _ := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	for _, cluster := range i.clusters() {
+		/* Remotes are often asleep, or send to groups regardless, so failing to bind is not fatal. */
+		if err := i.zi.NodeBinder().BindNodeToController(ctx, ieee, localEndpoint, i.remoteEndpoint, cluster); err != nil {
+			i.logger.Warn(ctx, "Failed to bind remote control cluster to controller.", logwrap.Datum("ClusterID", cluster), logwrap.Err(err))
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) attachMatch() {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+	}
+
+	i.ieeeAddress, _, _, _ = i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	m := communicator.NewMatch(i.zclFilter, i.zclMessage)
+	i.match = &m
+	i.zi.ZCLCommunicator().RegisterMatch(m)
+}
+
+func (i *Implementation) Detach(_ context.Context, _ implcaps.DetachType) error {
+	i.matchMutex.Lock()
+	defer i.matchMutex.Unlock()
+
+	if i.match != nil {
+		i.zi.ZCLCommunicator().UnregisterMatch(*i.match)
+		i.match = nil
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLRemoteControl"
+}
+
+func (i *Implementation) clusters() []zigbee.ClusterID {
+	var clusters []zigbee.ClusterID
+
+	if v, _ := i.s.Bool(OnOffClusterPresentKey); v {
+		clusters = append(clusters, zcl.OnOffId)
+	}
+
+	if v, _ := i.s.Bool(LevelClusterPresentKey); v {
+		clusters = append(clusters, zcl.LevelControlId)
+	}
+
+	if v, _ := i.s.Bool(ScenesClusterPresentKey); v {
+		clusters = append(clusters, zcl.ScenesId)
+	}
+
+	return clusters
+}
+
+func (i *Implementation) zclFilter(a zigbee.IEEEAddress, _ zigbee.ApplicationMessage, m zcl.Message) bool {
+	return a == i.ieeeAddress &&
+		m.SourceEndpoint == i.remoteEndpoint &&
+		m.Direction == zcl.ClientToServer &&
+		(m.ClusterID == zcl.OnOffId || m.ClusterID == zcl.LevelControlId || m.ClusterID == zcl.ScenesId)
+}
+
+func (i *Implementation) zclMessage(m communicator.MessageWithSource) {
+	switch cmd := m.Message.Command.(type) {
+	case *onoff.On, *onoff.OnWithRecallGlobalScene, *onoff.OnWithTimedOff:
+		i.action(extcaps.ButtonOn, extcaps.ButtonPressed, 0)
+	case *onoff.Off, *onoff.OffWithEffect:
+		i.action(extcaps.ButtonOff, extcaps.ButtonPressed, 0)
+	case *onoff.Toggle:
+		i.action(extcaps.ButtonToggle, extcaps.ButtonPressed, 0)
+	case *level.Step:
+		i.step(cmd.StepMode)
+	case *level.StepWithOnOff:
+		i.step(cmd.StepMode)
+	case *level.Move:
+		i.move(cmd.MoveMode)
+	case *level.MoveWithOnOff:
+		i.move(cmd.MoveMode)
+	case *level.Stop, *level.StopWithOnOff:
+		i.release()
+	case *scenes.RecallScene:
+		i.action(extcaps.ButtonScene, extcaps.ButtonPressed, cmd.SceneID)
+	}
+}
+
+func levelModeToButton(mode uint8) (extcaps.Button, bool) {
+	switch mode {
+	case levelModeUp:
+		return extcaps.ButtonUp, true
+	case levelModeDown:
+		return extcaps.ButtonDown, true
+	default:
+		return "", false
+	}
+}
+
+func (i *Implementation) step(mode uint8) {
+	if button, ok := levelModeToButton(mode); ok {
+		i.action(button, extcaps.ButtonPressed, 0)
+	}
+}
+
+func (i *Implementation) move(mode uint8) {
+	if button, ok := levelModeToButton(mode); ok {
+		i.heldMutex.Lock()
+		i.held = &button
+		i.heldMutex.Unlock()
+
+		i.action(button, extcaps.ButtonHeld, 0)
+	}
+}
+
+func (i *Implementation) release() {
+	i.heldMutex.Lock()
+	held := i.held
+	i.held = nil
+	i.heldMutex.Unlock()
+
+	/* Stop is only meaningful if a button has been held, otherwise the button being released is unknown. */
+	if held != nil {
+		i.action(*held, extcaps.ButtonReleased, 0)
+	}
+}
+
+func (i *Implementation) action(button extcaps.Button, action extcaps.ButtonAction, scene uint8) {
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+
+	i.zi.SendEvent(extcaps.BasicHumanInterfaceDeviceAction{Device: i.d, Button: button, Action: action, Scene: scene})
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Buttons(_ context.Context) ([]extcaps.Button, error) {
+	var buttons []extcaps.Button
+
+	for _, cluster := range i.clusters() {
+		switch cluster {
+		case zcl.OnOffId:
+			buttons = append(buttons, extcaps.ButtonOn, extcaps.ButtonOff, extcaps.ButtonToggle)
+		case zcl.LevelControlId:
+			buttons = append(buttons, extcaps.ButtonUp, extcaps.ButtonDown)
+		case zcl.ScenesId:
+			buttons = append(buttons, extcaps.ButtonScene)
+		}
+	}
+
+	return buttons, nil
+}
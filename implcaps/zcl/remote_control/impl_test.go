@@ -0,0 +1,191 @@
+package remote_control
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/local/level"
+	"github.com/shimmeringbee/zcl/commands/local/onoff"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/implcaps/zcl/scenes"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewRemoteControl(mzi)
+	i.Init(nil, memory.New())
+
+	return i, mzi, mzc
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewRemoteControl(newMockZDAInterface(t))
+
+		assert.Equal(t, capabilities.BasicHumanInterfaceDeviceFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.BasicHumanInterfaceDeviceFlag], i.Name())
+		assert.Equal(t, "ZCLRemoteControl", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("registers the match, returning true if successful", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 4)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(4), i.remoteEndpoint)
+		assert.Equal(t, zigbee.IEEEAddress(1), i.ieeeAddress)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("binds the present output clusters to the controller and registers the match", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+
+		mnb := &zigbee.MockProvider{}
+		defer mnb.AssertExpectations(t)
+
+		mzi.On("NodeBinder").Return(mnb)
+		mnb.On("BindNodeToController", mock.Anything, zigbee.IEEEAddress(1), zigbee.Endpoint(2), zigbee.Endpoint(4), zcl.OnOffId).Return(nil)
+		mnb.On("BindNodeToController", mock.Anything, zigbee.IEEEAddress(1), zigbee.Endpoint(2), zigbee.Endpoint(4), zcl.LevelControlId).Return(io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{
+			"ZigbeeEndpoint":            zigbee.Endpoint(4),
+			"ZigbeeOnOffClusterPresent": true,
+			"ZigbeeLevelClusterPresent": true,
+		})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		buttons, _ := i.Buttons(context.TODO())
+		assert.Equal(t, []extcaps.Button{extcaps.ButtonOn, extcaps.ButtonOff, extcaps.ButtonToggle, extcaps.ButtonUp, extcaps.ButtonDown}, buttons)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("unregisters the match", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+		mzc.On("RegisterMatch", mock.Anything)
+		mzc.On("UnregisterMatch", mock.Anything)
+
+		i.attachMatch()
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+		assert.Nil(t, i.match)
+	})
+}
+
+func TestImplementation_zclFilter(t *testing.T) {
+	t.Run("only accepts client to server messages from the device's control clusters", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		i.ieeeAddress = 1
+		i.remoteEndpoint = 4
+
+		valid := zcl.Message{SourceEndpoint: 4, Direction: zcl.ClientToServer, ClusterID: zcl.OnOffId}
+		assert.True(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, valid))
+
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(2), zigbee.ApplicationMessage{}, valid))
+
+		wrongDirection := valid
+		wrongDirection.Direction = zcl.ServerToClient
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongDirection))
+
+		wrongCluster := valid
+		wrongCluster.ClusterID = zcl.IASZoneId
+		assert.False(t, i.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongCluster))
+
+		mzi.AssertNotCalled(t, "TransmissionLookup", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImplementation_zclMessage(t *testing.T) {
+	t.Run("translates on off commands into presses", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonToggle, Action: extcaps.ButtonPressed}).Once()
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonOn, Action: extcaps.ButtonPressed}).Once()
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonOff, Action: extcaps.ButtonPressed}).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &onoff.Toggle{}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &onoff.On{}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &onoff.Off{}}})
+
+		lastUpdated, _ := i.LastUpdateTime(context.TODO())
+		assert.NotZero(t, lastUpdated)
+	})
+
+	t.Run("translates step commands into presses", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonUp, Action: extcaps.ButtonPressed}).Once()
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonDown, Action: extcaps.ButtonPressed}).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &level.Step{StepMode: 0x00}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &level.StepWithOnOff{StepMode: 0x01}}})
+	})
+
+	t.Run("translates move and stop commands into hold and release", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonDown, Action: extcaps.ButtonHeld}).Once()
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonDown, Action: extcaps.ButtonReleased}).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &level.MoveWithOnOff{MoveMode: 0x01}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &level.Stop{}}})
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &level.StopWithOnOff{}}})
+	})
+
+	t.Run("translates recall scene into a scene press", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+
+		mzi.On("SendEvent", extcaps.BasicHumanInterfaceDeviceAction{Button: extcaps.ButtonScene, Action: extcaps.ButtonPressed, Scene: 3}).Once()
+
+		i.zclMessage(communicator.MessageWithSource{Message: zcl.Message{Command: &scenes.RecallScene{GroupID: 1, SceneID: 3}}})
+	})
+}
@@ -0,0 +1,127 @@
+package scenes
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the Scenes cluster, the subset required is defined here. */
+
+const (
+	SceneCount   = zcl.AttributeID(0x0000)
+	CurrentScene = zcl.AttributeID(0x0001)
+	CurrentGroup = zcl.AttributeID(0x0002)
+	SceneValid   = zcl.AttributeID(0x0003)
+)
+
+const (
+	AddSceneId           = zcl.CommandIdentifier(0x00)
+	ViewSceneId          = zcl.CommandIdentifier(0x01)
+	RemoveSceneId        = zcl.CommandIdentifier(0x02)
+	StoreSceneId         = zcl.CommandIdentifier(0x04)
+	RecallSceneId        = zcl.CommandIdentifier(0x05)
+	GetSceneMembershipId = zcl.CommandIdentifier(0x06)
+)
+
+const (
+	AddSceneResponseId           = zcl.CommandIdentifier(0x00)
+	ViewSceneResponseId          = zcl.CommandIdentifier(0x01)
+	RemoveSceneResponseId        = zcl.CommandIdentifier(0x02)
+	StoreSceneResponseId         = zcl.CommandIdentifier(0x04)
+	GetSceneMembershipResponseId = zcl.CommandIdentifier(0x06)
+)
+
+const (
+	StatusSuccess           = uint8(0x00)
+	StatusInvalidField      = uint8(0x85)
+	StatusInsufficientSpace = uint8(0x89)
+	StatusNotFound          = uint8(0x8b)
+)
+
+// ExtensionFieldSet is the state of one cluster stored within a scene, the data is the cluster's attribute values in
+// the order defined by the cluster's scene table extension.
+type ExtensionFieldSet struct {
+	ClusterID zigbee.ClusterID
+	Data      []byte `bcsliceprefix:"8"`
+}
+
+type AddScene struct {
+	GroupID            uint16
+	SceneID            uint8
+	TransitionTime     uint16
+	SceneName          string
+	ExtensionFieldSets []ExtensionFieldSet
+}
+
+type AddSceneResponse struct {
+	Status  uint8
+	GroupID uint16
+	SceneID uint8
+}
+
+type ViewScene struct {
+	GroupID uint16
+	SceneID uint8
+}
+
+type ViewSceneResponse struct {
+	Status             uint8
+	GroupID            uint16
+	SceneID            uint8
+	TransitionTime     uint16              `bcincludeif:"Status==0"`
+	SceneName          string              `bcincludeif:"Status==0"`
+	ExtensionFieldSets []ExtensionFieldSet `bcincludeif:"Status==0"`
+}
+
+type RemoveScene struct {
+	GroupID uint16
+	SceneID uint8
+}
+
+type RemoveSceneResponse struct {
+	Status  uint8
+	GroupID uint16
+	SceneID uint8
+}
+
+type StoreScene struct {
+	GroupID uint16
+	SceneID uint8
+}
+
+type StoreSceneResponse struct {
+	Status  uint8
+	GroupID uint16
+	SceneID uint8
+}
+
+type RecallScene struct {
+	GroupID uint16
+	SceneID uint8
+}
+
+type GetSceneMembership struct {
+	GroupID uint16
+}
+
+type GetSceneMembershipResponse struct {
+	Status    uint8
+	Capacity  uint8
+	GroupID   uint16
+	SceneList []uint8 `bcincludeif:"Status==0" bcsliceprefix:"8"`
+}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ClientToServer, AddSceneId, &AddScene{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ClientToServer, ViewSceneId, &ViewScene{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ClientToServer, RemoveSceneId, &RemoveScene{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ClientToServer, StoreSceneId, &StoreScene{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ClientToServer, RecallSceneId, &RecallScene{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ClientToServer, GetSceneMembershipId, &GetSceneMembership{})
+
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ServerToClient, AddSceneResponseId, &AddSceneResponse{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ServerToClient, ViewSceneResponseId, &ViewSceneResponse{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ServerToClient, RemoveSceneResponseId, &RemoveSceneResponse{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ServerToClient, StoreSceneResponseId, &StoreSceneResponse{})
+	cr.RegisterLocal(zcl.ScenesId, zigbee.NoManufacturer, zcl.ServerToClient, GetSceneMembershipResponseId, &GetSceneMembershipResponse{})
+}
@@ -0,0 +1,423 @@
+package scenes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ extcaps.Scenes = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+var _ implcaps.GroupCommandObserver = (*Implementation)(nil)
+
+const (
+	ScenesKey         = "Scenes"
+	NameKey           = "Name"
+	TransitionTimeKey = "TransitionTime"
+	CurrentGroupKey   = "CurrentGroup"
+	CurrentSceneKey   = "CurrentScene"
+	SceneValidKey     = "SceneValid"
+)
+
+// maximumTransitionTime is the longest transition time in seconds the Scenes cluster can store.
+const maximumTransitionTime = 0xffff
+
+var ErrSceneNotFound = errors.New("scene not found on device")
+
+func NewScenes(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, currentMutex: &sync.Mutex{}}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	zi implcaps.ZDAInterface
+
+	remoteEndpoint zigbee.Endpoint
+
+	currentGroupMonitor attribute.Monitor
+	currentSceneMonitor attribute.Monitor
+	sceneValidMonitor   attribute.Monitor
+
+	currentMutex *sync.Mutex
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.ScenesFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.ScenesFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.currentGroupMonitor = i.zi.NewAttributeMonitor()
+	i.currentGroupMonitor.Init(s.Section("AttributeMonitor", "CurrentGroup"), d, i.update)
+	i.currentSceneMonitor = i.zi.NewAttributeMonitor()
+	i.currentSceneMonitor.Init(s.Section("AttributeMonitor", "CurrentScene"), d, i.update)
+	i.sceneValidMonitor = i.zi.NewAttributeMonitor()
+	i.sceneValidMonitor.Init(s.Section("AttributeMonitor", "SceneValid"), d, i.update)
+}
+
+func (i *Implementation) monitors() []attribute.Monitor {
+	return []attribute.Monitor{i.currentGroupMonitor, i.currentSceneMonitor, i.sceneValidMonitor}
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("scenes missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	for _, m := range i.monitors() {
+		if err := m.Load(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Enumerate monitors the current scene attributes, so that scenes recalled by other devices (such as remote controls
+// bound directly to the device) are visible.
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	reporting := attribute.ReportingConfig{
+		Mode:             attribute.AttemptConfigureReporting,
+		MinimumInterval:  1 * time.Second,
+		MaximumInterval:  5 * time.Minute,
+		ReportableChange: uint(1),
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	if err := i.currentGroupMonitor.Attach(ctx, i.remoteEndpoint, zcl.ScenesId, CurrentGroup, zcl.TypeUnsignedInt16, reporting, polling); err != nil {
+		return false, err
+	}
+
+	if err := i.currentSceneMonitor.Attach(ctx, i.remoteEndpoint, zcl.ScenesId, CurrentScene, zcl.TypeUnsignedInt8, reporting, polling); err != nil {
+		return false, err
+	}
+
+	if err := i.sceneValidMonitor.Attach(ctx, i.remoteEndpoint, zcl.ScenesId, SceneValid, zcl.TypeBoolean, reporting, polling); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	for _, m := range i.monitors() {
+		if err := m.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLScenes"
+}
+
+func (i *Implementation) update(id zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	group, _ := i.s.UInt(CurrentGroupKey)
+	scene, _ := i.s.UInt(CurrentSceneKey)
+	valid, _ := i.s.Bool(SceneValidKey)
+
+	switch id {
+	case CurrentGroup:
+		if value, ok := v.Value.(uint64); ok {
+			group = value
+		}
+	case CurrentScene:
+		if value, ok := v.Value.(uint64); ok {
+			scene = value
+		}
+	case SceneValid:
+		if value, ok := v.Value.(bool); ok {
+			valid = value
+		}
+	}
+
+	i.updateCurrent(uint16(group), uint8(scene), valid)
+}
+
+// updateCurrent records the scene the device is in, announcing it if the device has moved to a different scene.
+func (i *Implementation) updateCurrent(group uint16, scene uint8, valid bool) {
+	i.currentMutex.Lock()
+	defer i.currentMutex.Unlock()
+
+	oldGroup, _ := i.s.UInt(CurrentGroupKey)
+	oldScene, _ := i.s.UInt(CurrentSceneKey)
+	oldValid, found := i.s.Bool(SceneValidKey)
+
+	if !found || oldGroup != uint64(group) || oldScene != uint64(scene) || oldValid != valid {
+		i.s.Set(CurrentGroupKey, uint64(group))
+		i.s.Set(CurrentSceneKey, uint64(scene))
+		i.s.Set(SceneValidKey, valid)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		if valid {
+			i.zi.SendEvent(extcaps.SceneRecalled{Device: i.d, Scene: i.scene(group, scene)})
+		}
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+// GroupCommand updates the current scene from scenes recalled by multicast to the devices groups.
+func (i *Implementation) GroupCommand(m zcl.Message) {
+	if cmd, ok := m.Command.(*RecallScene); ok {
+		i.updateCurrent(cmd.GroupID, cmd.SceneID, true)
+	}
+}
+
+func (i *Implementation) sceneSection(group uint16, scene uint8) persistence.Section {
+	return i.s.Section(ScenesKey, strconv.Itoa(int(group)), strconv.Itoa(int(scene)))
+}
+
+func (i *Implementation) scene(group uint16, scene uint8) extcaps.Scene {
+	ss := i.sceneSection(group, scene)
+
+	name, _ := ss.String(NameKey)
+	transition, _ := ss.Int(TransitionTimeKey)
+
+	return extcaps.Scene{Group: group, ID: scene, Name: name, TransitionTime: time.Duration(transition) * time.Second}
+}
+
+func (i *Implementation) storeScene(s extcaps.Scene) {
+	ss := i.sceneSection(s.Group, s.ID)
+	ss.Set(NameKey, s.Name)
+	ss.Set(TransitionTimeKey, int(transitionTime(s.TransitionTime)))
+
+	i.scenesChanged()
+}
+
+func (i *Implementation) deleteScene(group uint16, scene uint8) {
+	gs := i.s.Section(ScenesKey, strconv.Itoa(int(group)))
+
+	if !gs.SectionDelete(strconv.Itoa(int(scene))) {
+		return
+	}
+
+	if len(gs.SectionKeys()) == 0 {
+		i.s.Section(ScenesKey).SectionDelete(strconv.Itoa(int(group)))
+	}
+
+	i.scenesChanged()
+}
+
+func (i *Implementation) scenesChanged() {
+	scenes, _ := i.Scenes(context.Background())
+	i.zi.SendEvent(extcaps.ScenesUpdate{Device: i.d, Scenes: scenes})
+}
+
+func transitionTime(d time.Duration) uint16 {
+	return uint16(math.Min(maximumTransitionTime, math.Max(0, math.Round(d.Seconds()))))
+}
+
+func (i *Implementation) message(id zcl.CommandIdentifier, cmd any) (zigbee.IEEEAddress, bool, zcl.Message) {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.ScenesId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	}
+}
+
+func (i *Implementation) request(ctx context.Context, id zcl.CommandIdentifier, cmd any) (zcl.Message, error) {
+	ieee, ack, msg := i.message(id, cmd)
+	return i.zi.ZCLCommunicator().RequestResponse(ctx, ieee, ack, msg)
+}
+
+// responseStatus extracts the status from a scene command response, returning an error for an unexpected response.
+func responseStatus(resp zcl.Message) (uint8, error) {
+	switch cmd := resp.Command.(type) {
+	case *AddSceneResponse:
+		return cmd.Status, nil
+	case *ViewSceneResponse:
+		return cmd.Status, nil
+	case *RemoveSceneResponse:
+		return cmd.Status, nil
+	case *StoreSceneResponse:
+		return cmd.Status, nil
+	default:
+		return 0, fmt.Errorf("unexpected response to scene command: %T", resp.Command)
+	}
+}
+
+func (i *Implementation) AddScene(ctx context.Context, s extcaps.Scene) error {
+	resp, err := i.request(ctx, AddSceneId, &AddScene{GroupID: s.Group, SceneID: s.ID, TransitionTime: transitionTime(s.TransitionTime), SceneName: s.Name})
+	if err != nil {
+		return err
+	}
+
+	if status, err := responseStatus(resp); err != nil {
+		return err
+	} else if status != StatusSuccess {
+		return fmt.Errorf("failed to add scene %04x/%02x: status %02x", s.Group, s.ID, status)
+	}
+
+	i.storeScene(s)
+	return nil
+}
+
+// StoreScene captures the devices current state into the scene, creating it if it is not already known.
+func (i *Implementation) StoreScene(ctx context.Context, group uint16, scene uint8) error {
+	resp, err := i.request(ctx, StoreSceneId, &StoreScene{GroupID: group, SceneID: scene})
+	if err != nil {
+		return err
+	}
+
+	if status, err := responseStatus(resp); err != nil {
+		return err
+	} else if status != StatusSuccess {
+		return fmt.Errorf("failed to store scene %04x/%02x: status %02x", group, scene, status)
+	}
+
+	i.storeScene(i.scene(group, scene))
+	return nil
+}
+
+func (i *Implementation) RecallScene(ctx context.Context, group uint16, scene uint8) error {
+	ieee, ack, msg := i.message(RecallSceneId, &RecallScene{GroupID: group, SceneID: scene})
+
+	if err := i.zi.ZCLCommunicator().Request(ctx, ieee, ack, msg); err != nil {
+		return err
+	}
+
+	i.updateCurrent(group, scene, true)
+	return nil
+}
+
+// RemoveScene deletes the scene from the device, a scene which does not exist is treated as success.
+func (i *Implementation) RemoveScene(ctx context.Context, group uint16, scene uint8) error {
+	resp, err := i.request(ctx, RemoveSceneId, &RemoveScene{GroupID: group, SceneID: scene})
+	if err != nil {
+		return err
+	}
+
+	if status, err := responseStatus(resp); err != nil {
+		return err
+	} else if status != StatusSuccess && status != StatusNotFound {
+		return fmt.Errorf("failed to remove scene %04x/%02x: status %02x", group, scene, status)
+	}
+
+	i.deleteScene(group, scene)
+	return nil
+}
+
+// ViewScene queries the device for the scene, refreshing the persisted metadata. A scene which no longer exists on the
+// device is forgotten and ErrSceneNotFound is returned.
+func (i *Implementation) ViewScene(ctx context.Context, group uint16, scene uint8) (extcaps.Scene, error) {
+	resp, err := i.request(ctx, ViewSceneId, &ViewScene{GroupID: group, SceneID: scene})
+	if err != nil {
+		return extcaps.Scene{}, err
+	}
+
+	cmd, ok := resp.Command.(*ViewSceneResponse)
+	if !ok {
+		return extcaps.Scene{}, fmt.Errorf("unexpected response to view scene: %T", resp.Command)
+	}
+
+	switch cmd.Status {
+	case StatusSuccess:
+		s := extcaps.Scene{Group: group, ID: scene, Name: cmd.SceneName, TransitionTime: time.Duration(cmd.TransitionTime) * time.Second}
+
+		if s != i.scene(group, scene) {
+			i.storeScene(s)
+		}
+
+		return s, nil
+	case StatusNotFound:
+		i.deleteScene(group, scene)
+		return extcaps.Scene{}, ErrSceneNotFound
+	default:
+		return extcaps.Scene{}, fmt.Errorf("failed to view scene %04x/%02x: status %02x", group, scene, cmd.Status)
+	}
+}
+
+func (i *Implementation) Scenes(_ context.Context) ([]extcaps.Scene, error) {
+	var scenes []extcaps.Scene
+
+	ss := i.s.Section(ScenesKey)
+
+	for _, gk := range ss.SectionKeys() {
+		group, err := strconv.ParseUint(gk, 10, 16)
+		if err != nil {
+			continue
+		}
+
+		for _, sk := range ss.Section(gk).SectionKeys() {
+			if scene, err := strconv.ParseUint(sk, 10, 8); err == nil {
+				scenes = append(scenes, i.scene(uint16(group), uint8(scene)))
+			}
+		}
+	}
+
+	slices.SortFunc(scenes, func(a, b extcaps.Scene) int {
+		if a.Group != b.Group {
+			return int(a.Group) - int(b.Group)
+		}
+
+		return int(a.ID) - int(b.ID)
+	})
+
+	return scenes, nil
+}
+
+func (i *Implementation) ActiveScene(_ context.Context) (extcaps.Scene, bool, error) {
+	if valid, _ := i.s.Bool(SceneValidKey); !valid {
+		return extcaps.Scene{}, false, nil
+	}
+
+	group, _ := i.s.UInt(CurrentGroupKey)
+	scene, _ := i.s.UInt(CurrentSceneKey)
+
+	return i.scene(uint16(group), uint8(scene)), true, nil
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
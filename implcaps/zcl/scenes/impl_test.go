@@ -0,0 +1,281 @@
+package scenes
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newImplementation(t *testing.T) (*Implementation, *implcaps.MockZDAInterface, *mocks.MockZCLCommunicator) {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+	mzi.On("ZCLRegister", mock.Anything)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc).Maybe()
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3).Maybe()
+
+	i := NewScenes(mzi)
+	i.s = memory.New()
+	i.remoteEndpoint = 4
+
+	return i, mzi, mzc
+}
+
+func request(id zcl.CommandIdentifier, cmd any) zcl.Message {
+	return zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: 3,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.ScenesId,
+		SourceEndpoint:      2,
+		DestinationEndpoint: 4,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	}
+}
+
+func analogReporting(rc attribute.ReportingConfig) bool {
+	return rc.ReportableChange == uint(1)
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		assert.Equal(t, extcaps.ScenesFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.ScenesFlag], i.Name())
+		assert.Equal(t, "ZCLScenes", i.ImplName())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads attribute monitors, returning true if successful", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+		mm.On("Load", mock.Anything).Return(nil).Times(3)
+
+		i.currentGroupMonitor, i.currentSceneMonitor, i.sceneValidMonitor = mm, mm, mm
+		i.s.Set(implcaps.RemoteEndpointKey, 5)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(5), i.remoteEndpoint)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("attaches to the current scene attributes", func(t *testing.T) {
+		i, _, _ := newImplementation(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(5), zcl.ScenesId, CurrentGroup, zcl.TypeUnsignedInt16, mock.MatchedBy(analogReporting), mock.Anything).Return(nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(5), zcl.ScenesId, CurrentScene, zcl.TypeUnsignedInt8, mock.MatchedBy(analogReporting), mock.Anything).Return(nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(5), zcl.ScenesId, SceneValid, zcl.TypeBoolean, mock.Anything, mock.Anything).Return(nil)
+
+		i.currentGroupMonitor, i.currentSceneMonitor, i.sceneValidMonitor = mm, mm, mm
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(5)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		v, _ := i.s.Int(implcaps.RemoteEndpointKey)
+		assert.Equal(t, int64(5), v)
+	})
+}
+
+func TestImplementation_AddScene(t *testing.T) {
+	t.Run("adds the scene and persists its metadata", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		scene := extcaps.Scene{Group: 1, ID: 2, Name: "Evening", TransitionTime: 5 * time.Second}
+		mzi.On("SendEvent", extcaps.ScenesUpdate{Scenes: []extcaps.Scene{scene}})
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, request(AddSceneId, &AddScene{GroupID: 1, SceneID: 2, TransitionTime: 5, SceneName: "Evening"})).Return(zcl.Message{Command: &AddSceneResponse{Status: StatusSuccess, GroupID: 1, SceneID: 2}}, nil)
+
+		err := i.AddScene(context.TODO(), scene)
+		assert.NoError(t, err)
+
+		scenes, _ := i.Scenes(context.TODO())
+		assert.Equal(t, []extcaps.Scene{scene}, scenes)
+	})
+
+	t.Run("errors and does not persist if the device rejects the scene", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{Command: &AddSceneResponse{Status: StatusInsufficientSpace, GroupID: 1, SceneID: 2}}, nil)
+
+		err := i.AddScene(context.TODO(), extcaps.Scene{Group: 1, ID: 2})
+		assert.Error(t, err)
+
+		scenes, _ := i.Scenes(context.TODO())
+		assert.Empty(t, scenes)
+	})
+}
+
+func TestImplementation_StoreScene(t *testing.T) {
+	t.Run("stores the scene, keeping any existing metadata", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		scene := extcaps.Scene{Group: 1, ID: 2, Name: "Evening"}
+		mzi.On("SendEvent", extcaps.ScenesUpdate{Scenes: []extcaps.Scene{scene}}).Twice()
+		i.storeScene(scene)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, request(StoreSceneId, &StoreScene{GroupID: 1, SceneID: 2})).Return(zcl.Message{Command: &StoreSceneResponse{Status: StatusSuccess, GroupID: 1, SceneID: 2}}, nil)
+
+		err := i.StoreScene(context.TODO(), 1, 2)
+		assert.NoError(t, err)
+
+		scenes, _ := i.Scenes(context.TODO())
+		assert.Equal(t, []extcaps.Scene{scene}, scenes)
+	})
+
+	t.Run("errors if the device does not respond", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{}, io.EOF)
+
+		err := i.StoreScene(context.TODO(), 1, 2)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestImplementation_RecallScene(t *testing.T) {
+	t.Run("recalls the scene and announces it as active", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, request(RecallSceneId, &RecallScene{GroupID: 1, SceneID: 2})).Return(nil)
+		mzi.On("SendEvent", extcaps.SceneRecalled{Scene: extcaps.Scene{Group: 1, ID: 2}})
+
+		err := i.RecallScene(context.TODO(), 1, 2)
+		assert.NoError(t, err)
+
+		active, valid, _ := i.ActiveScene(context.TODO())
+		assert.True(t, valid)
+		assert.Equal(t, extcaps.Scene{Group: 1, ID: 2}, active)
+	})
+}
+
+func TestImplementation_RemoveScene(t *testing.T) {
+	t.Run("removes the scene and its metadata", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		scene := extcaps.Scene{Group: 1, ID: 2}
+		mzi.On("SendEvent", extcaps.ScenesUpdate{Scenes: []extcaps.Scene{scene}}).Once()
+		mzi.On("SendEvent", extcaps.ScenesUpdate{}).Once()
+		i.storeScene(scene)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, request(RemoveSceneId, &RemoveScene{GroupID: 1, SceneID: 2})).Return(zcl.Message{Command: &RemoveSceneResponse{Status: StatusNotFound, GroupID: 1, SceneID: 2}}, nil)
+
+		err := i.RemoveScene(context.TODO(), 1, 2)
+		assert.NoError(t, err)
+
+		scenes, _ := i.Scenes(context.TODO())
+		assert.Empty(t, scenes)
+		assert.Empty(t, i.s.Section(ScenesKey).SectionKeys())
+	})
+}
+
+func TestImplementation_ViewScene(t *testing.T) {
+	t.Run("returns the scene from the device, persisting its metadata", func(t *testing.T) {
+		i, mzi, mzc := newImplementation(t)
+
+		scene := extcaps.Scene{Group: 1, ID: 2, Name: "Morning", TransitionTime: 2 * time.Second}
+		mzi.On("SendEvent", extcaps.ScenesUpdate{Scenes: []extcaps.Scene{scene}})
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, request(ViewSceneId, &ViewScene{GroupID: 1, SceneID: 2})).Return(zcl.Message{Command: &ViewSceneResponse{Status: StatusSuccess, GroupID: 1, SceneID: 2, TransitionTime: 2, SceneName: "Morning"}}, nil)
+
+		s, err := i.ViewScene(context.TODO(), 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, scene, s)
+
+		scenes, _ := i.Scenes(context.TODO())
+		assert.Equal(t, []extcaps.Scene{scene}, scenes)
+	})
+
+	t.Run("returns an error if the scene is not on the device", func(t *testing.T) {
+		i, _, mzc := newImplementation(t)
+
+		mzc.On("RequestResponse", mock.Anything, zigbee.IEEEAddress(1), false, mock.Anything).Return(zcl.Message{Command: &ViewSceneResponse{Status: StatusNotFound, GroupID: 1, SceneID: 2}}, nil)
+
+		_, err := i.ViewScene(context.TODO(), 1, 2)
+		assert.ErrorIs(t, err, ErrSceneNotFound)
+	})
+}
+
+func TestImplementation_Scenes(t *testing.T) {
+	t.Run("returns persisted scenes ordered by group and id", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("SendEvent", mock.Anything)
+
+		i.storeScene(extcaps.Scene{Group: 10, ID: 1})
+		i.storeScene(extcaps.Scene{Group: 2, ID: 12})
+		i.storeScene(extcaps.Scene{Group: 2, ID: 3})
+
+		scenes, err := i.Scenes(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []extcaps.Scene{{Group: 2, ID: 3}, {Group: 2, ID: 12}, {Group: 10, ID: 1}}, scenes)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("announces scenes recalled on the device by others", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+
+		i.update(CurrentGroup, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt16, Value: uint64(1)})
+		i.update(CurrentScene, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(2)})
+
+		mzi.On("SendEvent", extcaps.SceneRecalled{Scene: extcaps.Scene{Group: 1, ID: 2}}).Once()
+		i.update(SceneValid, zcl.AttributeDataTypeValue{DataType: zcl.TypeBoolean, Value: true})
+
+		i.update(SceneValid, zcl.AttributeDataTypeValue{DataType: zcl.TypeBoolean, Value: false})
+
+		_, valid, _ := i.ActiveScene(context.TODO())
+		assert.False(t, valid)
+
+		changed, _ := i.LastChangeTime(context.TODO())
+		assert.WithinDuration(t, time.Now(), changed, time.Second)
+	})
+}
+
+func TestImplementation_GroupCommand(t *testing.T) {
+	t.Run("updates the active scene from a multicast recall", func(t *testing.T) {
+		i, mzi, _ := newImplementation(t)
+		mzi.On("SendEvent", extcaps.SceneRecalled{Scene: extcaps.Scene{Group: 5, ID: 6}})
+
+		i.GroupCommand(zcl.Message{Command: &RecallScene{GroupID: 5, SceneID: 6}})
+
+		active, valid, _ := i.ActiveScene(context.TODO())
+		assert.True(t, valid)
+		assert.Equal(t, extcaps.Scene{Group: 5, ID: 6}, active)
+	})
+}
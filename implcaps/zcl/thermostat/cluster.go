@@ -0,0 +1,29 @@
+package thermostat
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the Thermostat cluster, the subset required is defined here. */
+
+const (
+	LocalTemperature        = zcl.AttributeID(0x0000)
+	OccupiedCoolingSetpoint = zcl.AttributeID(0x0011)
+	OccupiedHeatingSetpoint = zcl.AttributeID(0x0012)
+	SystemMode              = zcl.AttributeID(0x001c)
+	ThermostatRunningState  = zcl.AttributeID(0x0029)
+)
+
+const (
+	SetpointRaiseLowerId = zcl.CommandIdentifier(0x00)
+)
+
+type SetpointRaiseLower struct {
+	Mode   uint8
+	Amount int8
+}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.ThermostatId, zigbee.NoManufacturer, zcl.ClientToServer, SetpointRaiseLowerId, &SetpointRaiseLower{})
+}
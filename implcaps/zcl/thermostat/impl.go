@@ -0,0 +1,380 @@
+package thermostat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ extcaps.Thermostat = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const LocalTemperatureKey = "LocalTemperature"
+const HeatingSetpointKey = "HeatingSetpoint"
+const CoolingSetpointKey = "CoolingSetpoint"
+const SystemModeKey = "SystemMode"
+const RunningStateKey = "RunningState"
+
+// AttributePresent is the persistence key recording if the thermostat supports a monitored attribute.
+var AttributePresent = func(name string) string { return fmt.Sprintf("%sPresent", name) }
+
+// Bits of the ThermostatRunningState attribute, first and second stages are treated the same.
+const (
+	runningStateHeat = uint64(0x0001 | 0x0008)
+	runningStateCool = uint64(0x0002 | 0x0010)
+	runningStateFan  = uint64(0x0004 | 0x0020 | 0x0040)
+)
+
+// attributeKeys maps the monitored attributes to the persistence key that their value is stored under.
+var attributeKeys = map[zcl.AttributeID]string{
+	LocalTemperature:        LocalTemperatureKey,
+	OccupiedHeatingSetpoint: HeatingSetpointKey,
+	OccupiedCoolingSetpoint: CoolingSetpointKey,
+	SystemMode:              SystemModeKey,
+	ThermostatRunningState:  RunningStateKey,
+}
+
+// invalidTemperature is the value of LocalTemperature when no measurement is available.
+const invalidTemperature = int64(-0x8000)
+
+type monitoredAttribute struct {
+	name             string
+	id               zcl.AttributeID
+	dataType         zcl.AttributeDataType
+	reportableChange any
+}
+
+var monitoredAttributes = []monitoredAttribute{
+	{name: "LocalTemperature", id: LocalTemperature, dataType: zcl.TypeSignedInt16, reportableChange: 10},
+	{name: "OccupiedHeatingSetpoint", id: OccupiedHeatingSetpoint, dataType: zcl.TypeSignedInt16, reportableChange: 10},
+	{name: "OccupiedCoolingSetpoint", id: OccupiedCoolingSetpoint, dataType: zcl.TypeSignedInt16, reportableChange: 10},
+	{name: "SystemMode", id: SystemMode, dataType: zcl.TypeEnum8},
+	{name: "ThermostatRunningState", id: ThermostatRunningState, dataType: zcl.TypeBitmap16},
+}
+
+func NewThermostat(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, l: zi.Logger()}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	zi implcaps.ZDAInterface
+	l  logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+
+	monitors map[string]attribute.Monitor
+	present  map[string]bool
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return extcaps.ThermostatFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[extcaps.ThermostatFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.monitors = map[string]attribute.Monitor{}
+	i.present = map[string]bool{}
+
+	for _, ma := range monitoredAttributes {
+		m := i.zi.NewAttributeMonitor()
+		m.Init(s.Section("AttributeMonitor", ma.name), d, i.update)
+		i.monitors[ma.name] = m
+	}
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("monitor missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	for _, ma := range monitoredAttributes {
+		i.present[ma.name], _ = i.s.Bool(AttributePresent(ma.name))
+	}
+
+	for _, ma := range i.active() {
+		if err := i.monitors[ma.name].Load(ctx); err != nil {
+			i.l.Warn(ctx, "Failed to load thermostat attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", ma.name))
+			return false, fmt.Errorf("%s monitor, load failed: %w", ma.name, err)
+		}
+	}
+
+	return true, nil
+}
+
+// active returns the monitored attributes which the thermostat supports.
+func (i *Implementation) active() []monitoredAttribute {
+	var active []monitoredAttribute
+
+	for _, ma := range monitoredAttributes {
+		if i.present[ma.name] {
+			active = append(active, ma)
+		}
+	}
+
+	return active
+}
+
+// Enumerate reads the monitored attributes from the thermostat, attaching monitors to only those which it supports, as
+// the cooling setpoint and running state are optional.
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	var attributes []zcl.AttributeID
+	for _, ma := range monitoredAttributes {
+		attributes = append(attributes, ma.id)
+	}
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.ThermostatId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, attributes)
+	if err != nil {
+		i.l.Warn(ctx, "Failed to read thermostat attributes.", logwrap.Err(err))
+		return false, fmt.Errorf("failed to read thermostat attributes: %w", err)
+	}
+
+	responses := communicator.ReadResponsesToMap(recs)
+
+	for _, ma := range monitoredAttributes {
+		rec, found := responses[ma.id]
+		i.present[ma.name] = found && rec.Status == 0
+		i.s.Set(AttributePresent(ma.name), i.present[ma.name])
+	}
+
+	if len(i.active()) == 0 {
+		i.l.Warn(ctx, "Thermostat supports none of the monitored attributes.")
+		return false, nil
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	for _, ma := range i.active() {
+		reporting := attribute.ReportingConfig{
+			Mode:             attribute.AttemptConfigureReporting,
+			MinimumInterval:  1 * time.Second,
+			MaximumInterval:  5 * time.Minute,
+			ReportableChange: ma.reportableChange,
+		}
+
+		if err := i.monitors[ma.name].Attach(ctx, i.remoteEndpoint, zcl.ThermostatId, ma.id, ma.dataType, reporting, polling); err != nil {
+			i.l.Warn(ctx, "Failed to attach thermostat attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", ma.name))
+			return false, fmt.Errorf("%s monitor, attach failed: %w", ma.name, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	var errs []error
+
+	for _, ma := range i.active() {
+		if err := i.monitors[ma.name].Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+			i.l.Warn(ctx, "Failed to detach thermostat attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", ma.name))
+			errs = append(errs, fmt.Errorf("%s monitor, detach failed: %w", ma.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLThermostat"
+}
+
+func (i *Implementation) update(id zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	changed := false
+
+	switch id {
+	case LocalTemperature, OccupiedHeatingSetpoint, OccupiedCoolingSetpoint:
+		value, ok := v.Value.(int64)
+		if !ok || value == invalidTemperature {
+			return
+		}
+
+		changed = i.storeTemperature(attributeKeys[id], zclToKelvin(value))
+	case SystemMode:
+		value, ok := v.Value.(uint8)
+		if !ok {
+			return
+		}
+
+		changed = i.storeUint(attributeKeys[id], uint64(value))
+	case ThermostatRunningState:
+		value, ok := v.Value.(uint64)
+		if !ok {
+			return
+		}
+
+		changed = i.storeUint(attributeKeys[id], value)
+	default:
+		return
+	}
+
+	i.stored(changed)
+}
+
+func (i *Implementation) storeTemperature(key string, k float64) bool {
+	if current, found := i.s.Float(key); found && math.Abs(current-k) < 0.005 {
+		return false
+	}
+
+	i.s.Set(key, k)
+	return true
+}
+
+func (i *Implementation) storeUint(key string, v uint64) bool {
+	if current, found := i.s.UInt(key); found && current == v {
+		return false
+	}
+
+	i.s.Set(key, v)
+	return true
+}
+
+func (i *Implementation) stored(changed bool) {
+	if changed {
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+		i.zi.SendEvent(extcaps.ThermostatUpdate{Device: i.d, State: i.state()})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) state() extcaps.ThermostatState {
+	localTemperature, _ := i.s.Float(LocalTemperatureKey)
+	heatingSetpoint, _ := i.s.Float(HeatingSetpointKey)
+	coolingSetpoint, _ := i.s.Float(CoolingSetpointKey)
+	mode, _ := i.s.UInt(SystemModeKey)
+	running, _ := i.s.UInt(RunningStateKey)
+
+	return extcaps.ThermostatState{
+		LocalTemperature: localTemperature,
+		HeatingSetpoint:  heatingSetpoint,
+		CoolingSetpoint:  coolingSetpoint,
+		Mode:             extcaps.ThermostatMode(mode),
+		Heating:          running&runningStateHeat != 0,
+		Cooling:          running&runningStateCool != 0,
+		Fan:              running&runningStateFan != 0,
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (extcaps.ThermostatState, error) {
+	return i.state(), nil
+}
+
+func (i *Implementation) SetHeatingSetpoint(ctx context.Context, k float64) error {
+	if err := i.writeAttribute(ctx, OccupiedHeatingSetpoint, zcl.TypeSignedInt16, kelvinToZCL(k)); err != nil {
+		return err
+	}
+
+	i.stored(i.storeTemperature(HeatingSetpointKey, zclToKelvin(kelvinToZCL(k))))
+	return nil
+}
+
+func (i *Implementation) SetCoolingSetpoint(ctx context.Context, k float64) error {
+	if err := i.writeAttribute(ctx, OccupiedCoolingSetpoint, zcl.TypeSignedInt16, kelvinToZCL(k)); err != nil {
+		return err
+	}
+
+	i.stored(i.storeTemperature(CoolingSetpointKey, zclToKelvin(kelvinToZCL(k))))
+	return nil
+}
+
+func (i *Implementation) SetMode(ctx context.Context, mode extcaps.ThermostatMode) error {
+	if err := i.writeAttribute(ctx, SystemMode, zcl.TypeEnum8, uint64(mode)); err != nil {
+		return err
+	}
+
+	i.stored(i.storeUint(SystemModeKey, uint64(mode)))
+	return nil
+}
+
+// AdjustSetpoint sends a Setpoint Raise/Lower command, the device adjusts its setpoint in steps of 0.1 Kelvin and the
+// resulting setpoint will be reported back by the attribute monitor.
+func (i *Implementation) AdjustSetpoint(ctx context.Context, setpoint extcaps.ThermostatSetpoint, delta float64) error {
+	amount := int8(math.Max(math.MinInt8, math.Min(math.MaxInt8, math.Round(delta*10))))
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.ThermostatId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   SetpointRaiseLowerId,
+		Command:             &SetpointRaiseLower{Mode: uint8(setpoint), Amount: amount},
+	})
+}
+
+func (i *Implementation) writeAttribute(ctx context.Context, id zcl.AttributeID, dataType zcl.AttributeDataType, value any) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	recs, err := i.zi.ZCLCommunicator().WriteAttributes(ctx, ieee, ack, zcl.ThermostatId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+		id: {
+			DataType: dataType,
+			Value:    value,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if rec, found := communicator.WriteResponsesToMap(recs)[id]; found && rec.Status != 0 {
+		return fmt.Errorf("failed to write thermostat attribute 0x%04x: status %d", id, rec.Status)
+	}
+
+	return nil
+}
+
+// zclToKelvin converts a ZCL temperature in hundredths of a degree Celsius to Kelvin.
+func zclToKelvin(v int64) float64 {
+	return (float64(v) / 100.0) + 273.15
+}
+
+// kelvinToZCL converts Kelvin to a ZCL temperature in hundredths of a degree Celsius.
+func kelvinToZCL(k float64) int64 {
+	return int64(math.Round((k - 273.15) * 100.0))
+}
@@ -0,0 +1,420 @@
+package thermostat
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newWithMonitor(t *testing.T, mzi *implcaps.MockZDAInterface) (*Implementation, *attribute.MockMonitor) {
+	mm := &attribute.MockMonitor{}
+	t.Cleanup(func() { mm.AssertExpectations(t) })
+
+	i := NewThermostat(mzi)
+	i.s = memory.New()
+	i.monitors = map[string]attribute.Monitor{}
+	i.present = map[string]bool{}
+
+	for _, ma := range monitoredAttributes {
+		i.monitors[ma.name] = mm
+	}
+
+	return i, mm
+}
+
+func newWithCommunicator(t *testing.T) (*Implementation, *attribute.MockMonitor, *mocks.MockZCLCommunicator) {
+	mzi := newMockZDAInterface(t)
+
+	mzc := &mocks.MockZCLCommunicator{}
+	t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+	mzi.On("ZCLCommunicator").Return(mzc)
+	mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+
+	i, mm := newWithMonitor(t, mzi)
+	return i, mm, mzc
+}
+
+func setAllPresent(i *Implementation) {
+	for _, ma := range monitoredAttributes {
+		i.present[ma.name] = true
+		i.s.Set(AttributePresent(ma.name), true)
+	}
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewThermostat(newMockZDAInterface(t))
+
+		assert.Equal(t, extcaps.ThermostatFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[extcaps.ThermostatFlag], i.Name())
+		assert.Equal(t, "ZCLThermostat", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs attribute monitors correctly initialising them", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm).Times(len(monitoredAttributes))
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		s := memory.New()
+		mm.On("Init", mock.Anything, md, mock.Anything).Times(len(monitoredAttributes))
+
+		i := NewThermostat(mzi)
+		i.Init(md, s)
+
+		assert.Len(t, i.monitors, len(monitoredAttributes))
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads attribute monitors, returning true if successful", func(t *testing.T) {
+		i, mm := newWithMonitor(t, newMockZDAInterface(t))
+		mm.On("Load", mock.Anything).Return(nil).Times(len(monitoredAttributes))
+
+		setAllPresent(i)
+		i.s.Set(implcaps.RemoteEndpointKey, 3)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(3), i.remoteEndpoint)
+	})
+
+	t.Run("only loads monitors of attributes the thermostat supports", func(t *testing.T) {
+		i, mm := newWithMonitor(t, newMockZDAInterface(t))
+		mm.On("Load", mock.Anything).Return(nil).Twice()
+
+		i.s.Set(AttributePresent("LocalTemperature"), true)
+		i.s.Set(AttributePresent("SystemMode"), true)
+		i.s.Set(implcaps.RemoteEndpointKey, 3)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _ := newWithMonitor(t, newMockZDAInterface(t))
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails if a monitor fails to load", func(t *testing.T) {
+		i, mm := newWithMonitor(t, newMockZDAInterface(t))
+		mm.On("Load", mock.Anything).Return(io.EOF).Once()
+
+		setAllPresent(i)
+		i.s.Set(implcaps.RemoteEndpointKey, 3)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func successfulRead(ids ...zcl.AttributeID) []global.ReadAttributeResponseRecord {
+	var recs []global.ReadAttributeResponseRecord
+
+	for _, ma := range monitoredAttributes {
+		rec := global.ReadAttributeResponseRecord{Identifier: ma.id, Status: 0x86}
+
+		for _, id := range ids {
+			if id == ma.id {
+				rec = global.ReadAttributeResponseRecord{Identifier: ma.id, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: ma.dataType, Value: uint64(0)}}
+			}
+		}
+
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	allAttributes := []zcl.AttributeID{LocalTemperature, OccupiedHeatingSetpoint, OccupiedCoolingSetpoint, SystemMode, ThermostatRunningState}
+
+	t.Run("attaches all monitors to the thermostat cluster", func(t *testing.T) {
+		i, mm, mzc := newWithCommunicator(t)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.ThermostatId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(2), uint8(3), allAttributes).Return(successfulRead(allAttributes...), nil)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, LocalTemperature, zcl.TypeSignedInt16, mock.Anything, mock.Anything).Return(nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, OccupiedHeatingSetpoint, zcl.TypeSignedInt16, mock.Anything, mock.Anything).Return(nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, OccupiedCoolingSetpoint, zcl.TypeSignedInt16, mock.Anything, mock.Anything).Return(nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, SystemMode, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(nil)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, ThermostatRunningState, zcl.TypeBitmap16, mock.Anything, mock.Anything).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(2)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		ep, _ := i.s.Int(implcaps.RemoteEndpointKey)
+		assert.Equal(t, int64(2), ep)
+	})
+
+	t.Run("only attaches monitors to attributes which are read successfully", func(t *testing.T) {
+		i, mm, mzc := newWithCommunicator(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(successfulRead(LocalTemperature, OccupiedHeatingSetpoint, SystemMode), nil)
+
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, LocalTemperature, zcl.TypeSignedInt16, mock.Anything, mock.Anything).Return(nil).Once()
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, OccupiedHeatingSetpoint, zcl.TypeSignedInt16, mock.Anything, mock.Anything).Return(nil).Once()
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(2), zcl.ThermostatId, SystemMode, zcl.TypeEnum8, mock.Anything, mock.Anything).Return(nil).Once()
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(2)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		cooling, _ := i.s.Bool(AttributePresent("OccupiedCoolingSetpoint"))
+		assert.False(t, cooling)
+
+		heating, _ := i.s.Bool(AttributePresent("OccupiedHeatingSetpoint"))
+		assert.True(t, heating)
+	})
+
+	t.Run("fails if the attributes can not be read", func(t *testing.T) {
+		i, _, mzc := newWithCommunicator(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("fails if a monitor fails to attach", func(t *testing.T) {
+		i, mm, mzc := newWithCommunicator(t)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(successfulRead(allAttributes...), nil)
+		mm.On("Attach", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(io.EOF).Once()
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detaches all monitors", func(t *testing.T) {
+		i, mm := newWithMonitor(t, newMockZDAInterface(t))
+		mm.On("Detach", mock.Anything, true).Return(nil).Times(len(monitoredAttributes))
+
+		setAllPresent(i)
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("detaches every monitor even if one fails, returning its error", func(t *testing.T) {
+		i, mm := newWithMonitor(t, newMockZDAInterface(t))
+		mm.On("Detach", mock.Anything, false).Return(io.EOF).Once()
+		mm.On("Detach", mock.Anything, false).Return(nil).Times(len(monitoredAttributes) - 1)
+
+		setAllPresent(i)
+
+		err := i.Detach(context.TODO(), implcaps.DeviceRemoved)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("converts temperatures to kelvin and sends an event on change", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _ := newWithMonitor(t, mzi)
+
+		mzi.On("SendEvent", mock.Anything).Run(func(args mock.Arguments) {
+			e, ok := args.Get(0).(extcaps.ThermostatUpdate)
+			assert.True(t, ok)
+			assert.InDelta(t, 294.15, e.State.LocalTemperature, 0.001)
+		}).Once()
+
+		i.update(LocalTemperature, zcl.AttributeDataTypeValue{DataType: zcl.TypeSignedInt16, Value: int64(2100)})
+		i.update(LocalTemperature, zcl.AttributeDataTypeValue{DataType: zcl.TypeSignedInt16, Value: int64(2100)})
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.NotZero(t, lct)
+	})
+
+	t.Run("ignores invalid local temperatures", func(t *testing.T) {
+		i, _ := newWithMonitor(t, newMockZDAInterface(t))
+
+		i.update(LocalTemperature, zcl.AttributeDataTypeValue{DataType: zcl.TypeSignedInt16, Value: int64(-0x8000)})
+
+		_, found := i.s.Float(LocalTemperatureKey)
+		assert.False(t, found)
+	})
+
+	t.Run("decodes system mode and running state", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _ := newWithMonitor(t, mzi)
+		mzi.On("SendEvent", mock.Anything).Twice()
+
+		i.update(SystemMode, zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(0x04)})
+		i.update(ThermostatRunningState, zcl.AttributeDataTypeValue{DataType: zcl.TypeBitmap16, Value: uint64(0x0005)})
+
+		state, err := i.Status(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, extcaps.ThermostatHeat, state.Mode)
+		assert.True(t, state.Heating)
+		assert.False(t, state.Cooling)
+		assert.True(t, state.Fan)
+	})
+}
+
+func TestImplementation_SetHeatingSetpoint(t *testing.T) {
+	t.Run("writes the setpoint in hundredths of celsius", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _ := newWithMonitor(t, mzi)
+		i.remoteEndpoint = 4
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+		mzi.On("SendEvent", mock.Anything)
+
+		mzc.On("WriteAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.ThermostatId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			OccupiedHeatingSetpoint: {DataType: zcl.TypeSignedInt16, Value: int64(2050)},
+		}).Return([]global.WriteAttributesResponseRecord{{Identifier: OccupiedHeatingSetpoint}}, nil)
+
+		err := i.SetHeatingSetpoint(context.TODO(), 293.65)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.InDelta(t, 293.65, state.HeatingSetpoint, 0.001)
+	})
+
+	t.Run("returns an error if the write is rejected", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _ := newWithMonitor(t, mzi)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+
+		mzc.On("WriteAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.WriteAttributesResponseRecord{{Identifier: OccupiedHeatingSetpoint, Status: 0x87}}, nil)
+
+		err := i.SetHeatingSetpoint(context.TODO(), 400)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_SetMode(t *testing.T) {
+	t.Run("writes the system mode", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _ := newWithMonitor(t, mzi)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+		mzi.On("SendEvent", mock.Anything)
+
+		mzc.On("WriteAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.ThermostatId, zigbee.NoManufacturer, zigbee.Endpoint(2), mock.Anything, uint8(3), map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+			SystemMode: {DataType: zcl.TypeEnum8, Value: uint64(extcaps.ThermostatCool)},
+		}).Return([]global.WriteAttributesResponseRecord{}, nil)
+
+		err := i.SetMode(context.TODO(), extcaps.ThermostatCool)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.Equal(t, extcaps.ThermostatCool, state.Mode)
+	})
+}
+
+func TestImplementation_AdjustSetpoint(t *testing.T) {
+	t.Run("sends a setpoint raise lower command in tenths of a degree", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _ := newWithMonitor(t, mzi)
+		i.remoteEndpoint = 4
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: 3,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.ThermostatId,
+			SourceEndpoint:      2,
+			DestinationEndpoint: 4,
+			CommandIdentifier:   SetpointRaiseLowerId,
+			Command:             &SetpointRaiseLower{Mode: uint8(extcaps.HeatingSetpoint), Amount: -15},
+		}).Return(nil)
+
+		err := i.AdjustSetpoint(context.TODO(), extcaps.HeatingSetpoint, -1.5)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i, _ := newWithMonitor(t, newMockZDAInterface(t))
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
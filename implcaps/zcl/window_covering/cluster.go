@@ -0,0 +1,44 @@
+package window_covering
+
+import (
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zigbee"
+)
+
+/* The zcl library does not yet provide the Window Covering cluster, the subset required is defined here. */
+
+const (
+	WindowCoveringType            = zcl.AttributeID(0x0000)
+	CurrentPositionLiftPercentage = zcl.AttributeID(0x0008)
+	CurrentPositionTiltPercentage = zcl.AttributeID(0x0009)
+)
+
+const (
+	UpOpenId             = zcl.CommandIdentifier(0x00)
+	DownCloseId          = zcl.CommandIdentifier(0x01)
+	StopId               = zcl.CommandIdentifier(0x02)
+	GoToLiftPercentageId = zcl.CommandIdentifier(0x05)
+	GoToTiltPercentageId = zcl.CommandIdentifier(0x08)
+)
+
+type UpOpen struct{}
+
+type DownClose struct{}
+
+type Stop struct{}
+
+type GoToLiftPercentage struct {
+	Percentage uint8
+}
+
+type GoToTiltPercentage struct {
+	Percentage uint8
+}
+
+func Register(cr *zcl.CommandRegistry) {
+	cr.RegisterLocal(zcl.WindowCoveringId, zigbee.NoManufacturer, zcl.ClientToServer, UpOpenId, &UpOpen{})
+	cr.RegisterLocal(zcl.WindowCoveringId, zigbee.NoManufacturer, zcl.ClientToServer, DownCloseId, &DownClose{})
+	cr.RegisterLocal(zcl.WindowCoveringId, zigbee.NoManufacturer, zcl.ClientToServer, StopId, &Stop{})
+	cr.RegisterLocal(zcl.WindowCoveringId, zigbee.NoManufacturer, zcl.ClientToServer, GoToLiftPercentageId, &GoToLiftPercentage{})
+	cr.RegisterLocal(zcl.WindowCoveringId, zigbee.NoManufacturer, zcl.ClientToServer, GoToTiltPercentageId, &GoToTiltPercentage{})
+}
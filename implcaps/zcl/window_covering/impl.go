@@ -0,0 +1,287 @@
+package window_covering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zigbee"
+	"math"
+	"time"
+)
+
+var _ extcaps.Cover = (*Implementation)(nil)
+var _ capabilities.WithLastChangeTime = (*Implementation)(nil)
+var _ capabilities.WithLastUpdateTime = (*Implementation)(nil)
+var _ implcaps.ZDACapability = (*Implementation)(nil)
+
+const WindowCoveringTypeKey = "WindowCoveringType"
+const LiftKey = "Lift"
+const TiltKey = "Tilt"
+
+// Values of the WindowCoveringType attribute which affect the capabilities offered, as per ZCL 7.4.2.1.1.
+const (
+	TypeShutter           = uint64(0x06)
+	TypeTiltBlindTilt     = uint64(0x07)
+	TypeTiltBlindLiftTilt = uint64(0x08)
+)
+
+var ErrTiltNotSupported = errors.New("cover does not support tilt")
+
+func NewWindowCovering(zi implcaps.ZDAInterface) *Implementation {
+	zi.ZCLRegister(Register)
+	return &Implementation{zi: zi, l: zi.Logger()}
+}
+
+type Implementation struct {
+	s  persistence.Section
+	d  da.Device
+	zi implcaps.ZDAInterface
+	l  logwrap.Logger
+
+	remoteEndpoint zigbee.Endpoint
+
+	liftMonitor attribute.Monitor
+	tiltMonitor attribute.Monitor
+
+	coveringType uint64
+}
+
+func (i *Implementation) Capability() da.Capability {
+	return capabilities.CoverFlag
+}
+
+func (i *Implementation) Name() string {
+	return capabilities.StandardNames[capabilities.CoverFlag]
+}
+
+func (i *Implementation) Init(d da.Device, s persistence.Section) {
+	i.d = d
+	i.s = s
+
+	i.liftMonitor = i.zi.NewAttributeMonitor()
+	i.liftMonitor.Init(s.Section("AttributeMonitor", "CurrentPositionLiftPercentage"), d, i.update)
+	i.tiltMonitor = i.zi.NewAttributeMonitor()
+	i.tiltMonitor.Init(s.Section("AttributeMonitor", "CurrentPositionTiltPercentage"), d, i.update)
+}
+
+func (i *Implementation) liftSupported() bool {
+	return i.coveringType != TypeTiltBlindTilt
+}
+
+func (i *Implementation) tiltSupported() bool {
+	return i.coveringType == TypeShutter || i.coveringType == TypeTiltBlindTilt || i.coveringType == TypeTiltBlindLiftTilt
+}
+
+// monitors returns the attribute monitors that are in use given the type of window covering.
+func (i *Implementation) monitors() map[string]attribute.Monitor {
+	m := map[string]attribute.Monitor{}
+
+	if i.liftSupported() {
+		m["CurrentPositionLiftPercentage"] = i.liftMonitor
+	}
+
+	if i.tiltSupported() {
+		m["CurrentPositionTiltPercentage"] = i.tiltMonitor
+	}
+
+	return m
+}
+
+func (i *Implementation) Load(ctx context.Context) (bool, error) {
+	if v, ok := i.s.Int(implcaps.RemoteEndpointKey); ok {
+		i.remoteEndpoint = zigbee.Endpoint(v)
+	} else {
+		return false, fmt.Errorf("monitor missing config parameter: %s", implcaps.RemoteEndpointKey)
+	}
+
+	i.coveringType, _ = i.s.UInt(WindowCoveringTypeKey)
+
+	for name, m := range i.monitors() {
+		if err := m.Load(ctx); err != nil {
+			i.l.Warn(ctx, "Failed to load window covering attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", name))
+			return false, fmt.Errorf("%s monitor, load failed: %w", name, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Enumerate(ctx context.Context, m map[string]any) (bool, error) {
+	i.remoteEndpoint = implcaps.Get(m, "ZigbeeEndpoint", zigbee.Endpoint(1))
+
+	i.s.Set(implcaps.RemoteEndpointKey, int(i.remoteEndpoint))
+
+	i.coveringType = 0
+
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+	if recs, err := i.zi.ZCLCommunicator().ReadAttributes(ctx, ieee, ack, zcl.WindowCoveringId, zigbee.NoManufacturer, localEndpoint, i.remoteEndpoint, seq, []zcl.AttributeID{WindowCoveringType}); err != nil {
+		i.l.Warn(ctx, "Failed to read window covering type, assuming lift only.", logwrap.Err(err))
+	} else if rec, found := communicator.ReadResponsesToMap(recs)[WindowCoveringType]; found && rec.Status == 0 && rec.DataTypeValue != nil {
+		if v, ok := rec.DataTypeValue.Value.(uint8); ok {
+			i.coveringType = uint64(v)
+		}
+	}
+
+	i.s.Set(WindowCoveringTypeKey, i.coveringType)
+
+	reporting := attribute.ReportingConfig{
+		Mode:             attribute.AttemptConfigureReporting,
+		MinimumInterval:  1 * time.Second,
+		MaximumInterval:  5 * time.Minute,
+		ReportableChange: uint(1),
+	}
+
+	polling := attribute.PollingConfig{
+		Mode:     attribute.PollIfReportingFailed,
+		Interval: 1 * time.Minute,
+	}
+
+	attributes := map[string]zcl.AttributeID{
+		"CurrentPositionLiftPercentage": CurrentPositionLiftPercentage,
+		"CurrentPositionTiltPercentage": CurrentPositionTiltPercentage,
+	}
+
+	for name, mon := range i.monitors() {
+		if err := mon.Attach(ctx, i.remoteEndpoint, zcl.WindowCoveringId, attributes[name], zcl.TypeUnsignedInt8, reporting, polling); err != nil {
+			i.l.Warn(ctx, "Failed to attach window covering attribute monitor.", logwrap.Err(err), logwrap.Datum("Attribute", name))
+			return false, fmt.Errorf("%s monitor, attach failed: %w", name, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (i *Implementation) Detach(ctx context.Context, detachType implcaps.DetachType) error {
+	for name, m := range i.monitors() {
+		if err := m.Detach(ctx, detachType == implcaps.NoLongerEnumerated); err != nil {
+			return fmt.Errorf("%s monitor, detach failed: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Implementation) ImplName() string {
+	return "ZCLWindowCovering"
+}
+
+func (i *Implementation) update(id zcl.AttributeID, v zcl.AttributeDataTypeValue) {
+	if v.DataType != zcl.TypeUnsignedInt8 {
+		return
+	}
+
+	var value uint64
+
+	switch raw := v.Value.(type) {
+	case uint64:
+		value = raw
+	case uint8:
+		value = uint64(raw)
+	default:
+		return
+	}
+
+	if value > 100 {
+		return
+	}
+
+	switch id {
+	case CurrentPositionLiftPercentage:
+		i.updatePosition(LiftKey, value)
+	case CurrentPositionTiltPercentage:
+		i.updatePosition(TiltKey, value)
+	}
+}
+
+func (i *Implementation) updatePosition(key string, percentage uint64) {
+	if current, found := i.s.UInt(key); !found || current != percentage {
+		i.s.Set(key, percentage)
+		converter.Store(i.s, implcaps.LastChangedKey, time.Now(), converter.TimeEncoder)
+
+		i.zi.SendEvent(extcaps.CoverUpdate{Device: i.d, State: i.state()})
+	}
+
+	converter.Store(i.s, implcaps.LastUpdatedKey, time.Now(), converter.TimeEncoder)
+}
+
+func (i *Implementation) state() extcaps.CoverState {
+	lift, _ := i.s.UInt(LiftKey)
+	tilt, _ := i.s.UInt(TiltKey)
+
+	return extcaps.CoverState{
+		LiftSupported: i.liftSupported(),
+		Lift:          float64(lift) / 100.0,
+		TiltSupported: i.tiltSupported(),
+		Tilt:          float64(tilt) / 100.0,
+	}
+}
+
+func (i *Implementation) LastUpdateTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastUpdatedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) LastChangeTime(_ context.Context) (time.Time, error) {
+	t, _ := converter.Retrieve(i.s, implcaps.LastChangedKey, converter.TimeDecoder)
+	return t, nil
+}
+
+func (i *Implementation) Status(_ context.Context) (extcaps.CoverState, error) {
+	return i.state(), nil
+}
+
+// Open fully opens the cover, the resulting position will be reported back by the attribute monitor.
+func (i *Implementation) Open(ctx context.Context) error {
+	return i.sendCommand(ctx, UpOpenId, &UpOpen{})
+}
+
+// Close fully closes the cover, the resulting position will be reported back by the attribute monitor.
+func (i *Implementation) Close(ctx context.Context) error {
+	return i.sendCommand(ctx, DownCloseId, &DownClose{})
+}
+
+func (i *Implementation) Stop(ctx context.Context) error {
+	return i.sendCommand(ctx, StopId, &Stop{})
+}
+
+func (i *Implementation) SetLift(ctx context.Context, position float64) error {
+	return i.sendCommand(ctx, GoToLiftPercentageId, &GoToLiftPercentage{Percentage: toPercentage(position)})
+}
+
+func (i *Implementation) SetTilt(ctx context.Context, position float64) error {
+	if !i.tiltSupported() {
+		return ErrTiltNotSupported
+	}
+
+	return i.sendCommand(ctx, GoToTiltPercentageId, &GoToTiltPercentage{Percentage: toPercentage(position)})
+}
+
+func (i *Implementation) sendCommand(ctx context.Context, id zcl.CommandIdentifier, cmd any) error {
+	ieee, localEndpoint, ack, seq := i.zi.TransmissionLookup(i.d, zigbee.ProfileHomeAutomation)
+
+	return i.zi.ZCLCommunicator().Request(ctx, ieee, ack, zcl.Message{
+		FrameType:           zcl.FrameLocal,
+		Direction:           zcl.ClientToServer,
+		TransactionSequence: seq,
+		Manufacturer:        zigbee.NoManufacturer,
+		ClusterID:           zcl.WindowCoveringId,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: i.remoteEndpoint,
+		CommandIdentifier:   id,
+		Command:             cmd,
+	})
+}
+
+func toPercentage(position float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, position)) * 100))
+}
@@ -0,0 +1,291 @@
+package window_covering
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	mocks2 "github.com/shimmeringbee/da/mocks"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/extcaps"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newMockZDAInterface(t *testing.T) *implcaps.MockZDAInterface {
+	mzi := &implcaps.MockZDAInterface{}
+	t.Cleanup(func() { mzi.AssertExpectations(t) })
+
+	mzi.On("ZCLRegister", mock.Anything)
+	mzi.On("Logger").Return(logwrap.New(discard.Discard()))
+
+	return mzi
+}
+
+func newWithMonitors(t *testing.T, mzi *implcaps.MockZDAInterface) (*Implementation, *attribute.MockMonitor, *attribute.MockMonitor) {
+	lift := &attribute.MockMonitor{}
+	t.Cleanup(func() { lift.AssertExpectations(t) })
+
+	tilt := &attribute.MockMonitor{}
+	t.Cleanup(func() { tilt.AssertExpectations(t) })
+
+	i := NewWindowCovering(mzi)
+	i.s = memory.New()
+	i.liftMonitor = lift
+	i.tiltMonitor = tilt
+
+	return i, lift, tilt
+}
+
+func TestImplementation_BaseFunctions(t *testing.T) {
+	t.Run("basic static functions respond correctly", func(t *testing.T) {
+		i := NewWindowCovering(newMockZDAInterface(t))
+
+		assert.Equal(t, capabilities.CoverFlag, i.Capability())
+		assert.Equal(t, capabilities.StandardNames[capabilities.CoverFlag], i.Name())
+		assert.Equal(t, "ZCLWindowCovering", i.ImplName())
+	})
+}
+
+func TestImplementation_Init(t *testing.T) {
+	t.Run("constructs attribute monitors correctly initialising them", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mzi.On("NewAttributeMonitor").Return(mm).Twice()
+
+		md := &mocks2.MockDevice{}
+		defer md.AssertExpectations(t)
+
+		mm.On("Init", mock.Anything, md, mock.Anything).Twice()
+
+		i := NewWindowCovering(mzi)
+		i.Init(md, memory.New())
+	})
+}
+
+func TestImplementation_Load(t *testing.T) {
+	t.Run("loads only the lift monitor for a roller shade", func(t *testing.T) {
+		i, lift, _ := newWithMonitors(t, newMockZDAInterface(t))
+		lift.On("Load", mock.Anything).Return(nil)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+		i.s.Set(WindowCoveringTypeKey, uint64(0x00))
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+
+	t.Run("loads both monitors for a lift and tilt blind", func(t *testing.T) {
+		i, lift, tilt := newWithMonitors(t, newMockZDAInterface(t))
+		lift.On("Load", mock.Anything).Return(nil)
+		tilt.On("Load", mock.Anything).Return(nil)
+
+		i.s.Set(implcaps.RemoteEndpointKey, 2)
+		i.s.Set(WindowCoveringTypeKey, TypeTiltBlindLiftTilt)
+
+		attached, err := i.Load(context.TODO())
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails if the remote endpoint is missing", func(t *testing.T) {
+		i, _, _ := newWithMonitors(t, newMockZDAInterface(t))
+
+		attached, err := i.Load(context.TODO())
+
+		assert.False(t, attached)
+		assert.Error(t, err)
+	})
+}
+
+func TestImplementation_Enumerate(t *testing.T) {
+	t.Run("reads the covering type and attaches the tilt monitor for tilt only blinds", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, tilt := newWithMonitors(t, mzi)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+
+		mzc.On("ReadAttributes", mock.Anything, zigbee.IEEEAddress(1), false, zcl.WindowCoveringId, zigbee.NoManufacturer, zigbee.Endpoint(2), zigbee.Endpoint(4), uint8(3), []zcl.AttributeID{WindowCoveringType}).Return([]global.ReadAttributeResponseRecord{
+			{Identifier: WindowCoveringType, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeEnum8, Value: uint8(TypeTiltBlindTilt)}},
+		}, nil)
+
+		tilt.On("Attach", mock.Anything, zigbee.Endpoint(4), zcl.WindowCoveringId, CurrentPositionTiltPercentage, zcl.TypeUnsignedInt8, mock.Anything, mock.Anything).Return(nil)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{"ZigbeeEndpoint": zigbee.Endpoint(4)})
+
+		assert.True(t, attached)
+		assert.NoError(t, err)
+
+		state, _ := i.Status(context.TODO())
+		assert.False(t, state.LiftSupported)
+		assert.True(t, state.TiltSupported)
+	})
+
+	t.Run("assumes lift only if the covering type can not be read", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, lift, _ := newWithMonitors(t, mzi)
+
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+
+		mzc.On("ReadAttributes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]global.ReadAttributeResponseRecord{}, io.EOF)
+
+		lift.On("Attach", mock.Anything, zigbee.Endpoint(1), zcl.WindowCoveringId, CurrentPositionLiftPercentage, zcl.TypeUnsignedInt8, mock.Anything, mock.Anything).Return(io.EOF)
+
+		attached, err := i.Enumerate(context.TODO(), map[string]any{})
+
+		assert.False(t, attached)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestImplementation_Detach(t *testing.T) {
+	t.Run("detaches the monitors in use", func(t *testing.T) {
+		i, lift, _ := newWithMonitors(t, newMockZDAInterface(t))
+		lift.On("Detach", mock.Anything, true).Return(nil)
+
+		err := i.Detach(context.TODO(), implcaps.NoLongerEnumerated)
+		assert.NoError(t, err)
+	})
+}
+
+func TestImplementation_update(t *testing.T) {
+	t.Run("updates lift position, sending an event on change", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, _ := newWithMonitors(t, mzi)
+
+		mzi.On("SendEvent", extcaps.CoverUpdate{State: extcaps.CoverState{LiftSupported: true, Lift: 0.4}}).Once()
+
+		i.update(CurrentPositionLiftPercentage, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(40)})
+		i.update(CurrentPositionLiftPercentage, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint8(40)})
+
+		lct, _ := i.LastChangeTime(context.TODO())
+		assert.NotZero(t, lct)
+	})
+
+	t.Run("ignores out of range positions", func(t *testing.T) {
+		i, _, _ := newWithMonitors(t, newMockZDAInterface(t))
+
+		i.update(CurrentPositionLiftPercentage, zcl.AttributeDataTypeValue{DataType: zcl.TypeUnsignedInt8, Value: uint64(0xff)})
+
+		_, found := i.s.UInt(LiftKey)
+		assert.False(t, found)
+	})
+}
+
+func TestImplementation_Commands(t *testing.T) {
+	expectCommand := func(t *testing.T, mzi *implcaps.MockZDAInterface, id zcl.CommandIdentifier, cmd any) {
+		mzc := &mocks.MockZCLCommunicator{}
+		t.Cleanup(func() { mzc.AssertExpectations(t) })
+
+		mzi.On("ZCLCommunicator").Return(mzc)
+		mzi.On("TransmissionLookup", mock.Anything, zigbee.ProfileHomeAutomation).Return(zigbee.IEEEAddress(1), zigbee.Endpoint(2), false, 3)
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ClientToServer,
+			TransactionSequence: 3,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.WindowCoveringId,
+			SourceEndpoint:      2,
+			DestinationEndpoint: 4,
+			CommandIdentifier:   id,
+			Command:             cmd,
+		}).Return(nil)
+	}
+
+	t.Run("open sends up open", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, _ := newWithMonitors(t, mzi)
+		i.remoteEndpoint = 4
+		expectCommand(t, mzi, UpOpenId, &UpOpen{})
+
+		assert.NoError(t, i.Open(context.TODO()))
+	})
+
+	t.Run("close sends down close", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, _ := newWithMonitors(t, mzi)
+		i.remoteEndpoint = 4
+		expectCommand(t, mzi, DownCloseId, &DownClose{})
+
+		assert.NoError(t, i.Close(context.TODO()))
+	})
+
+	t.Run("stop sends stop", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, _ := newWithMonitors(t, mzi)
+		i.remoteEndpoint = 4
+		expectCommand(t, mzi, StopId, &Stop{})
+
+		assert.NoError(t, i.Stop(context.TODO()))
+	})
+
+	t.Run("set lift sends go to lift percentage", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, _ := newWithMonitors(t, mzi)
+		i.remoteEndpoint = 4
+		expectCommand(t, mzi, GoToLiftPercentageId, &GoToLiftPercentage{Percentage: 75})
+
+		assert.NoError(t, i.SetLift(context.TODO(), 0.75))
+	})
+
+	t.Run("set tilt sends go to tilt percentage if supported", func(t *testing.T) {
+		mzi := newMockZDAInterface(t)
+		i, _, _ := newWithMonitors(t, mzi)
+		i.remoteEndpoint = 4
+		i.coveringType = TypeTiltBlindLiftTilt
+		expectCommand(t, mzi, GoToTiltPercentageId, &GoToTiltPercentage{Percentage: 100})
+
+		assert.NoError(t, i.SetTilt(context.TODO(), 1.5))
+	})
+
+	t.Run("set tilt fails if tilt is not supported", func(t *testing.T) {
+		i, _, _ := newWithMonitors(t, newMockZDAInterface(t))
+
+		assert.ErrorIs(t, i.SetTilt(context.TODO(), 0.5), ErrTiltNotSupported)
+	})
+}
+
+func TestImplementation_LastTimes(t *testing.T) {
+	t.Run("returns the last updated and changed times", func(t *testing.T) {
+		i, _, _ := newWithMonitors(t, newMockZDAInterface(t))
+
+		changedTime := time.UnixMilli(time.Now().UnixMilli())
+		updatedTime := changedTime.Add(5 * time.Minute)
+
+		converter.Store(i.s, implcaps.LastUpdatedKey, updatedTime, converter.TimeEncoder)
+		converter.Store(i.s, implcaps.LastChangedKey, changedTime, converter.TimeEncoder)
+
+		lct, err := i.LastChangeTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, changedTime, lct)
+
+		lut, err := i.LastUpdateTime(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, updatedTime, lut)
+	})
+}
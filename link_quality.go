@@ -0,0 +1,41 @@
+package zda
+
+import (
+	"github.com/shimmeringbee/zda/implcaps"
+	"sync"
+	"time"
+)
+
+// LinkQualityAverageWeight is the weight given to each new frame's LQI in a node's rolling average, the average
+// represents roughly the last 1/LinkQualityAverageWeight frames.
+const LinkQualityAverageWeight = 0.125
+
+type linkQuality struct {
+	m       *sync.Mutex
+	quality implcaps.LinkQuality
+}
+
+func newLinkQuality() *linkQuality {
+	return &linkQuality{m: &sync.Mutex{}}
+}
+
+func (l *linkQuality) record(lqi uint8, t time.Time) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.quality.Received.IsZero() {
+		l.quality.Average = float64(lqi)
+	} else {
+		l.quality.Average += (float64(lqi) - l.quality.Average) * LinkQualityAverageWeight
+	}
+
+	l.quality.Last = lqi
+	l.quality.Received = t
+}
+
+func (l *linkQuality) get() (implcaps.LinkQuality, bool) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.quality, !l.quality.Received.IsZero()
+}
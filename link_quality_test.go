@@ -0,0 +1,29 @@
+package zda
+
+import (
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_linkQuality(t *testing.T) {
+	t.Run("reports nothing until a frame is recorded", func(t *testing.T) {
+		l := newLinkQuality()
+
+		_, found := l.get()
+		assert.False(t, found)
+	})
+
+	t.Run("the first frame sets the average, later frames are weighted into it", func(t *testing.T) {
+		l := newLinkQuality()
+		now := time.Now()
+
+		l.record(200, now.Add(-time.Second))
+		l.record(120, now)
+
+		q, found := l.get()
+		assert.True(t, found)
+		assert.Equal(t, implcaps.LinkQuality{Last: 120, Average: 190, Received: now}, q)
+	})
+}
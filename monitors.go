@@ -0,0 +1,119 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zigbee"
+	"sync"
+	"time"
+)
+
+// nodeMonitors tracks the attribute monitors attached to a node, so that the longest a node should be silent for can
+// be derived from the reporting and polling intervals configured on it, and so that their reporting can be verified
+// when the node rejoins.
+type nodeMonitors struct {
+	m        *sync.Mutex
+	monitors map[attribute.Monitor]time.Duration
+}
+
+func newNodeMonitors() *nodeMonitors {
+	return &nodeMonitors{m: &sync.Mutex{}, monitors: make(map[attribute.Monitor]time.Duration)}
+}
+
+func (e *nodeMonitors) track(m attribute.Monitor, interval time.Duration) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.monitors[m] = interval
+}
+
+func (e *nodeMonitors) forget(m attribute.Monitor) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	delete(e.monitors, m)
+}
+
+// expectedInterval returns the shortest interval of any monitor on the node, a node must be heard from at least this
+// often. False is returned if no monitor on the node has an expected interval.
+func (e *nodeMonitors) expectedInterval() (time.Duration, bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	var shortest time.Duration
+
+	for _, interval := range e.monitors {
+		if interval > 0 && (shortest == 0 || interval < shortest) {
+			shortest = interval
+		}
+	}
+
+	return shortest, shortest > 0
+}
+
+func (e *nodeMonitors) all() []attribute.Monitor {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	var monitors []attribute.Monitor
+
+	for m := range e.monitors {
+		monitors = append(monitors, m)
+	}
+
+	return monitors
+}
+
+var _ attribute.Monitor = (*trackedMonitor)(nil)
+
+// trackedMonitor wraps an attribute monitor provided to capabilities, recording its expected interval against the
+// node once it has been attached or loaded.
+type trackedMonitor struct {
+	attribute.Monitor
+	gw *ZDA
+
+	address zigbee.IEEEAddress
+}
+
+func (t *trackedMonitor) Init(s persistence.Section, d da.Device, cb attribute.MonitorCallback) {
+	if addr, ok := d.Identifier().(IEEEAddressWithSubIdentifier); ok {
+		t.address = addr.IEEEAddress
+	}
+
+	t.Monitor.Init(s, d, cb)
+}
+
+func (t *trackedMonitor) Load(ctx context.Context) error {
+	if err := t.Monitor.Load(ctx); err != nil {
+		return err
+	}
+
+	t.track()
+	return nil
+}
+
+func (t *trackedMonitor) Attach(ctx context.Context, e zigbee.Endpoint, c zigbee.ClusterID, a zcl.AttributeID, dt zcl.AttributeDataType, rc attribute.ReportingConfig, pc attribute.PollingConfig) error {
+	if err := t.Monitor.Attach(ctx, e, c, a, dt, rc, pc); err != nil {
+		return err
+	}
+
+	t.track()
+	return nil
+}
+
+func (t *trackedMonitor) Detach(ctx context.Context, unconfigure bool) error {
+	if n := t.gw.getNode(t.address); n != nil {
+		n.monitors.forget(t)
+	}
+
+	return t.Monitor.Detach(ctx, unconfigure)
+}
+
+func (t *trackedMonitor) track() {
+	if n := t.gw.getNode(t.address); n != nil {
+		n.monitors.track(t, t.Monitor.ExpectedInterval())
+	}
+}
@@ -0,0 +1,70 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func Test_nodeMonitors(t *testing.T) {
+	t.Run("reports nothing if no monitor has an interval", func(t *testing.T) {
+		e := newNodeMonitors()
+		e.track(&attribute.MockMonitor{}, 0)
+
+		_, found := e.expectedInterval()
+		assert.False(t, found)
+	})
+
+	t.Run("returns the shortest interval of tracked monitors", func(t *testing.T) {
+		e := newNodeMonitors()
+
+		short := &attribute.MockMonitor{}
+		e.track(&attribute.MockMonitor{}, 10*time.Minute)
+		e.track(short, time.Minute)
+
+		interval, found := e.expectedInterval()
+		assert.True(t, found)
+		assert.Equal(t, time.Minute, interval)
+
+		e.forget(short)
+
+		interval, _ = e.expectedInterval()
+		assert.Equal(t, 10*time.Minute, interval)
+	})
+}
+
+func Test_trackedMonitor(t *testing.T) {
+	t.Run("records the monitors interval against the node once attached, and forgets it on detach", func(t *testing.T) {
+		gw, _, _, stop := newTestGateway()
+		defer stop(t)
+
+		n, _ := gw.createNode(zigbee.GenerateLocalAdministeredIEEEAddress())
+		d := gw.createNextDevice(n)
+
+		mm := &attribute.MockMonitor{}
+		defer mm.AssertExpectations(t)
+
+		mm.On("Init", mock.Anything, d, mock.Anything)
+		mm.On("Attach", mock.Anything, zigbee.Endpoint(1), zigbee.ClusterID(2), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mm.On("ExpectedInterval").Return(5 * time.Minute)
+		mm.On("Detach", mock.Anything, true).Return(nil)
+
+		tm := &trackedMonitor{Monitor: mm, gw: gw}
+		tm.Init(nil, d, nil)
+
+		assert.NoError(t, tm.Attach(context.Background(), 1, 2, 3, 0, attribute.ReportingConfig{}, attribute.PollingConfig{}))
+
+		interval, found := zdaInterface{gw: gw}.ExpectedInterval(n.address)
+		assert.True(t, found)
+		assert.Equal(t, 5*time.Minute, interval)
+
+		assert.NoError(t, tm.Detach(context.Background(), true))
+
+		_, found = zdaInterface{gw: gw}.ExpectedInterval(n.address)
+		assert.False(t, found)
+	})
+}
@@ -76,6 +76,9 @@ type node struct {
 	sequence         chan uint8
 	enumerationSem   *semaphore.Weighted
 	enumerationState bool
+	commandQueue     *commandQueue
+	linkQuality      *linkQuality
+	monitors         *nodeMonitors
 
 	// Mutable data, obtain lock first.
 	device    map[uint8]*device
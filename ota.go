@@ -0,0 +1,9 @@
+package zda
+
+import "github.com/shimmeringbee/zda/ota"
+
+// WithOTAImageStore provides the store of OTA upgrade images which devices will be upgraded with, without a store
+// devices will always be told no image is available.
+func (z *ZDA) WithOTAImageStore(s ota.ImageStore) {
+	z.otaImageStore = s
+}
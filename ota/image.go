@@ -0,0 +1,76 @@
+package ota
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FileIdentifier is the magic number present at the start of every Zigbee OTA upgrade file.
+const FileIdentifier = uint32(0x0beef11e)
+
+// minimumHeaderLength is the length of the mandatory fields of the OTA header, as per ZCL 11.4.2.
+const minimumHeaderLength = 56
+
+var ErrNotOTAImage = errors.New("file is not a zigbee ota image")
+
+// Header is the mandatory portion of a Zigbee OTA upgrade file header.
+type Header struct {
+	HeaderVersion    uint16
+	HeaderLength     uint16
+	FieldControl     uint16
+	ManufacturerCode uint16
+	ImageType        uint16
+	FileVersion      uint32
+	StackVersion     uint16
+	HeaderString     string
+	TotalImageSize   uint32
+}
+
+type rawHeader struct {
+	FileIdentifier   uint32
+	HeaderVersion    uint16
+	HeaderLength     uint16
+	FieldControl     uint16
+	ManufacturerCode uint16
+	ImageType        uint16
+	FileVersion      uint32
+	StackVersion     uint16
+	HeaderString     [32]byte
+	TotalImageSize   uint32
+}
+
+// ParseHeader reads the OTA header from the start of the reader provided.
+func ParseHeader(r io.Reader) (Header, error) {
+	var raw rawHeader
+
+	if err := binary.Read(r, binary.LittleEndian, &raw); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Header{}, ErrNotOTAImage
+		}
+
+		return Header{}, err
+	}
+
+	if raw.FileIdentifier != FileIdentifier {
+		return Header{}, ErrNotOTAImage
+	}
+
+	if raw.HeaderLength < minimumHeaderLength || raw.TotalImageSize < uint32(raw.HeaderLength) {
+		return Header{}, fmt.Errorf("%w: invalid header length %d", ErrNotOTAImage, raw.HeaderLength)
+	}
+
+	return Header{
+		HeaderVersion:    raw.HeaderVersion,
+		HeaderLength:     raw.HeaderLength,
+		FieldControl:     raw.FieldControl,
+		ManufacturerCode: raw.ManufacturerCode,
+		ImageType:        raw.ImageType,
+		FileVersion:      raw.FileVersion,
+		StackVersion:     raw.StackVersion,
+		HeaderString:     strings.TrimRight(string(raw.HeaderString[:]), "\x00"),
+		TotalImageSize:   raw.TotalImageSize,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package ota
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeImage(manufacturer uint16, imageType uint16, version uint32, payload []byte) []byte {
+	raw := rawHeader{
+		FileIdentifier:   FileIdentifier,
+		HeaderVersion:    0x0100,
+		HeaderLength:     minimumHeaderLength,
+		ManufacturerCode: manufacturer,
+		ImageType:        imageType,
+		FileVersion:      version,
+		StackVersion:     0x0002,
+		TotalImageSize:   uint32(minimumHeaderLength + len(payload)),
+	}
+	copy(raw.HeaderString[:], "Test Image")
+
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, raw)
+	buf.Write(payload)
+
+	return buf.Bytes()
+}
+
+func TestParseHeader(t *testing.T) {
+	t.Run("parses a valid ota header", func(t *testing.T) {
+		h, err := ParseHeader(bytes.NewReader(makeImage(0x1234, 0x0001, 0x00000010, []byte{0x01, 0x02})))
+		assert.NoError(t, err)
+
+		assert.Equal(t, uint16(0x1234), h.ManufacturerCode)
+		assert.Equal(t, uint16(0x0001), h.ImageType)
+		assert.Equal(t, uint32(0x00000010), h.FileVersion)
+		assert.Equal(t, "Test Image", h.HeaderString)
+		assert.Equal(t, uint32(58), h.TotalImageSize)
+	})
+
+	t.Run("rejects data without the ota file identifier", func(t *testing.T) {
+		_, err := ParseHeader(bytes.NewReader(make([]byte, 64)))
+		assert.ErrorIs(t, err, ErrNotOTAImage)
+	})
+
+	t.Run("rejects truncated data", func(t *testing.T) {
+		_, err := ParseHeader(bytes.NewReader([]byte{0x1e, 0xf1, 0xee, 0x0b}))
+		assert.ErrorIs(t, err, ErrNotOTAImage)
+	})
+}
@@ -0,0 +1,22 @@
+package ota
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockImageStore struct {
+	mock.Mock
+}
+
+func (m *MockImageStore) Latest(ctx context.Context, manufacturer uint16, imageType uint16) (Image, bool, error) {
+	args := m.Called(ctx, manufacturer, imageType)
+	return args.Get(0).(Image), args.Bool(1), args.Error(2)
+}
+
+func (m *MockImageStore) Read(ctx context.Context, image Image, offset uint32, size int) ([]byte, error) {
+	args := m.Called(ctx, image, offset, size)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+var _ ImageStore = (*MockImageStore)(nil)
@@ -0,0 +1,109 @@
+package ota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Image is an OTA upgrade image held within an ImageStore.
+type Image struct {
+	Header
+	// Reference identifies the image within the store it was found in.
+	Reference string
+}
+
+// ImageStore provides OTA upgrade images to be served to devices.
+type ImageStore interface {
+	// Latest returns the image with the highest file version for the manufacturer and image type, if one exists.
+	Latest(ctx context.Context, manufacturer uint16, imageType uint16) (Image, bool, error)
+	// Read returns up to size bytes of the image, starting at offset. Fewer bytes are returned at the end of the image.
+	Read(ctx context.Context, image Image, offset uint32, size int) ([]byte, error)
+}
+
+// FileExtension is the extension of files which DirectoryStore will consider to be OTA images.
+const FileExtension = ".ota"
+
+var _ ImageStore = (*DirectoryStore)(nil)
+
+// NewDirectoryStore constructs an ImageStore which serves the OTA images in a local directory. The directory is
+// scanned upon each request, so images can be added or removed without restarting.
+func NewDirectoryStore(path string) *DirectoryStore {
+	return &DirectoryStore{path: path}
+}
+
+type DirectoryStore struct {
+	path string
+}
+
+func (d *DirectoryStore) Latest(_ context.Context, manufacturer uint16, imageType uint16) (Image, bool, error) {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return Image{}, false, fmt.Errorf("failed to read ota image directory: %w", err)
+	}
+
+	var latest Image
+	var found bool
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), FileExtension) {
+			continue
+		}
+
+		header, err := d.header(entry.Name())
+		if err != nil {
+			/* Invalid images are ignored, they should not prevent valid images from being served. */
+			continue
+		}
+
+		if header.ManufacturerCode != manufacturer || header.ImageType != imageType {
+			continue
+		}
+
+		if !found || header.FileVersion > latest.FileVersion {
+			latest = Image{Header: header, Reference: entry.Name()}
+			found = true
+		}
+	}
+
+	return latest, found, nil
+}
+
+func (d *DirectoryStore) header(name string) (Header, error) {
+	f, err := os.Open(filepath.Join(d.path, name))
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+
+	return ParseHeader(f)
+}
+
+func (d *DirectoryStore) Read(_ context.Context, image Image, offset uint32, size int) ([]byte, error) {
+	if offset >= image.TotalImageSize {
+		return nil, fmt.Errorf("offset %d beyond end of image", offset)
+	}
+
+	if remaining := int(image.TotalImageSize - offset); size > remaining {
+		size = remaining
+	}
+
+	f, err := os.Open(filepath.Join(d.path, filepath.Base(image.Reference)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, size)
+
+	n, err := f.ReadAt(data, int64(offset))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return data[:n], nil
+}
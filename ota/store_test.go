@@ -0,0 +1,51 @@
+package ota
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryStore(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "old.ota"), makeImage(0x1234, 0x0001, 1, []byte{0xaa}), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new.ota"), makeImage(0x1234, 0x0001, 2, []byte{0x01, 0x02, 0x03}), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.ota"), makeImage(0x1234, 0x0002, 5, []byte{0xbb}), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.ota"), []byte("not an image"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.bin"), makeImage(0x1234, 0x0001, 9, []byte{0xcc}), 0600))
+
+	s := NewDirectoryStore(dir)
+
+	t.Run("returns the latest image for the manufacturer and image type", func(t *testing.T) {
+		img, found, err := s.Latest(context.TODO(), 0x1234, 0x0001)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, uint32(2), img.FileVersion)
+		assert.Equal(t, "new.ota", img.Reference)
+	})
+
+	t.Run("returns not found if no image matches", func(t *testing.T) {
+		_, found, err := s.Latest(context.TODO(), 0x4321, 0x0001)
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("reads blocks of the image, truncated at the end of the image", func(t *testing.T) {
+		img, _, _ := s.Latest(context.TODO(), 0x1234, 0x0001)
+
+		data, err := s.Read(context.TODO(), img, minimumHeaderLength+1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x02, 0x03}, data)
+
+		_, err = s.Read(context.TODO(), img, img.TotalImageSize, 10)
+		assert.Error(t, err)
+	})
+
+	t.Run("errors if the directory does not exist", func(t *testing.T) {
+		_, _, err := NewDirectoryStore(filepath.Join(dir, "missing")).Latest(context.TODO(), 0x1234, 0x0001)
+		assert.Error(t, err)
+	})
+}
@@ -2,6 +2,8 @@ package zda
 
 import (
 	"github.com/shimmeringbee/persistence"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps/zcl/alarm_sensor"
 	"github.com/shimmeringbee/zigbee"
 	"strconv"
 )
@@ -45,3 +47,69 @@ func (z *ZDA) deviceListFromPersistence(id zigbee.IEEEAddress) []IEEEAddressWith
 
 	return deviceList
 }
+
+// sectionMoveNode moves the persistence of a node to a new address, replacing any existing section at that address.
+func (z *ZDA) sectionMoveNode(from zigbee.IEEEAddress, to zigbee.IEEEAddress) {
+	z.sectionRemoveNode(to)
+	copySection(z.sectionForNode(to), z.sectionForNode(from))
+	z.sectionRemoveNode(from)
+}
+
+func copySection(dst persistence.Section, src persistence.Section) {
+	for _, k := range src.Keys() {
+		switch src.Type(k) {
+		case persistence.Int:
+			v, _ := src.Int(k)
+			dst.Set(k, v)
+		case persistence.UnsignedInt:
+			v, _ := src.UInt(k)
+			dst.Set(k, v)
+		case persistence.String:
+			v, _ := src.String(k)
+			dst.Set(k, v)
+		case persistence.Bool:
+			v, _ := src.Bool(k)
+			dst.Set(k, v)
+		case persistence.Float:
+			v, _ := src.Float(k)
+			dst.Set(k, v)
+		case persistence.Bytes:
+			v, _ := src.Bytes(k)
+			dst.Set(k, v)
+		}
+	}
+
+	for _, k := range src.SectionKeys() {
+		copySection(dst.Section(k), src.Section(k))
+	}
+}
+
+// hardwareStateKeys record configuration performed upon a node's hardware, such as enrollment or reporting, which does
+// not hold for a replacement node.
+var hardwareStateKeys = []string{
+	alarm_sensor.EnrolledKey,
+	alarm_sensor.ZoneIDKey,
+	attribute.ReportingConfiguredKey,
+	attribute.ReportingMinimumIntervalKey,
+	attribute.ReportingMaximumIntervalKey,
+	attribute.ReportableChangeKey,
+	attribute.PollingConfiguredKey,
+}
+
+// sectionClearHardwareState removes the hardware state from the capabilities of each device on a node, so that they
+// configure the node again when next enumerated.
+func (z *ZDA) sectionClearHardwareState(i zigbee.IEEEAddress) {
+	for _, id := range z.deviceListFromPersistence(i) {
+		clearSectionKeys(z.sectionForDevice(id).Section("Capability"), hardwareStateKeys)
+	}
+}
+
+func clearSectionKeys(s persistence.Section, keys []string) {
+	for _, k := range keys {
+		s.Delete(k)
+	}
+
+	for _, k := range s.SectionKeys() {
+		clearSectionKeys(s.Section(k), keys)
+	}
+}
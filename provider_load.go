@@ -11,7 +11,13 @@ func (z *ZDA) providerLoad() {
 	ctx, end := z.logger.Segment(z.ctx, "Loading persistence.")
 	defer end()
 
+	z.purgeTombstones(ctx)
+
 	for _, i := range z.nodeListFromPersistence() {
+		if _, departed := z.nodeDeparted(i); departed {
+			continue
+		}
+
 		z.providerLoadNode(ctx, i)
 	}
 }
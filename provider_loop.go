@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/shimmeringbee/logwrap"
 	"github.com/shimmeringbee/zigbee"
+	"time"
 )
 
 func (z *ZDA) providerLoop() {
@@ -36,20 +37,31 @@ func (z *ZDA) providerLoop() {
 func (z *ZDA) receiveNodeJoinEvent(e zigbee.NodeJoinEvent) {
 	z.logger.LogInfo(z.ctx, "Node has joined zigbee network.", logwrap.Datum("IEEEAddress", e.IEEEAddress.String()))
 
-	if n, created := z.createNode(e.IEEEAddress); created {
+	if n, restored := z.restoreNode(z.ctx, e.IEEEAddress); restored {
+		z.logger.LogInfo(z.ctx, "Restored departed node from tombstone.", logwrap.Datum("IEEEAddress", e.IEEEAddress.String()))
+
+		if err := z.callbacks.Call(z.ctx, nodeJoin{n: n}); err != nil {
+			z.logger.LogError(z.ctx, "Error occurred while advertising node join.", logwrap.Err(err), logwrap.Datum("IEEEAddress", e.IEEEAddress.String()))
+		}
+	} else if n, created := z.createNode(e.IEEEAddress); created {
 		d := z.createNextDevice(n)
 		z.logger.LogInfo(z.ctx, "Created default device.", logwrap.Datum("Identifier", d.address.String()))
 
 		if err := z.callbacks.Call(z.ctx, nodeJoin{n: n}); err != nil {
 			z.logger.LogError(z.ctx, "Error occurred while advertising node join.", logwrap.Err(err), logwrap.Datum("Identifier", d.address.String()))
 		}
+	} else {
+		go z.rejoin(z.ctx, n)
 	}
 }
 
 func (z *ZDA) receiveNodeLeaveEvent(e zigbee.NodeLeaveEvent) {
 	z.logger.LogInfo(z.ctx, "Node has left zigbee network.", logwrap.Datum("IEEEAddress", e.IEEEAddress.String()))
 
-	if n := z.getNode(e.IEEEAddress); n != nil {
+	if n := z.getNode(e.IEEEAddress); n != nil && z.tombstoneRetention > 0 {
+		z.tombstoneNode(z.ctx, n)
+		z.purgeTombstones(z.ctx)
+	} else if n != nil {
 		for _, d := range z.getDevicesOnNode(n) {
 			_ = z.logger.SegmentFn(z.ctx, "Device leaving zigbee network.", logwrap.Datum("Identifier", d.address.String()))(func(ctx context.Context) error {
 				z.logger.LogInfo(ctx, "Remove device upon node leaving zigbee network.")
@@ -65,6 +77,10 @@ func (z *ZDA) receiveNodeLeaveEvent(e zigbee.NodeLeaveEvent) {
 }
 
 func (z *ZDA) receiveNodeIncomingMessageEvent(e zigbee.NodeIncomingMessageEvent) {
+	if n := z.getNode(e.IEEEAddress); n != nil {
+		n.linkQuality.record(e.LinkQuality, time.Now())
+	}
+
 	if err := z.zclCommunicator.ProcessIncomingMessage(e); err != nil {
 		z.logger.LogWarn(z.ctx, "ZCL communicator failed to process incoming message.", logwrap.Datum("IEEEAddress", e.IEEEAddress.String()), logwrap.Err(err))
 		return
@@ -5,11 +5,13 @@ import (
 	"github.com/shimmeringbee/logwrap"
 	"github.com/shimmeringbee/logwrap/impl/discard"
 	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zda/mocks"
 	"github.com/shimmeringbee/zigbee"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"io"
 	"testing"
+	"time"
 )
 
 func Test_gateway_receiveNodeJoinEvent(t *testing.T) {
@@ -83,3 +85,28 @@ func Test_gateway_receiveNodeLeaveEvent(t *testing.T) {
 		assert.Nil(t, d)
 	})
 }
+
+func Test_gateway_receiveNodeIncomingMessageEvent(t *testing.T) {
+	t.Run("records the link quality of the frame against the node", func(t *testing.T) {
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		g := New(context.Background(), memory.New(), nil, nil)
+		g.events = make(chan any, 0xffff)
+		g.WithLogWrapLogger(logwrap.New(discard.Discard()))
+		g.zclCommunicator = mzc
+
+		addr := zigbee.GenerateLocalAdministeredIEEEAddress()
+		n, _ := g.createNode(addr)
+
+		e := zigbee.NodeIncomingMessageEvent{Node: zigbee.Node{IEEEAddress: addr}, IncomingMessage: zigbee.IncomingMessage{LinkQuality: 150}}
+		mzc.On("ProcessIncomingMessage", e).Return(nil)
+
+		g.receiveNodeIncomingMessageEvent(e)
+
+		q, found := n.linkQuality.get()
+		assert.True(t, found)
+		assert.Equal(t, uint8(150), q.Last)
+		assert.WithinDuration(t, time.Now(), q.Received, time.Second)
+	})
+}
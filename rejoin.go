@@ -0,0 +1,80 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/zda/attribute"
+	"time"
+)
+
+// RejoinPolicy controls how ZDA handles a known node rejoining the network, such as after a power cut or a factory
+// reset. A node which has lost the reporting configuration of its attribute monitors is considered factory reset.
+type RejoinPolicy int
+
+const (
+	// RejoinIgnore takes no action when a known node rejoins.
+	RejoinIgnore RejoinPolicy = iota
+	// RejoinRefreshReporting verifies the reporting configuration of every attribute monitor on the node, and if any has
+	// been lost every attribute monitor on the node binds and configures reporting again.
+	RejoinRefreshReporting
+	// RejoinReenumerate verifies the reporting configuration of every attribute monitor on the node, and if any has
+	// been lost the node is fully enumerated again.
+	RejoinReenumerate
+)
+
+// RejoinVerificationDurationMax is the longest the reporting configuration of a rejoining node will be verified for.
+const RejoinVerificationDurationMax = 1 * time.Minute
+
+// WithRejoinPolicy sets how known nodes which rejoin the network are handled. Defaults to RejoinIgnore.
+func (z *ZDA) WithRejoinPolicy(p RejoinPolicy) {
+	z.rejoinPolicy = p
+}
+
+func (z *ZDA) rejoin(pctx context.Context, n *node) {
+	if z.rejoinPolicy == RejoinIgnore {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pctx, RejoinVerificationDurationMax)
+	defer cancel()
+
+	ctx, segmentEnd := z.logger.Segment(ctx, "Node rejoin.", logwrap.Datum("IEEEAddress", n.address.String()))
+	defer segmentEnd()
+
+	lost := z.lostReporting(ctx, n)
+	if len(lost) == 0 {
+		z.logger.LogInfo(ctx, "Rejoined node retains its reporting configuration.")
+		return
+	}
+
+	z.logger.LogWarn(ctx, "Rejoined node has lost its reporting configuration, it has likely been factory reset.", logwrap.Datum("LostMonitors", len(lost)))
+
+	switch z.rejoinPolicy {
+	case RejoinRefreshReporting:
+		for _, m := range n.monitors.all() {
+			if err := m.RefreshReporting(ctx); err != nil {
+				z.logger.LogError(ctx, "Failed to refresh reporting configuration of rejoined node.", logwrap.Err(err))
+			}
+		}
+	case RejoinReenumerate:
+		if err := z.callbacks.Call(ctx, nodeReenumerate{n: n}); err != nil {
+			z.logger.LogError(ctx, "Error occurred while requesting re-enumeration of rejoined node.", logwrap.Err(err))
+		}
+	}
+}
+
+// lostReporting returns the attribute monitors on the node whose reporting configuration the node no longer has,
+// monitors which fail to be verified are assumed to be intact.
+func (z *ZDA) lostReporting(ctx context.Context, n *node) []attribute.Monitor {
+	var lost []attribute.Monitor
+
+	for _, m := range n.monitors.all() {
+		if present, err := m.VerifyReporting(ctx); err != nil {
+			z.logger.LogWarn(ctx, "Failed to verify reporting configuration of rejoined node.", logwrap.Err(err))
+		} else if !present {
+			lost = append(lost, m)
+		}
+	}
+
+	return lost
+}
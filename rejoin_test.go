@@ -0,0 +1,93 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+func newTestRejoinNode(t *testing.T, gw *ZDA, present ...bool) (*node, []*attribute.MockMonitor) {
+	n, _ := gw.createNode(zigbee.GenerateLocalAdministeredIEEEAddress())
+
+	var monitors []*attribute.MockMonitor
+
+	for _, p := range present {
+		mm := &attribute.MockMonitor{}
+		t.Cleanup(func() { mm.AssertExpectations(t) })
+		mm.On("VerifyReporting", mock.Anything).Return(p, nil)
+
+		n.monitors.track(mm, time.Minute)
+		monitors = append(monitors, mm)
+	}
+
+	return n, monitors
+}
+
+func TestZDA_rejoin(t *testing.T) {
+	t.Run("refreshes the reporting configuration of every monitor if the node has lost any", func(t *testing.T) {
+		gw, _, _, stop := newTestGateway()
+		defer stop(t)
+		gw.WithRejoinPolicy(RejoinRefreshReporting)
+
+		n, monitors := newTestRejoinNode(t, gw, true, false)
+		monitors[0].On("RefreshReporting", mock.Anything).Return(nil)
+		monitors[1].On("RefreshReporting", mock.Anything).Return(nil)
+
+		gw.rejoin(context.Background(), n)
+	})
+
+	t.Run("re-enumerates a node which has lost its reporting configuration", func(t *testing.T) {
+		gw, _, _, stop := newTestGateway()
+		defer stop(t)
+		gw.WithRejoinPolicy(RejoinReenumerate)
+
+		var reenumerated *node
+		gw.callbacks.Add(func(_ context.Context, re nodeReenumerate) error {
+			reenumerated = re.n
+			return nil
+		})
+
+		n, _ := newTestRejoinNode(t, gw, false)
+
+		/* Prevent the gateway's own enumerator from interrogating the node. */
+		assert.True(t, n.enumerationSem.TryAcquire(1))
+
+		gw.rejoin(context.Background(), n)
+		assert.Equal(t, n, reenumerated)
+	})
+
+	t.Run("does not re-enumerate a node which retains its reporting configuration, or fails verification", func(t *testing.T) {
+		gw, _, _, stop := newTestGateway()
+		defer stop(t)
+		gw.WithRejoinPolicy(RejoinReenumerate)
+
+		gw.callbacks.Add(func(_ context.Context, re nodeReenumerate) error {
+			assert.Fail(t, "node should not be re-enumerated")
+			return nil
+		})
+
+		n, _ := newTestRejoinNode(t, gw, true)
+
+		failing := &attribute.MockMonitor{}
+		defer failing.AssertExpectations(t)
+		failing.On("VerifyReporting", mock.Anything).Return(false, io.EOF)
+		n.monitors.track(failing, time.Minute)
+
+		gw.rejoin(context.Background(), n)
+	})
+
+	t.Run("does nothing if rejoins are ignored, as they are by default", func(t *testing.T) {
+		gw, _, _, stop := newTestGateway()
+		defer stop(t)
+
+		n, _ := gw.createNode(zigbee.GenerateLocalAdministeredIEEEAddress())
+		n.monitors.track(&attribute.MockMonitor{}, time.Minute)
+
+		gw.rejoin(context.Background(), n)
+	})
+}
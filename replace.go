@@ -0,0 +1,97 @@
+package zda
+
+import (
+	"context"
+	"errors"
+	"github.com/shimmeringbee/da"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/zigbee"
+)
+
+var ErrNodeNotFound = errors.New("node not found")
+var ErrReplacementInvalid = errors.New("node can not replace itself")
+var ErrReplacementEnumerating = errors.New("replacement node is still being enumerated")
+
+// DeviceReplaced is sent for each device moved onto a replacement node by ReplaceNode, permitting consumers to update
+// references to the device's previous identifier.
+type DeviceReplaced struct {
+	// Previous identifier of the device, on the node which was replaced.
+	Previous IEEEAddressWithSubIdentifier
+	// Device now on the replacement node.
+	Device da.Device
+}
+
+// ReplaceNode migrates a dead node to the new node which replaces it. The new node must have joined the network and
+// finished its initial enumeration. The new node's own devices are removed, and the dead node's persistence, including
+// its devices' sub identifiers, unique ids, group memberships and capability data, is moved to the new node's address.
+// State describing the dead node's hardware, such as alarm sensor enrollment and configured reporting, is not moved,
+// and the new node is then enumerated again to configure the new hardware. The dead node may either still be loaded or be
+// retained as a tombstone.
+func (z *ZDA) ReplaceNode(ctx context.Context, oldAddress zigbee.IEEEAddress, newAddress zigbee.IEEEAddress) error {
+	if oldAddress == newAddress {
+		return ErrReplacementInvalid
+	}
+
+	if err := z.moveNode(ctx, oldAddress, newAddress); err != nil {
+		return err
+	}
+
+	n := z.getNode(newAddress)
+
+	for _, d := range z.getDevicesOnNode(n) {
+		z.sendEvent(DeviceReplaced{Previous: IEEEAddressWithSubIdentifier{IEEEAddress: oldAddress, SubIdentifier: d.address.SubIdentifier}, Device: d})
+	}
+
+	if err := z.callbacks.Call(ctx, nodeReenumerate{n: n}); err != nil {
+		z.logger.LogError(ctx, "Error occurred while advertising node re-enumeration.", logwrap.Err(err), logwrap.Datum("IEEEAddress", newAddress.String()))
+	}
+
+	return nil
+}
+
+// moveNode removes the new node, and loads it again from the old node's persistence moved to its address.
+func (z *ZDA) moveNode(ctx context.Context, oldAddress zigbee.IEEEAddress, newAddress zigbee.IEEEAddress) error {
+	/* Prevent the old node's tombstone being purged, or restored, while its persistence is being moved. */
+	z.tombstoneLock.Lock()
+	defer z.tombstoneLock.Unlock()
+
+	if !z.section.Section("Node").SectionExists(oldAddress.String()) {
+		return ErrNodeNotFound
+	}
+
+	newNode := z.getNode(newAddress)
+	if newNode == nil {
+		return ErrNodeNotFound
+	}
+
+	/* Prevent enumeration of the new node recreating its persistence while it is being removed. */
+	if !newNode.enumerationSem.TryAcquire(1) {
+		return ErrReplacementEnumerating
+	}
+
+	z.logger.LogInfo(ctx, "Replacing node.", logwrap.Datum("IEEEAddress", oldAddress.String()), logwrap.Datum("ReplacementIEEEAddress", newAddress.String()))
+
+	for _, d := range z.getDevicesOnNode(newNode) {
+		_ = z.removeDevice(ctx, d.address)
+	}
+
+	_ = z.removeNode(newAddress)
+	newNode.enumerationSem.Release(1)
+
+	if oldNode := z.getNode(oldAddress); oldNode != nil {
+		for _, d := range z.getDevicesOnNode(oldNode) {
+			_ = z.unloadDevice(ctx, d.address)
+		}
+
+		_ = z.unloadNode(oldAddress)
+	}
+
+	z.sectionMoveNode(oldAddress, newAddress)
+	z.sectionMoveNodeGroupMemberships(oldAddress, newAddress)
+	z.sectionClearHardwareState(newAddress)
+	z.sectionForNode(newAddress).Delete(DepartedKey)
+
+	z.providerLoadNode(ctx, newAddress)
+
+	return nil
+}
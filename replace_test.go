@@ -0,0 +1,211 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/callbacks"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/commands/local/ias_zone"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/attribute"
+	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/implcaps/factory"
+	"github.com/shimmeringbee/zda/implcaps/zcl/alarm_sensor"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestReplacementNode(t *testing.T, g *ZDA) (zigbee.IEEEAddress, *[]*node) {
+	var reenumerated []*node
+
+	/* Replace the enumerator, the gateway has no provider to query. */
+	g.callbacks = callbacks.Create()
+	g.callbacks.Add(func(_ context.Context, re nodeReenumerate) error {
+		reenumerated = append(reenumerated, re.n)
+		return nil
+	})
+
+	addr := zigbee.GenerateLocalAdministeredIEEEAddress()
+	n, _ := g.createNode(addr)
+	d := g.createNextDevice(n)
+	g.setDeviceUniqueId(d, 1)
+
+	return addr, &reenumerated
+}
+
+func TestZDA_ReplaceNode(t *testing.T) {
+	t.Run("the devices of a dead node are moved onto the replacement, keeping their sub identifiers and data", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.sectionForGroup(7).Section("Member", id.IEEEAddress.String()).Set("3", true)
+
+		newAddr, reenumerated := newTestReplacementNode(t, g)
+		temporary := g.getDevicesOnNode(g.getNode(newAddr))[0].address
+
+		assert.NoError(t, g.ReplaceNode(context.Background(), id.IEEEAddress, newAddr))
+
+		assert.Nil(t, g.getNode(id.IEEEAddress))
+		assert.Nil(t, g.getDevice(temporary))
+		assert.Equal(t, []zigbee.IEEEAddress{newAddr}, g.nodeListFromPersistence())
+
+		n := g.getNode(newAddr)
+		assert.NotNil(t, n)
+		assert.Equal(t, []*node{n}, *reenumerated)
+
+		replaced := IEEEAddressWithSubIdentifier{IEEEAddress: newAddr, SubIdentifier: id.SubIdentifier}
+		assert.Equal(t, []IEEEAddressWithSubIdentifier{replaced}, g.deviceListFromPersistence(newAddr))
+
+		d := g.getDevice(replaced)
+		assert.NotNil(t, d)
+		assert.Equal(t, n, d.n)
+		assert.Equal(t, 5, d.deviceId)
+
+		pi, err := d.Capability(capabilities.ProductInformationFlag).(capabilities.ProductInformation).Get(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "NEXUS-7", pi.Name)
+
+		assert.Contains(t, g.GroupMembers(7), d)
+		assert.False(t, g.sectionForGroup(7).Section("Member").SectionExists(id.IEEEAddress.String()))
+
+		var events []DeviceReplaced
+		for len(g.events) > 0 {
+			if e, ok := (<-g.events).(DeviceReplaced); ok {
+				events = append(events, e)
+			}
+		}
+
+		assert.Equal(t, []DeviceReplaced{{Previous: id, Device: d}}, events)
+	})
+
+	t.Run("a tombstoned node can be replaced, and the replacement is loaded from its own address on restart", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		newAddr, _ := newTestReplacementNode(t, g)
+
+		assert.NoError(t, g.ReplaceNode(context.Background(), id.IEEEAddress, newAddr))
+
+		_, departed := g.nodeDeparted(newAddr)
+		assert.False(t, departed)
+
+		_, found := g.nodeDeparted(id.IEEEAddress)
+		assert.False(t, found)
+
+		replaced := IEEEAddressWithSubIdentifier{IEEEAddress: newAddr, SubIdentifier: id.SubIdentifier}
+		_ = g.unloadDevice(context.Background(), replaced)
+		_ = g.unloadNode(newAddr)
+
+		g.providerLoad()
+
+		assert.Nil(t, g.getNode(id.IEEEAddress))
+		assert.Equal(t, g.getNode(newAddr), g.getDevice(replaced).n)
+	})
+
+	t.Run("removing the replacement removes the node's persistence", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.WithTombstoneRetention(0)
+		newAddr, _ := newTestReplacementNode(t, g)
+
+		assert.NoError(t, g.ReplaceNode(context.Background(), id.IEEEAddress, newAddr))
+
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: newAddr}})
+
+		assert.Empty(t, g.nodeListFromPersistence())
+	})
+
+	t.Run("fails if either node is unknown, or the replacement is invalid", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		newAddr, _ := newTestReplacementNode(t, g)
+		unknown := zigbee.GenerateLocalAdministeredIEEEAddress()
+
+		assert.ErrorIs(t, g.ReplaceNode(context.Background(), unknown, newAddr), ErrNodeNotFound)
+		assert.ErrorIs(t, g.ReplaceNode(context.Background(), id.IEEEAddress, unknown), ErrNodeNotFound)
+		assert.ErrorIs(t, g.ReplaceNode(context.Background(), newAddr, newAddr), ErrReplacementInvalid)
+
+		g.getNode(newAddr).enumerationSem.TryAcquire(1)
+		assert.ErrorIs(t, g.ReplaceNode(context.Background(), id.IEEEAddress, newAddr), ErrReplacementEnumerating)
+		assert.NotNil(t, g.getNode(id.IEEEAddress))
+	})
+}
+
+// enrollingCommunicator delivers incoming messages to registered matches, recording the ias cie address writes made to
+// enroll an alarm sensor rather than transmitting them.
+type enrollingCommunicator struct {
+	communicator.Communicator
+	enrolled chan zigbee.IEEEAddress
+}
+
+func (c enrollingCommunicator) WriteAttributes(_ context.Context, ieeeAddress zigbee.IEEEAddress, _ bool, cluster zigbee.ClusterID, _ zigbee.ManufacturerCode, _ zigbee.Endpoint, _ zigbee.Endpoint, _ uint8, attributes map[zcl.AttributeID]zcl.AttributeDataTypeValue) ([]global.WriteAttributesResponseRecord, error) {
+	if _, found := attributes[ias_zone.IASCIEAddress]; cluster == zcl.IASZoneId && found {
+		c.enrolled <- ieeeAddress
+	}
+
+	return nil, nil
+}
+
+func (c enrollingCommunicator) Request(context.Context, zigbee.IEEEAddress, bool, zcl.Message) error {
+	return nil
+}
+
+func TestZDA_ReplaceNode_HardwareState(t *testing.T) {
+	t.Run("a replaced alarm sensor is enrolled again, and its monitors are no longer considered configured", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+
+		mp := &zigbee.MockProvider{}
+		mp.On("AdapterNode").Return(zigbee.Node{IEEEAddress: zigbee.IEEEAddress(0xaa)}).Maybe()
+		g.provider = mp
+
+		zc := enrollingCommunicator{Communicator: g.zclCommunicator, enrolled: make(chan zigbee.IEEEAddress, 1)}
+		g.zdaInterface = zdaInterface{gw: g, c: zc}
+
+		cS := g.sectionForDevice(id).Section("Capability", capabilities.StandardNames[capabilities.AlarmSensorFlag])
+		cS.Set("Implementation", factory.ZCLAlarmSensor)
+		dS := cS.Section("Data")
+		dS.Set(implcaps.RemoteEndpointKey, 1)
+		dS.Set(alarm_sensor.EnrolledKey, true)
+		dS.Set(alarm_sensor.ZoneIDKey, alarm_sensor.DefaultZoneID)
+
+		mS := g.sectionForDevice(id).Section("Capability", "TemperatureSensor", "Data", "AttributeMonitor", "Temperature")
+		mS.Set(attribute.ReportingConfiguredKey, true)
+		mS.Set(attribute.ClusterIdKey, 0x0402)
+
+		newAddr, _ := newTestReplacementNode(t, g)
+
+		assert.NoError(t, g.ReplaceNode(context.Background(), id.IEEEAddress, newAddr))
+
+		replaced := IEEEAddressWithSubIdentifier{IEEEAddress: newAddr, SubIdentifier: id.SubIdentifier}
+
+		rmS := g.sectionForDevice(replaced).Section("Capability", "TemperatureSensor", "Data", "AttributeMonitor", "Temperature")
+		_, configured := rmS.Bool(attribute.ReportingConfiguredKey)
+		assert.False(t, configured)
+		assert.True(t, rmS.Exists(attribute.ClusterIdKey))
+
+		assert.NotNil(t, g.getDevice(replaced).Capability(capabilities.AlarmSensorFlag))
+
+		am, err := g.zclCommandRegistry.Marshal(zcl.Message{
+			FrameType:           zcl.FrameLocal,
+			Direction:           zcl.ServerToClient,
+			Manufacturer:        zigbee.NoManufacturer,
+			ClusterID:           zcl.IASZoneId,
+			SourceEndpoint:      1,
+			DestinationEndpoint: DefaultGatewayHomeAutomationEndpoint,
+			CommandIdentifier:   ias_zone.ZoneStatusChangeNotificationId,
+			Command:             &ias_zone.ZoneStatusChangeNotification{Alarm1: true},
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, zc.ProcessIncomingMessage(zigbee.NodeIncomingMessageEvent{
+			Node:            zigbee.Node{IEEEAddress: newAddr},
+			IncomingMessage: zigbee.IncomingMessage{ApplicationMessage: am},
+		}))
+
+		select {
+		case addr := <-zc.enrolled:
+			assert.Equal(t, newAddr, addr)
+		case <-time.After(time.Second):
+			assert.Fail(t, "replacement alarm sensor was not enrolled")
+		}
+	})
+}
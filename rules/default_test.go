@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"github.com/shimmeringbee/zigbee"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -16,3 +17,80 @@ func TestDefault(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestDefault_MeasurementClusters(t *testing.T) {
+	t.Run("pressure and flow measurement clusters map to their respective capabilities", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadFS(Embedded))
+		assert.NoError(t, e.CompileRules())
+
+		o, err := e.Execute(Input{Self: 1, Endpoint: map[int]InputEndpoint{1: {ID: 1, InClusters: []int{0x0403}}}})
+		assert.NoError(t, err)
+		assert.Contains(t, o.Capabilities, "ZCLPressureSensor")
+		assert.NotContains(t, o.Capabilities, "ZCLFlowSensor")
+
+		o, err = e.Execute(Input{Self: 1, Endpoint: map[int]InputEndpoint{1: {ID: 1, InClusters: []int{0x0404}}}})
+		assert.NoError(t, err)
+		assert.Contains(t, o.Capabilities, "ZCLFlowSensor")
+		assert.NotContains(t, o.Capabilities, "ZCLPressureSensor")
+	})
+}
+
+func TestDefault_Diagnostics(t *testing.T) {
+	t.Run("diagnostics are added once per node, using the endpoint with the diagnostics cluster", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadFS(Embedded))
+		assert.NoError(t, e.CompileRules())
+
+		endpoints := map[int]InputEndpoint{1: {ID: 1, InClusters: []int{0x0006}}, 2: {ID: 2, InClusters: []int{0x0b05}}}
+
+		o, err := e.Execute(Input{Self: 1, Endpoint: endpoints})
+		assert.NoError(t, err)
+		assert.Equal(t, true, o.Capabilities["GenericDiagnostics"]["ZigbeeDiagnosticsClusterPresent"])
+		assert.Equal(t, zigbee.Endpoint(2), o.Capabilities["GenericDiagnostics"]["ZigbeeEndpoint"])
+
+		o, err = e.Execute(Input{Self: 2, Endpoint: endpoints})
+		assert.NoError(t, err)
+		assert.NotContains(t, o.Capabilities, "GenericDiagnostics")
+	})
+
+	t.Run("diagnostics are added without the cluster, for link quality alone", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadFS(Embedded))
+		assert.NoError(t, e.CompileRules())
+
+		o, err := e.Execute(Input{Self: 1, Endpoint: map[int]InputEndpoint{1: {ID: 1}}})
+		assert.NoError(t, err)
+		assert.Equal(t, false, o.Capabilities["GenericDiagnostics"]["ZigbeeDiagnosticsClusterPresent"])
+		assert.Equal(t, zigbee.Endpoint(1), o.Capabilities["GenericDiagnostics"]["ZigbeeEndpoint"])
+	})
+}
+
+func TestDefault_Availability(t *testing.T) {
+	t.Run("availability is added once per node, using the endpoint with the basic cluster", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadFS(Embedded))
+		assert.NoError(t, e.CompileRules())
+
+		endpoints := map[int]InputEndpoint{1: {ID: 1, InClusters: []int{0x0006}}, 2: {ID: 2, InClusters: []int{0x0000}}}
+
+		o, err := e.Execute(Input{Self: 1, Node: InputNode{Type: "router"}, Endpoint: endpoints})
+		assert.NoError(t, err)
+		assert.Equal(t, zigbee.Endpoint(2), o.Capabilities["GenericAvailability"]["ZigbeeEndpoint"])
+		assert.Equal(t, true, o.Capabilities["GenericAvailability"]["ZigbeeRouter"])
+
+		o, err = e.Execute(Input{Self: 2, Node: InputNode{Type: "router"}, Endpoint: endpoints})
+		assert.NoError(t, err)
+		assert.NotContains(t, o.Capabilities, "GenericAvailability")
+	})
+
+	t.Run("end devices are not marked as routers", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadFS(Embedded))
+		assert.NoError(t, e.CompileRules())
+
+		o, err := e.Execute(Input{Self: 1, Node: InputNode{Type: "enddevice"}, Endpoint: map[int]InputEndpoint{1: {ID: 1}}})
+		assert.NoError(t, err)
+		assert.Equal(t, false, o.Capabilities["GenericAvailability"]["ZigbeeRouter"])
+	})
+}
@@ -23,6 +23,9 @@ func (z *ZDA) createNode(addr zigbee.IEEEAddress) (*node, bool) {
 			sequence:       makeTransactionSequence(),
 			device:         make(map[uint8]*device),
 			enumerationSem: semaphore.NewWeighted(1),
+			commandQueue:   newCommandQueue(),
+			linkQuality:    newLinkQuality(),
+			monitors:       newNodeMonitors(),
 		}
 
 		z.node[addr] = n
@@ -41,13 +44,22 @@ func (z *ZDA) getNode(addr zigbee.IEEEAddress) *node {
 }
 
 func (z *ZDA) removeNode(addr zigbee.IEEEAddress) bool {
+	if !z.unloadNode(addr) {
+		return false
+	}
+
+	z.sectionRemoveNode(addr)
+	return true
+}
+
+// unloadNode removes a node from the node table, leaving its persistence intact.
+func (z *ZDA) unloadNode(addr zigbee.IEEEAddress) bool {
 	z.nodeLock.Lock()
 	defer z.nodeLock.Unlock()
 
 	_, found := z.node[addr]
 	if found {
 		delete(z.node, addr)
-		z.sectionRemoveNode(addr)
 	}
 
 	return found
@@ -151,6 +163,18 @@ func (z *ZDA) setDeviceUniqueId(d *device, id int) {
 }
 
 func (z *ZDA) removeDevice(ctx context.Context, addr IEEEAddressWithSubIdentifier) bool {
+	if !z.unloadDevice(ctx, addr) {
+		return false
+	}
+
+	z.sectionRemoveDevice(addr)
+	z.sectionRemoveDeviceFromGroups(addr)
+	return true
+}
+
+// unloadDevice detaches the capabilities of a device and removes it from the device table, leaving its persistence
+// intact.
+func (z *ZDA) unloadDevice(ctx context.Context, addr IEEEAddressWithSubIdentifier) bool {
 	n := z.getNode(addr.IEEEAddress)
 
 	if n == nil {
@@ -168,7 +192,9 @@ func (z *ZDA) removeDevice(ctx context.Context, addr IEEEAddressWithSubIdentifie
 				z.logger.LogWarn(ctx, "Error thrown while detaching capability.", logwrap.Datum("Device", capabilities.StandardNames[cf]), logwrap.Datum("CapabilityImplementation", impl.ImplName()), logwrap.Err(err))
 			}
 
-			z.detachCapabilityFromDevice(d, impl)
+			/* Capability persistence is left intact, it is removed with the device's section if the device is removed. */
+			z.sendEvent(da.CapabilityRemoved{Device: d, Capability: cf})
+			delete(d.capabilities, cf)
 		}
 		d.m.RUnlock()
 
@@ -177,7 +203,6 @@ func (z *ZDA) removeDevice(ctx context.Context, addr IEEEAddressWithSubIdentifie
 		z.sendEvent(da.DeviceRemoved{Device: d})
 
 		delete(n.device, addr.SubIdentifier)
-		z.sectionRemoveDevice(d.address)
 		return true
 	}
 
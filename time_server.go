@@ -0,0 +1,197 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zigbee"
+	"time"
+)
+
+const (
+	TimeAttribute       = zcl.AttributeID(0x0000)
+	TimeStatusAttribute = zcl.AttributeID(0x0001)
+	TimeZoneAttribute   = zcl.AttributeID(0x0002)
+	DstStartAttribute   = zcl.AttributeID(0x0003)
+	DstEndAttribute     = zcl.AttributeID(0x0004)
+	DstShiftAttribute   = zcl.AttributeID(0x0005)
+	LocalTimeAttribute  = zcl.AttributeID(0x0007)
+)
+
+const (
+	TimeStatusMaster        = uint8(0x01)
+	TimeStatusMasterZoneDst = uint8(0x04)
+)
+
+const (
+	zclStatusSuccess              = uint8(0x00)
+	zclStatusUnsupportedAttribute = uint8(0x86)
+)
+
+// zigbeeEpoch is the epoch of ZCL UTCTime, midnight on the 1st of January 2000 UTC.
+var zigbeeEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// WithTimeLocation sets the location used when answering devices reading the Time cluster from the gateway, the time
+// zone and daylight saving details are derived from it. Defaults to time.Local.
+func (z *ZDA) WithTimeLocation(l *time.Location) {
+	z.timeServer.location = l
+}
+
+type timeServer struct {
+	gw       *ZDA
+	location *time.Location
+	now      func() time.Time
+}
+
+func (t *timeServer) match() communicator.Match {
+	return communicator.NewMatch(t.zclFilter, t.zclMessage)
+}
+
+func (t *timeServer) zclFilter(_ zigbee.IEEEAddress, _ zigbee.ApplicationMessage, m zcl.Message) bool {
+	return m.ClusterID == zcl.TimeId && m.DestinationEndpoint == DefaultGatewayHomeAutomationEndpoint && m.Direction == zcl.ClientToServer && m.CommandIdentifier == global.ReadAttributesID
+}
+
+func (t *timeServer) zclMessage(m communicator.MessageWithSource) {
+	req, ok := m.Message.Command.(*global.ReadAttributes)
+	if !ok {
+		return
+	}
+
+	values := t.attributes(t.now())
+
+	var records []global.ReadAttributeResponseRecord
+
+	for _, id := range req.Identifier {
+		if v, found := values[id]; found {
+			records = append(records, global.ReadAttributeResponseRecord{Identifier: id, Status: zclStatusSuccess, DataTypeValue: &v})
+		} else {
+			records = append(records, global.ReadAttributeResponseRecord{Identifier: id, Status: zclStatusUnsupportedAttribute})
+		}
+	}
+
+	ctx, done := context.WithTimeout(t.gw.ctx, 5*time.Second)
+	defer done()
+
+	if err := t.gw.zclCommunicator.Request(ctx, m.SourceAddress, false, zcl.Message{
+		FrameType:           zcl.FrameGlobal,
+		Direction:           zcl.ServerToClient,
+		TransactionSequence: m.Message.TransactionSequence,
+		Manufacturer:        m.Message.Manufacturer,
+		ClusterID:           zcl.TimeId,
+		SourceEndpoint:      DefaultGatewayHomeAutomationEndpoint,
+		DestinationEndpoint: m.Message.SourceEndpoint,
+		CommandIdentifier:   global.ReadAttributesResponseID,
+		Command:             &global.ReadAttributesResponse{Records: records},
+	}); err != nil {
+		t.gw.logger.LogWarn(ctx, "Failed to respond to time read.", logwrap.Datum("IEEEAddress", m.SourceAddress.String()), logwrap.Err(err))
+	}
+}
+
+// attributes returns the Time cluster attributes at the instant provided, in the configured location.
+func (t *timeServer) attributes(now time.Time) map[zcl.AttributeID]zcl.AttributeDataTypeValue {
+	loc := t.location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	_, offset := now.In(loc).Zone()
+	standardOffset, dstStart, dstEnd, dstShift := daylightSaving(now, loc)
+
+	return map[zcl.AttributeID]zcl.AttributeDataTypeValue{
+		TimeAttribute:       {DataType: zcl.TypeUTCTime, Value: zcl.UTCTime(zigbeeTime(now))},
+		TimeStatusAttribute: {DataType: zcl.TypeBitmap8, Value: uint8(TimeStatusMaster | TimeStatusMasterZoneDst)},
+		TimeZoneAttribute:   {DataType: zcl.TypeSignedInt32, Value: int32(standardOffset)},
+		DstStartAttribute:   {DataType: zcl.TypeUnsignedInt32, Value: zigbeeTime(dstStart)},
+		DstEndAttribute:     {DataType: zcl.TypeUnsignedInt32, Value: zigbeeTime(dstEnd)},
+		DstShiftAttribute:   {DataType: zcl.TypeSignedInt32, Value: int32(dstShift)},
+		LocalTimeAttribute:  {DataType: zcl.TypeUnsignedInt32, Value: zigbeeTime(now.Add(time.Duration(offset) * time.Second))},
+	}
+}
+
+// zigbeeTime converts a time to seconds since the ZCL epoch, the zero time is converted to zero.
+func zigbeeTime(t time.Time) uint32 {
+	if t.IsZero() || t.Before(zigbeeEpoch) {
+		return 0
+	}
+
+	return uint32(t.Sub(zigbeeEpoch) / time.Second)
+}
+
+// daylightSavingSearch is how far daylight saving transitions are searched for, a little over a year ensures that
+// both transitions of a period are found.
+const daylightSavingSearch = 400 * 24 * time.Hour
+
+// daylightSaving returns the standard offset of the location and the current or next daylight saving period. If the
+// location does not observe daylight saving the start and end are zero.
+func daylightSaving(now time.Time, loc *time.Location) (int, time.Time, time.Time, int) {
+	_, offset := now.In(loc).Zone()
+
+	if now.In(loc).IsDST() {
+		start, _ := findTransition(now, loc, -daylightSavingSearch)
+		end, found := findTransition(now, loc, daylightSavingSearch)
+		if !found {
+			return offset, time.Time{}, time.Time{}, 0
+		}
+
+		_, standardOffset := end.In(loc).Zone()
+		return standardOffset, start, end, offset - standardOffset
+	}
+
+	start, found := findTransition(now, loc, daylightSavingSearch)
+	if !found || !start.In(loc).IsDST() {
+		return offset, time.Time{}, time.Time{}, 0
+	}
+
+	end, found := findTransition(start, loc, daylightSavingSearch)
+	if !found {
+		return offset, time.Time{}, time.Time{}, 0
+	}
+
+	_, dstOffset := start.In(loc).Zone()
+	return offset, start, end, dstOffset - offset
+}
+
+// findTransition returns the first instant within limit of from (searching backwards if limit is negative) at which
+// the UTC offset of the location changes. When searching backwards the instant returned is the start of the current
+// offset.
+func findTransition(from time.Time, loc *time.Location, limit time.Duration) (time.Time, bool) {
+	step := 24 * time.Hour
+	if limit < 0 {
+		step = -step
+		limit = -limit
+	}
+
+	_, initial := from.In(loc).Zone()
+
+	prev := from
+	for searched := time.Duration(0); searched < limit; searched += 24 * time.Hour {
+		next := prev.Add(step)
+
+		if _, o := next.In(loc).Zone(); o != initial {
+			/* Binary search between the last instant with the initial offset and the first without. */
+			same, changed := prev, next
+
+			for d := changed.Sub(same); d > time.Second || d < -time.Second; d = changed.Sub(same) {
+				mid := same.Add(d / 2)
+
+				if _, o := mid.In(loc).Zone(); o == initial {
+					same = mid
+				} else {
+					changed = mid
+				}
+			}
+
+			if step < 0 {
+				return same.Truncate(time.Second), true
+			}
+
+			return changed.Truncate(time.Second), true
+		}
+
+		prev = next
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,125 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/zcl"
+	"github.com/shimmeringbee/zcl/commands/global"
+	"github.com/shimmeringbee/zcl/communicator"
+	"github.com/shimmeringbee/zda/mocks"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func Test_timeServer_attributes(t *testing.T) {
+	t.Run("returns the time, with no daylight saving for a fixed zone", func(t *testing.T) {
+		ts := &timeServer{location: time.FixedZone("Test", 3600)}
+
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		attrs := ts.attributes(now)
+
+		assert.Equal(t, zcl.UTCTime(757382400), attrs[TimeAttribute].Value)
+		assert.Equal(t, uint8(0x05), attrs[TimeStatusAttribute].Value)
+		assert.Equal(t, int32(3600), attrs[TimeZoneAttribute].Value)
+		assert.Equal(t, uint32(0), attrs[DstStartAttribute].Value)
+		assert.Equal(t, uint32(0), attrs[DstEndAttribute].Value)
+		assert.Equal(t, int32(0), attrs[DstShiftAttribute].Value)
+		assert.Equal(t, uint32(757382400+3600), attrs[LocalTimeAttribute].Value)
+	})
+
+	t.Run("returns the next daylight saving period if not currently in daylight saving", func(t *testing.T) {
+		loc, err := time.LoadLocation("Europe/London")
+		assert.NoError(t, err)
+
+		ts := &timeServer{location: loc}
+
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		attrs := ts.attributes(now)
+
+		assert.Equal(t, int32(0), attrs[TimeZoneAttribute].Value)
+		assert.Equal(t, zigbeeTime(time.Date(2024, time.March, 31, 1, 0, 0, 0, time.UTC)), attrs[DstStartAttribute].Value)
+		assert.Equal(t, zigbeeTime(time.Date(2024, time.October, 27, 1, 0, 0, 0, time.UTC)), attrs[DstEndAttribute].Value)
+		assert.Equal(t, int32(3600), attrs[DstShiftAttribute].Value)
+		assert.Equal(t, uint32(757382400), attrs[LocalTimeAttribute].Value)
+	})
+
+	t.Run("returns the current daylight saving period, for a southern hemisphere location", func(t *testing.T) {
+		loc, err := time.LoadLocation("Australia/Sydney")
+		assert.NoError(t, err)
+
+		ts := &timeServer{location: loc}
+
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		attrs := ts.attributes(now)
+
+		assert.Equal(t, int32(36000), attrs[TimeZoneAttribute].Value)
+		assert.Equal(t, zigbeeTime(time.Date(2023, time.September, 30, 16, 0, 0, 0, time.UTC)), attrs[DstStartAttribute].Value)
+		assert.Equal(t, zigbeeTime(time.Date(2024, time.April, 6, 16, 0, 0, 0, time.UTC)), attrs[DstEndAttribute].Value)
+		assert.Equal(t, int32(3600), attrs[DstShiftAttribute].Value)
+		assert.Equal(t, uint32(757382400+39600), attrs[LocalTimeAttribute].Value)
+	})
+}
+
+func Test_timeServer_zclFilter(t *testing.T) {
+	t.Run("only matches attribute reads of the time cluster on the gateway endpoint", func(t *testing.T) {
+		ts := &timeServer{}
+
+		valid := zcl.Message{FrameType: zcl.FrameGlobal, Direction: zcl.ClientToServer, ClusterID: zcl.TimeId, DestinationEndpoint: DefaultGatewayHomeAutomationEndpoint, CommandIdentifier: global.ReadAttributesID}
+		assert.True(t, ts.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, valid))
+
+		wrongCluster := valid
+		wrongCluster.ClusterID = zcl.BasicId
+		assert.False(t, ts.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongCluster))
+
+		wrongEndpoint := valid
+		wrongEndpoint.DestinationEndpoint = 2
+		assert.False(t, ts.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongEndpoint))
+
+		wrongDirection := valid
+		wrongDirection.Direction = zcl.ServerToClient
+		assert.False(t, ts.zclFilter(zigbee.IEEEAddress(1), zigbee.ApplicationMessage{}, wrongDirection))
+	})
+}
+
+func Test_timeServer_zclMessage(t *testing.T) {
+	t.Run("responds to a read with the requested attributes, marking unknown attributes as unsupported", func(t *testing.T) {
+		mzc := &mocks.MockZCLCommunicator{}
+		defer mzc.AssertExpectations(t)
+
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		ts := &timeServer{
+			gw:       &ZDA{ctx: context.Background(), logger: logwrap.New(discard.Discard()), zclCommunicator: mzc},
+			location: time.UTC,
+			now:      func() time.Time { return now },
+		}
+
+		mzc.On("Request", mock.Anything, zigbee.IEEEAddress(1), false, zcl.Message{
+			FrameType:           zcl.FrameGlobal,
+			Direction:           zcl.ServerToClient,
+			TransactionSequence: 7,
+			ClusterID:           zcl.TimeId,
+			SourceEndpoint:      DefaultGatewayHomeAutomationEndpoint,
+			DestinationEndpoint: 3,
+			CommandIdentifier:   global.ReadAttributesResponseID,
+			Command: &global.ReadAttributesResponse{Records: []global.ReadAttributeResponseRecord{
+				{Identifier: TimeAttribute, Status: 0, DataTypeValue: &zcl.AttributeDataTypeValue{DataType: zcl.TypeUTCTime, Value: zcl.UTCTime(757382400)}},
+				{Identifier: 0x0009, Status: 0x86},
+			}},
+		}).Return(nil)
+
+		ts.zclMessage(communicator.MessageWithSource{
+			SourceAddress: zigbee.IEEEAddress(1),
+			Message: zcl.Message{
+				TransactionSequence: 7,
+				SourceEndpoint:      3,
+				Command:             &global.ReadAttributes{Identifier: []zcl.AttributeID{TimeAttribute, 0x0009}},
+			},
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/zigbee"
+	"time"
+)
+
+// DepartedKey is set on the persistence section of a node which has left the network while tombstones are enabled,
+// recording when it left.
+const DepartedKey = "Departed"
+
+// TombstonePurgeInterval is how often expired tombstones are removed while the gateway is running.
+const TombstonePurgeInterval = time.Hour
+
+// WithTombstoneRetention enables tombstone mode, the persistence of a node which leaves the network is retained for
+// the duration provided. If the node rejoins within that time its devices, sub identifiers and capability data are
+// restored. Expired tombstones are removed when the gateway starts, when another node leaves, and every
+// TombstonePurgeInterval. Defaults to zero, which removes a node's persistence as soon as it leaves.
+// Must be called before Start.
+func (z *ZDA) WithTombstoneRetention(d time.Duration) {
+	z.tombstoneRetention = d
+}
+
+// tombstoneNode unloads a node which has left the network, marking its retained persistence as departed.
+func (z *ZDA) tombstoneNode(ctx context.Context, n *node) {
+	for _, d := range z.getDevicesOnNode(n) {
+		z.logger.LogInfo(ctx, "Unload device upon node leaving zigbee network, retaining persistence.", logwrap.Datum("Identifier", d.address.String()))
+		_ = z.unloadDevice(ctx, d.address)
+	}
+
+	_ = z.unloadNode(n.address)
+	converter.Store(z.sectionForNode(n.address), DepartedKey, time.Now(), converter.TimeEncoder)
+}
+
+// nodeDeparted returns when a node with a retained persistence section left the network, false if the node has not
+// departed.
+func (z *ZDA) nodeDeparted(addr zigbee.IEEEAddress) (time.Time, bool) {
+	if !z.section.Section("Node").SectionExists(addr.String()) {
+		return time.Time{}, false
+	}
+
+	return converter.Retrieve(z.sectionForNode(addr), DepartedKey, converter.TimeDecoder)
+}
+
+func (z *ZDA) tombstoneExpired(departed time.Time) bool {
+	return time.Since(departed) >= z.tombstoneRetention
+}
+
+// restoreNode restores a departed node which has rejoined from its retained persistence, returning false if there is
+// no unexpired tombstone for the node.
+func (z *ZDA) restoreNode(ctx context.Context, addr zigbee.IEEEAddress) (*node, bool) {
+	z.tombstoneLock.Lock()
+	defer z.tombstoneLock.Unlock()
+
+	if z.getNode(addr) != nil {
+		return nil, false
+	}
+
+	departed, found := z.nodeDeparted(addr)
+	if !found {
+		return nil, false
+	}
+
+	if z.tombstoneExpired(departed) {
+		z.purgeNode(ctx, addr)
+		return nil, false
+	}
+
+	z.sectionForNode(addr).Delete(DepartedKey)
+	z.providerLoadNode(ctx, addr)
+
+	return z.getNode(addr), true
+}
+
+// purgeTombstones removes the persistence of every departed node whose tombstone has expired.
+func (z *ZDA) purgeTombstones(ctx context.Context) {
+	z.tombstoneLock.Lock()
+	defer z.tombstoneLock.Unlock()
+
+	for _, addr := range z.nodeListFromPersistence() {
+		if departed, found := z.nodeDeparted(addr); found && z.tombstoneExpired(departed) {
+			z.purgeNode(ctx, addr)
+		}
+	}
+}
+
+// tombstonePurger periodically purges expired tombstones, so they do not outlive their retention if no other node
+// leaves and the gateway is not restarted.
+func (z *ZDA) tombstonePurger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-z.ctx.Done():
+			return
+		case <-ticker.C:
+			z.purgeTombstones(z.ctx)
+		}
+	}
+}
+
+func (z *ZDA) purgeNode(ctx context.Context, addr zigbee.IEEEAddress) {
+	z.logger.LogInfo(ctx, "Removing expired tombstone of departed node.", logwrap.Datum("IEEEAddress", addr.String()))
+
+	for _, d := range z.deviceListFromPersistence(addr) {
+		z.sectionRemoveDeviceFromGroups(d)
+	}
+
+	z.sectionRemoveNode(addr)
+}
@@ -0,0 +1,121 @@
+package zda
+
+import (
+	"context"
+	"github.com/shimmeringbee/da/capabilities"
+	"github.com/shimmeringbee/logwrap"
+	"github.com/shimmeringbee/logwrap/impl/discard"
+	"github.com/shimmeringbee/persistence/converter"
+	"github.com/shimmeringbee/persistence/impl/memory"
+	"github.com/shimmeringbee/zigbee"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestTombstoneGateway(t *testing.T) (*ZDA, IEEEAddressWithSubIdentifier) {
+	g := New(context.Background(), memory.New(), nil, nil)
+	g.events = make(chan any, 0xffff)
+	g.WithLogWrapLogger(logwrap.New(discard.Discard()))
+	g.WithTombstoneRetention(time.Hour)
+
+	id := IEEEAddressWithSubIdentifier{IEEEAddress: zigbee.GenerateLocalAdministeredIEEEAddress(), SubIdentifier: 3}
+
+	dS := g.sectionForDevice(id)
+	dS.Set("UniqueId", 5)
+
+	cS := dS.Section("Capability", "ProductInformation")
+	cS.Set("Implementation", "GenericProductInformation")
+	cS.Section("Data").Set("Name", "NEXUS-7")
+
+	g.providerLoad()
+	assert.NotNil(t, g.getDevice(id))
+
+	return g, id
+}
+
+func TestZDA_tombstone(t *testing.T) {
+	t.Run("a departing node is unloaded, but its persistence is retained and marked departed", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		assert.Nil(t, g.getNode(id.IEEEAddress))
+		assert.Contains(t, g.deviceListFromPersistence(id.IEEEAddress), id)
+
+		departed, found := g.nodeDeparted(id.IEEEAddress)
+		assert.True(t, found)
+		assert.WithinDuration(t, time.Now(), departed, time.Second)
+	})
+
+	t.Run("a departed node is not loaded when the gateway starts", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		g.providerLoad()
+
+		assert.Nil(t, g.getNode(id.IEEEAddress))
+		assert.Contains(t, g.nodeListFromPersistence(), id.IEEEAddress)
+	})
+
+	t.Run("a node rejoining within the retention period has its devices and capabilities restored", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		n, restored := g.restoreNode(context.Background(), id.IEEEAddress)
+		assert.True(t, restored)
+		assert.Equal(t, g.getNode(id.IEEEAddress), n)
+
+		_, departed := g.nodeDeparted(id.IEEEAddress)
+		assert.False(t, departed)
+
+		d := g.getDevice(id)
+		assert.NotNil(t, d)
+		assert.Equal(t, 5, d.deviceId)
+
+		pi, err := d.Capability(capabilities.ProductInformationFlag).(capabilities.ProductInformation).Get(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "NEXUS-7", pi.Name)
+	})
+
+	t.Run("an expired tombstone is removed rather than restored", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		converter.Store(g.sectionForNode(id.IEEEAddress), DepartedKey, time.Now().Add(-2*time.Hour), converter.TimeEncoder)
+
+		_, restored := g.restoreNode(context.Background(), id.IEEEAddress)
+		assert.False(t, restored)
+		assert.NotContains(t, g.nodeListFromPersistence(), id.IEEEAddress)
+	})
+
+	t.Run("expired tombstones are purged, and without retention nodes are removed immediately", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		g.WithTombstoneRetention(0)
+		g.purgeTombstones(context.Background())
+
+		assert.NotContains(t, g.nodeListFromPersistence(), id.IEEEAddress)
+	})
+
+	t.Run("expired tombstones are purged periodically while the gateway is running", func(t *testing.T) {
+		g, id := newTestTombstoneGateway(t)
+		g.receiveNodeLeaveEvent(zigbee.NodeLeaveEvent{Node: zigbee.Node{IEEEAddress: id.IEEEAddress}})
+
+		go g.tombstonePurger(time.Millisecond)
+		defer g.ctxCancel()
+
+		g.tombstoneLock.Lock()
+		converter.Store(g.sectionForNode(id.IEEEAddress), DepartedKey, time.Now().Add(-2*time.Hour), converter.TimeEncoder)
+		g.tombstoneLock.Unlock()
+
+		assert.Eventually(t, func() bool {
+			g.tombstoneLock.Lock()
+			defer g.tombstoneLock.Unlock()
+
+			return !slices.Contains(g.nodeListFromPersistence(), id.IEEEAddress)
+		}, time.Second, time.Millisecond)
+	})
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/shimmeringbee/zcl/communicator"
 	"github.com/shimmeringbee/zda/attribute"
 	"github.com/shimmeringbee/zda/implcaps"
+	"github.com/shimmeringbee/zda/ota"
 	"github.com/shimmeringbee/zigbee"
+	"time"
 )
 
 var _ implcaps.ZDAInterface = (*zdaInterface)(nil)
@@ -38,9 +40,42 @@ func (z zdaInterface) ZCLCommunicator() communicator.Communicator {
 }
 
 func (z zdaInterface) NewAttributeMonitor() attribute.Monitor {
-	return attribute.NewMonitor(z.gw.zclCommunicator, z.gw.provider, z.gw.transmissionLookup, z.gw.logger)
+	return &trackedMonitor{Monitor: attribute.NewMonitor(z.gw.zclCommunicator, z.gw.provider, z.gw.transmissionLookup, z.gw.logger), gw: z.gw}
 }
 
 func (z zdaInterface) SendEvent(a any) {
 	z.gw.sendEvent(a)
 }
+
+func (z zdaInterface) AdapterNode() zigbee.Node {
+	return z.gw.provider.AdapterNode()
+}
+
+func (z zdaInterface) OTAImageStore() ota.ImageStore {
+	return z.gw.otaImageStore
+}
+
+func (z zdaInterface) CommandQueue(address zigbee.IEEEAddress) implcaps.CommandQueue {
+	if n := z.gw.getNode(address); n != nil {
+		return n.commandQueue
+	}
+
+	/* Nodes which are not known have nothing to check in, a detached queue can be safely used and discarded. */
+	return newCommandQueue()
+}
+
+func (z zdaInterface) LinkQuality(address zigbee.IEEEAddress) (implcaps.LinkQuality, bool) {
+	if n := z.gw.getNode(address); n != nil {
+		return n.linkQuality.get()
+	}
+
+	return implcaps.LinkQuality{}, false
+}
+
+func (z zdaInterface) ExpectedInterval(address zigbee.IEEEAddress) (time.Duration, bool) {
+	if n := z.gw.getNode(address); n != nil {
+		return n.monitors.expectedInterval()
+	}
+
+	return 0, false
+}